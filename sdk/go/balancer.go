@@ -0,0 +1,299 @@
+package pesto
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LoadBalancingStrategy decides which of the configured base URLs
+// will receive the next request.
+type LoadBalancingStrategy int
+
+const (
+	// RoundRobin sends the requests to each base URL in turn.
+	RoundRobin LoadBalancingStrategy = iota
+	// LeastOutstandingRequests sends the request to the base URL with the
+	// fewest requests that are still waiting for a response.
+	LeastOutstandingRequests
+	// Weighted distributes the requests proportionally to BaseURLWeights,
+	// using a smooth weighted round-robin.
+	Weighted
+)
+
+// node is a single Pesto instance known to the balancer.
+type node struct {
+	baseURL     *url.URL
+	weight      int
+	outstanding atomic.Int64
+
+	// currentWeight and ejectedUntil are guarded by balancer.mu
+	currentWeight int
+	ejectedUntil  time.Time
+}
+
+// resolve joins the request path into the node's base URL.
+func (n *node) resolve(requestURL *url.URL) *url.URL {
+	resolved := n.baseURL.JoinPath(requestURL.Path)
+	if !strings.HasPrefix(resolved.Path, "/") {
+		resolved.Path = "/" + resolved.Path
+	}
+	resolved.RawQuery = requestURL.RawQuery
+	return resolved
+}
+
+// balancer selects a node for every outgoing request, and keeps track of
+// the nodes that were ejected because they failed recently.
+type balancer struct {
+	strategy         LoadBalancingStrategy
+	ejectionDuration time.Duration
+
+	mu    sync.Mutex
+	nodes []*node
+	next  int
+}
+
+func newBalancer(baseURLs []*url.URL, weights []int, strategy LoadBalancingStrategy, ejectionDuration time.Duration) *balancer {
+	b := &balancer{
+		strategy:         strategy,
+		ejectionDuration: ejectionDuration,
+	}
+	b.setNodes(baseURLs, weights)
+
+	return b
+}
+
+// setNodes replaces every node of the balancer. Requests that are already
+// in flight keep their node.
+func (b *balancer) setNodes(baseURLs []*url.URL, weights []int) {
+	nodes := make([]*node, 0, len(baseURLs))
+	for i, baseURL := range baseURLs {
+		weight := 1
+		if i < len(weights) && weights[i] > 0 {
+			weight = weights[i]
+		}
+
+		nodes = append(nodes, &node{baseURL: baseURL, weight: weight})
+	}
+
+	b.mu.Lock()
+	b.nodes = nodes
+	b.next = 0
+	b.mu.Unlock()
+}
+
+// snapshot returns the current nodes.
+func (b *balancer) snapshot() []*node {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.nodes
+}
+
+// pick returns the next node to send the request to, skipping every node
+// inside the tried set. Healthy nodes are preferred over ejected ones, but if
+// every remaining node is ejected, the one with the nearest ejection expiry
+// is returned so the request still has a chance to succeed.
+// It returns nil if every node has been tried.
+func (b *balancer) pick(tried map[*node]bool) *node {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	var candidates []*node
+	var ejected *node
+	for i := 0; i < len(b.nodes); i++ {
+		// Iterate starting from b.next, so ties are broken in round-robin order.
+		n := b.nodes[(b.next+i)%len(b.nodes)]
+		if tried[n] {
+			continue
+		}
+
+		if now.Before(n.ejectedUntil) {
+			if ejected == nil || n.ejectedUntil.Before(ejected.ejectedUntil) {
+				ejected = n
+			}
+			continue
+		}
+
+		candidates = append(candidates, n)
+	}
+
+	if len(candidates) == 0 {
+		return ejected
+	}
+
+	var chosen *node
+	switch b.strategy {
+	case LeastOutstandingRequests:
+		chosen = candidates[0]
+		for _, n := range candidates[1:] {
+			if n.outstanding.Load() < chosen.outstanding.Load() {
+				chosen = n
+			}
+		}
+	case Weighted:
+		total := 0
+		for _, n := range candidates {
+			n.currentWeight += n.weight
+			total += n.weight
+			if chosen == nil || n.currentWeight > chosen.currentWeight {
+				chosen = n
+			}
+		}
+		chosen.currentWeight -= total
+	default:
+		chosen = candidates[0]
+	}
+
+	for i, n := range b.nodes {
+		if n == chosen {
+			b.next = i + 1
+			break
+		}
+	}
+
+	return chosen
+}
+
+// eject removes the node from the rotation for the configured ejection duration.
+func (b *balancer) eject(n *node) {
+	b.mu.Lock()
+	n.ejectedUntil = time.Now().Add(b.ejectionDuration)
+	b.mu.Unlock()
+}
+
+// restore puts the node back into the rotation.
+func (b *balancer) restore(n *node) {
+	b.mu.Lock()
+	n.ejectedUntil = time.Time{}
+	b.mu.Unlock()
+}
+
+// hasUntried reports whether there is any node that is not in the tried set.
+func (b *balancer) hasUntried(tried map[*node]bool) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, n := range b.nodes {
+		if !tried[n] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// outstandingBody decrements the outstanding request counter of the node
+// once the response body is closed.
+type outstandingBody struct {
+	io.ReadCloser
+	node *node
+	once sync.Once
+}
+
+func (o *outstandingBody) Close() error {
+	o.once.Do(func() {
+		o.node.outstanding.Add(-1)
+	})
+	return o.ReadCloser.Close()
+}
+
+// healthChecker periodically calls the ping endpoint of every node,
+// ejecting the ones that fail and restoring the ones that recover.
+//
+// It intentionally holds no reference to the Client, so the Client can be
+// garbage collected, which in turn stops the health checker.
+type healthChecker struct {
+	balancer   *balancer
+	httpClient *http.Client
+	token      *atomic.Pointer[string]
+	interval   time.Duration
+	stop       chan struct{}
+	stopOnce   sync.Once
+}
+
+// close stops the health checker. It is safe to call close more than once.
+func (h *healthChecker) close() {
+	h.stopOnce.Do(func() {
+		close(h.stop)
+	})
+}
+
+func (h *healthChecker) run() {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+			h.checkAll()
+		}
+	}
+}
+
+func (h *healthChecker) checkAll() {
+	var wg sync.WaitGroup
+	for _, n := range h.balancer.snapshot() {
+		wg.Add(1)
+		go func(n *node) {
+			defer wg.Done()
+
+			if h.check(n) {
+				h.balancer.restore(n)
+			} else {
+				h.balancer.eject(n)
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+func (h *healthChecker) check(n *node) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), h.interval)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, n.resolve(&url.URL{Path: "/api/ping"}).String(), nil)
+	if err != nil {
+		return false
+	}
+
+	request.Header.Set("X-Pesto-Token", *h.token.Load())
+	request.Header.Set("Accept", "application/json")
+
+	response, err := h.httpClient.Do(request)
+	if err != nil {
+		return false
+	}
+	_, _ = io.Copy(io.Discard, response.Body)
+	_ = response.Body.Close()
+
+	return response.StatusCode < http.StatusInternalServerError
+}
+
+// startHealthChecker starts the health checker for the client, and stops it
+// once the client is closed or garbage collected.
+func startHealthChecker(client *Client, interval time.Duration) {
+	checker := &healthChecker{
+		balancer:   client.balancer,
+		httpClient: client.httpClient,
+		token:      client.token,
+		interval:   interval,
+		stop:       make(chan struct{}),
+	}
+	client.healthChecker = checker
+
+	go checker.run()
+
+	runtime.SetFinalizer(client, func(c *Client) {
+		c.healthChecker.close()
+	})
+}
@@ -0,0 +1,269 @@
+package pesto_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("parsing url: %s", err.Error())
+	}
+
+	return parsedURL
+}
+
+func TestClient_LoadBalancing(t *testing.T) {
+	t.Run("RoundRobin", func(t *testing.T) {
+		first, firstHits := InstanceMockServer(http.StatusOK)
+		defer first.Close()
+		second, secondHits := InstanceMockServer(http.StatusOK)
+		defer second.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:    token,
+			BaseURLs: []*url.URL{mustParseURL(t, first.URL), mustParseURL(t, second.URL)},
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		for i := 0; i < 4; i++ {
+			_, err := client.Ping(ctx)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+		}
+
+		if hitCount(firstHits, "/api/ping") != 2 || hitCount(secondHits, "/api/ping") != 2 {
+			t.Errorf("expecting requests to be split evenly, got %d and %d", hitCount(firstHits, "/api/ping"), hitCount(secondHits, "/api/ping"))
+		}
+	})
+
+	t.Run("Weighted", func(t *testing.T) {
+		first, firstHits := InstanceMockServer(http.StatusOK)
+		defer first.Close()
+		second, secondHits := InstanceMockServer(http.StatusOK)
+		defer second.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:                 token,
+			BaseURLs:              []*url.URL{mustParseURL(t, first.URL), mustParseURL(t, second.URL)},
+			BaseURLWeights:        []int{3, 1},
+			LoadBalancingStrategy: pesto.Weighted,
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		for i := 0; i < 8; i++ {
+			_, err := client.Ping(ctx)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+		}
+
+		if hitCount(firstHits, "/api/ping") != 6 || hitCount(secondHits, "/api/ping") != 2 {
+			t.Errorf("expecting requests to be split 6:2, got %d:%d", hitCount(firstHits, "/api/ping"), hitCount(secondHits, "/api/ping"))
+		}
+	})
+
+	t.Run("LeastOutstandingRequests", func(t *testing.T) {
+		release := make(chan struct{})
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"message":"OK"}`))
+		}))
+		defer slow.Close()
+		fast, fastHits := InstanceMockServer(http.StatusOK)
+		defer fast.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:                 token,
+			BaseURLs:              []*url.URL{mustParseURL(t, slow.URL), mustParseURL(t, fast.URL)},
+			LoadBalancingStrategy: pesto.LeastOutstandingRequests,
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = client.Ping(ctx)
+		}()
+
+		// Wait until the slow instance holds the first request.
+		time.Sleep(time.Millisecond * 100)
+
+		for i := 0; i < 3; i++ {
+			_, err := client.Ping(ctx)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+		}
+
+		close(release)
+		<-done
+
+		if hitCount(fastHits, "/api/ping") != 3 {
+			t.Errorf("expecting the fast instance to receive 3 requests, got %d", hitCount(fastHits, "/api/ping"))
+		}
+	})
+
+	t.Run("FailoverOnTransportError", func(t *testing.T) {
+		down, _ := InstanceMockServer(http.StatusOK)
+		downURL := mustParseURL(t, down.URL)
+		down.Close()
+		up, upHits := InstanceMockServer(http.StatusOK)
+		defer up.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:    token,
+			BaseURLs: []*url.URL{downURL, mustParseURL(t, up.URL)},
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		for i := 0; i < 3; i++ {
+			response, err := client.ListRuntimes(ctx)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+
+			if len(response.Runtime) != 1 {
+				t.Errorf("expected runtime to have 1 value, got %d", len(response.Runtime))
+			}
+		}
+
+		if hitCount(upHits, "/api/list-runtimes") != 3 {
+			t.Errorf("expecting every request to fail over, got %d", hitCount(upHits, "/api/list-runtimes"))
+		}
+	})
+
+	t.Run("FailoverOnInternalServerError", func(t *testing.T) {
+		broken, brokenHits := InstanceMockServer(http.StatusInternalServerError)
+		defer broken.Close()
+		up, upHits := InstanceMockServer(http.StatusOK)
+		defer up.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:    token,
+			BaseURLs: []*url.URL{mustParseURL(t, broken.URL), mustParseURL(t, up.URL)},
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		for i := 0; i < 3; i++ {
+			_, err := client.Ping(ctx)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+		}
+
+		if hitCount(brokenHits, "/api/ping") != 1 {
+			t.Errorf("expecting the broken instance to be ejected after 1 request, got %d", hitCount(brokenHits, "/api/ping"))
+		}
+
+		if hitCount(upHits, "/api/ping") != 3 {
+			t.Errorf("expecting the healthy instance to receive 3 requests, got %d", hitCount(upHits, "/api/ping"))
+		}
+	})
+
+	t.Run("EveryInstanceFailing", func(t *testing.T) {
+		first, _ := InstanceMockServer(http.StatusInternalServerError)
+		defer first.Close()
+		second, _ := InstanceMockServer(http.StatusInternalServerError)
+		defer second.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:    token,
+			BaseURLs: []*url.URL{mustParseURL(t, first.URL), mustParseURL(t, second.URL)},
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err = client.Ping(ctx)
+		if err == nil {
+			t.Errorf("expecting an error, instead got nil")
+		}
+
+		if !errors.Is(err, pesto.ErrInternalServerError) {
+			t.Errorf("expecting an error of ErrInternalServerError, instead got %s", err.Error())
+		}
+	})
+
+	t.Run("HealthCheck", func(t *testing.T) {
+		unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Path == "/api/ping" {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"message":"Something's wrong on our end"}`))
+				return
+			}
+
+			t.Errorf("unexpected request to an unhealthy instance: %s", r.URL.Path)
+			w.Write([]byte(`{"runtime":[]}`))
+		}))
+		defer unhealthy.Close()
+		healthy, healthyHits := InstanceMockServer(http.StatusOK)
+		defer healthy.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:               token,
+			BaseURLs:            []*url.URL{mustParseURL(t, unhealthy.URL), mustParseURL(t, healthy.URL)},
+			HealthCheckInterval: time.Millisecond * 10,
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		time.Sleep(time.Millisecond * 100)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		for i := 0; i < 4; i++ {
+			_, err := client.ListRuntimes(ctx)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+		}
+
+		if hitCount(healthyHits, "/api/list-runtimes") != 4 {
+			t.Errorf("expecting the healthy instance to receive 4 requests, got %d", hitCount(healthyHits, "/api/list-runtimes"))
+		}
+	})
+}
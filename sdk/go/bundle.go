@@ -0,0 +1,385 @@
+package pesto
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SDKVersion is the version of this SDK, recorded in every Bundle.
+const SDKVersion = "1.0.0"
+
+// bundleFormat is the version of the bundle format. Bundles of a newer
+// format are rejected by ReadBundle.
+const bundleFormat = 1
+
+// Names of the tar entries of a bundle.
+const (
+	bundleEntry      = "bundle.json"
+	bundleCodeEntry  = "code"
+	bundleFilesEntry = "files/"
+)
+
+// Bundle is a recorded execution: the request, the response or the error, and
+// when it happened. A bundle is exported with WriteJSON or WriteTar, imported
+// with ReadBundle, and re-run with Replay, to reproduce what a user reported.
+//
+//	bundle, err := pesto.RecordBundle(ctx, client, request)
+//	// ...
+//	err = bundle.WriteJSON(file)
+type Bundle struct {
+	Request  CodeRequest
+	Response CodeResponse
+	// Error is the message of the error returned by the execution.
+	// Defaults to "" (the execution succeeded)
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// SDKVersion is the version of the SDK that recorded the bundle.
+	SDKVersion string
+}
+
+// bundleJSON is the JSON format of a Bundle. The timeouts are in milliseconds,
+// like the ones of the execute endpoint.
+type bundleJSON struct {
+	Format     int                `json:"format"`
+	SDKVersion string             `json:"sdkVersion"`
+	StartedAt  time.Time          `json:"startedAt"`
+	FinishedAt time.Time          `json:"finishedAt"`
+	Request    bundleRequestJSON  `json:"request"`
+	Response   bundleResponseJSON `json:"response"`
+	Error      string             `json:"error,omitempty"`
+}
+
+type bundleRequestJSON struct {
+	Language       string `json:"language"`
+	Version        string `json:"version"`
+	Code           string `json:"code,omitempty"`
+	Files          []File `json:"files,omitempty"`
+	CompileTimeout int64  `json:"compileTimeout,omitempty"`
+	RunTimeout     int64  `json:"runTimeout,omitempty"`
+	MemoryLimit    int32  `json:"memoryLimit,omitempty"`
+	MaxOutputBytes int    `json:"maxOutputBytes,omitempty"`
+}
+
+type bundleResponseJSON struct {
+	Language string             `json:"language"`
+	Version  string             `json:"version"`
+	Compile  bundleOutputJSON   `json:"compile"`
+	Runtime  bundleOutputJSON   `json:"runtime"`
+	Metadata bundleMetadataJSON `json:"metadata"`
+}
+
+type bundleOutputJSON struct {
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	Output    string `json:"output"`
+	ExitCode  int    `json:"exitCode"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+type bundleMetadataJSON struct {
+	RequestID      string `json:"requestId,omitempty"`
+	ServerID       string `json:"serverId,omitempty"`
+	TimedOut       bool   `json:"timedOut,omitempty"`
+	MemoryExceeded bool   `json:"memoryExceeded,omitempty"`
+	PeakMemory     int64  `json:"peakMemory,omitempty"`
+}
+
+// RecordBundle executes the request, and records it in a Bundle along with the
+// response. If the execution fails, the error is recorded in the bundle and returned.
+func RecordBundle(ctx context.Context, executor Executor, request CodeRequest) (Bundle, error) {
+	bundle := Bundle{Request: request, SDKVersion: SDKVersion, StartedAt: time.Now()}
+
+	response, err := executor.Execute(ctx, request)
+	bundle.FinishedAt = time.Now()
+	bundle.Response = response
+	if err != nil {
+		bundle.Error = err.Error()
+	}
+
+	return bundle, err
+}
+
+// ReplayRequest returns the recorded request, with the version that the server
+// resolved, so a request for VersionLatest runs on the same version again.
+func (b Bundle) ReplayRequest() CodeRequest {
+	request := b.Request
+	if b.Response.Version != "" {
+		request.Version = Version(b.Response.Version)
+	}
+
+	return request
+}
+
+// Replay executes the ReplayRequest of the bundle, and records it in a new Bundle
+// that can be compared with the recorded one.
+func (b Bundle) Replay(ctx context.Context, executor Executor) (Bundle, error) {
+	return RecordBundle(ctx, executor, b.ReplayRequest())
+}
+
+// MarshalJSON implements json.Marshaler.
+func (b Bundle) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.toJSON())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *Bundle) UnmarshalJSON(data []byte) error {
+	var body bundleJSON
+	err := json.Unmarshal(data, &body)
+	if err != nil {
+		return err
+	}
+
+	if body.Format < 1 || body.Format > bundleFormat {
+		return fmt.Errorf("%w: unsupported format %d", ErrInvalidBundle, body.Format)
+	}
+
+	*b = body.toBundle()
+	return nil
+}
+
+func (b Bundle) toJSON() bundleJSON {
+	request, response := b.Request, b.Response
+	return bundleJSON{
+		Format:     bundleFormat,
+		SDKVersion: b.SDKVersion,
+		StartedAt:  b.StartedAt,
+		FinishedAt: b.FinishedAt,
+		Request: bundleRequestJSON{
+			Language:       string(request.Language),
+			Version:        string(request.Version),
+			Code:           request.Code,
+			Files:          request.Files,
+			CompileTimeout: request.CompileTimeout.Milliseconds(),
+			RunTimeout:     request.RunTimeout.Milliseconds(),
+			MemoryLimit:    request.MemoryLimit,
+			MaxOutputBytes: request.MaxOutputBytes,
+		},
+		Response: bundleResponseJSON{
+			Language: response.Language,
+			Version:  response.Version,
+			Compile:  newBundleOutputJSON(response.Compile),
+			Runtime:  newBundleOutputJSON(response.Runtime),
+			Metadata: bundleMetadataJSON{
+				RequestID:      response.Metadata.RequestID,
+				ServerID:       response.Metadata.ServerID,
+				TimedOut:       response.Metadata.TimedOut,
+				MemoryExceeded: response.Metadata.MemoryExceeded,
+				PeakMemory:     response.Metadata.PeakMemory,
+			},
+		},
+		Error: b.Error,
+	}
+}
+
+func (b bundleJSON) toBundle() Bundle {
+	request, response := b.Request, b.Response
+	return Bundle{
+		Request: CodeRequest{
+			Language:       Language(request.Language),
+			Version:        Version(request.Version),
+			Code:           request.Code,
+			Files:          request.Files,
+			CompileTimeout: time.Duration(request.CompileTimeout) * time.Millisecond,
+			RunTimeout:     time.Duration(request.RunTimeout) * time.Millisecond,
+			MemoryLimit:    request.MemoryLimit,
+			MaxOutputBytes: request.MaxOutputBytes,
+		},
+		Response: CodeResponse{
+			Language: response.Language,
+			Version:  response.Version,
+			Compile:  response.Compile.toOutput(),
+			Runtime:  response.Runtime.toOutput(),
+			Metadata: Metadata{
+				RequestID:      response.Metadata.RequestID,
+				ServerID:       response.Metadata.ServerID,
+				TimedOut:       response.Metadata.TimedOut,
+				MemoryExceeded: response.Metadata.MemoryExceeded,
+				PeakMemory:     response.Metadata.PeakMemory,
+			},
+		},
+		Error:      b.Error,
+		StartedAt:  b.StartedAt,
+		FinishedAt: b.FinishedAt,
+		SDKVersion: b.SDKVersion,
+	}
+}
+
+func newBundleOutputJSON(output Output) bundleOutputJSON {
+	return bundleOutputJSON{
+		Stdout:    output.Stdout,
+		Stderr:    output.Stderr,
+		Output:    output.Output,
+		ExitCode:  output.ExitCode,
+		Truncated: output.Truncated,
+	}
+}
+
+func (o bundleOutputJSON) toOutput() Output {
+	return Output{
+		Stdout:    o.Stdout,
+		Stderr:    o.Stderr,
+		Output:    o.Output,
+		ExitCode:  o.ExitCode,
+		Truncated: o.Truncated,
+	}
+}
+
+// WriteJSON writes the bundle as indented JSON.
+func (b Bundle) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(b)
+}
+
+// WriteTar writes the bundle as a tar archive, which keeps the code readable:
+// bundle.json holds everything but the code, which is stored in the code entry,
+// and the files of the request are stored under the files/ directory.
+func (b Bundle) WriteTar(w io.Writer) error {
+	stripped := b
+	stripped.Request.Code = ""
+	stripped.Request.Files = nil
+	for _, file := range b.Request.Files {
+		stripped.Request.Files = append(stripped.Request.Files, File{Name: file.Name, Entrypoint: file.Entrypoint})
+	}
+
+	var metadata bytes.Buffer
+	err := stripped.WriteJSON(&metadata)
+	if err != nil {
+		return fmt.Errorf("encoding bundle: %w", err)
+	}
+
+	archive := tar.NewWriter(w)
+	modTime := b.FinishedAt
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+
+	err = writeTarEntry(archive, bundleEntry, metadata.Bytes(), modTime)
+	if err != nil {
+		return err
+	}
+
+	if b.Request.Code != "" {
+		err = writeTarEntry(archive, bundleCodeEntry, []byte(b.Request.Code), modTime)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, file := range b.Request.Files {
+		err = writeTarEntry(archive, bundleFilesEntry+file.Name, []byte(file.Code), modTime)
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func writeTarEntry(archive *tar.Writer, name string, content []byte, modTime time.Time) error {
+	err := archive.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(content)),
+		ModTime:  modTime,
+	})
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+
+	_, err = archive.Write(content)
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// ReadBundle reads a bundle that was written by WriteJSON or WriteTar.
+// The format is detected from the content.
+func ReadBundle(r io.Reader) (Bundle, error) {
+	reader := bufio.NewReaderSize(r, 512)
+
+	// The header of a tar archive has the "ustar" magic at offset 257.
+	header, _ := reader.Peek(262)
+	if len(header) == 262 && string(header[257:262]) == "ustar" {
+		return readBundleTar(reader)
+	}
+
+	var bundle Bundle
+	err := json.NewDecoder(reader).Decode(&bundle)
+	if err != nil {
+		if errors.Is(err, ErrInvalidBundle) {
+			return Bundle{}, err
+		}
+
+		return Bundle{}, fmt.Errorf("%w: %s", ErrInvalidBundle, err.Error())
+	}
+
+	return bundle, nil
+}
+
+func readBundleTar(r io.Reader) (Bundle, error) {
+	var metadata []byte
+	contents := make(map[string]string)
+
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Bundle{}, fmt.Errorf("%w: %s", ErrInvalidBundle, err.Error())
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		content, err := io.ReadAll(archive)
+		if err != nil {
+			return Bundle{}, fmt.Errorf("%w: reading %s: %s", ErrInvalidBundle, header.Name, err.Error())
+		}
+
+		if header.Name == bundleEntry {
+			metadata = content
+		} else {
+			contents[header.Name] = string(content)
+		}
+	}
+
+	if metadata == nil {
+		return Bundle{}, fmt.Errorf("%w: missing %s", ErrInvalidBundle, bundleEntry)
+	}
+
+	var bundle Bundle
+	err := json.Unmarshal(metadata, &bundle)
+	if err != nil {
+		if errors.Is(err, ErrInvalidBundle) {
+			return Bundle{}, err
+		}
+
+		return Bundle{}, fmt.Errorf("%w: %s", ErrInvalidBundle, err.Error())
+	}
+
+	bundle.Request.Code = contents[bundleCodeEntry]
+	for i, file := range bundle.Request.Files {
+		code, ok := contents[bundleFilesEntry+file.Name]
+		if !ok {
+			return Bundle{}, fmt.Errorf("%w: missing %s%s", ErrInvalidBundle, bundleFilesEntry, file.Name)
+		}
+		bundle.Request.Files[i].Code = code
+	}
+
+	return bundle, nil
+}
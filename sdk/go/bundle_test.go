@@ -0,0 +1,205 @@
+package pesto_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/pestotest"
+)
+
+func newTestBundle() pesto.Bundle {
+	startedAt := time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)
+	return pesto.Bundle{
+		Request: pesto.CodeRequest{
+			Language: pesto.LanguagePython,
+			Version:  pesto.VersionLatest,
+			Files: []pesto.File{
+				{Name: "main.py", Code: "import greet", Entrypoint: true},
+				{Name: "lib/greet.py", Code: "print('Hello World')"},
+			},
+			CompileTimeout: 5 * time.Second,
+			RunTimeout:     1500 * time.Millisecond,
+			MemoryLimit:    1024 * 1024,
+			MaxOutputBytes: 100,
+		},
+		Response: pesto.CodeResponse{
+			Language: "Python",
+			Version:  "3.10.2",
+			Runtime:  pesto.Output{Stdout: "Hello World\n", Output: "Hello World\n", Truncated: true},
+			Metadata: pesto.Metadata{RequestID: "request-1", TimedOut: true, PeakMemory: 2048},
+		},
+		StartedAt:  startedAt,
+		FinishedAt: startedAt.Add(time.Second),
+		SDKVersion: pesto.SDKVersion,
+	}
+}
+
+func TestBundle_JSON(t *testing.T) {
+	bundle := newTestBundle()
+
+	var buffer bytes.Buffer
+	err := bundle.WriteJSON(&buffer)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	for _, field := range []string{`"format": 1`, `"compileTimeout": 5000`, `"runTimeout": 1500`, `"version": "3.10.2"`, `"requestId": "request-1"`} {
+		if !strings.Contains(buffer.String(), field) {
+			t.Errorf("expecting the JSON to contain %s, got %s", field, buffer.String())
+		}
+	}
+
+	got, err := pesto.ReadBundle(&buffer)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if !reflect.DeepEqual(got, bundle) {
+		t.Errorf("expecting %+v, got %+v", bundle, got)
+	}
+}
+
+func TestBundle_Tar(t *testing.T) {
+	bundle := newTestBundle()
+
+	var buffer bytes.Buffer
+	err := bundle.WriteTar(&buffer)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	// The code is stored in its own entries, outside of bundle.json.
+	contents := make(map[string]string)
+	archive := tar.NewReader(bytes.NewReader(buffer.Bytes()))
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading tar: %s", err.Error())
+		}
+
+		content, _ := io.ReadAll(archive)
+		contents[header.Name] = string(content)
+	}
+
+	if contents["files/lib/greet.py"] != "print('Hello World')" || contents["files/main.py"] != "import greet" {
+		t.Errorf("expecting the files to be stored under files/, got %v", contents)
+	}
+
+	if strings.Contains(contents["bundle.json"], "import greet") {
+		t.Errorf("expecting bundle.json to not contain the code, got %s", contents["bundle.json"])
+	}
+
+	got, err := pesto.ReadBundle(&buffer)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if !reflect.DeepEqual(got, bundle) {
+		t.Errorf("expecting %+v, got %+v", bundle, got)
+	}
+
+	if bundle.Request.Files[0].Code != "import greet" {
+		t.Errorf("expecting WriteTar to not modify the bundle, got %+v", bundle.Request.Files)
+	}
+}
+
+func TestReadBundle_Error(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "Malformed", input: "{"},
+		{name: "Empty", input: ""},
+		{name: "NewerFormat", input: `{"format": 2}`},
+		{name: "MissingFormat", input: `{"request": {}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := pesto.ReadBundle(strings.NewReader(test.input))
+			if !errors.Is(err, pesto.ErrInvalidBundle) {
+				t.Errorf("expecting an error of ErrInvalidBundle, instead got %v", err)
+			}
+		})
+	}
+
+	t.Run("MissingFile", func(t *testing.T) {
+		var buffer bytes.Buffer
+		archive := tar.NewWriter(&buffer)
+		metadata := []byte(`{"format": 1, "request": {"files": [{"name": "main.py"}]}}`)
+		_ = archive.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "bundle.json", Size: int64(len(metadata)), Mode: 0o644})
+		_, _ = archive.Write(metadata)
+		_ = archive.Close()
+
+		_, err := pesto.ReadBundle(&buffer)
+		if !errors.Is(err, pesto.ErrInvalidBundle) {
+			t.Errorf("expecting an error of ErrInvalidBundle, instead got %v", err)
+		}
+	})
+}
+
+func TestBundle_Replay(t *testing.T) {
+	var received []pestotest.ExecuteRequest
+	server := pestotest.NewServer(
+		pestotest.WithToken(token, 100),
+		pestotest.WithExecuteFunc(func(runtime pesto.Runtime, request pestotest.ExecuteRequest) pesto.CodeResponse {
+			received = append(received, request)
+			return pesto.CodeResponse{Runtime: pesto.Output{Stdout: "Hello World\n"}}
+		}),
+	)
+	defer server.Close()
+
+	client, err := pesto.NewClientWithConfig(pesto.Config{Token: token, BaseURL: server.BaseURL()})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	recorded, err := pesto.RecordBundle(ctx, client, pesto.CodeRequest{
+		Language: pesto.LanguagePython,
+		Version:  pesto.VersionLatest,
+		Code:     "print('Hello World')",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if recorded.SDKVersion != pesto.SDKVersion || recorded.StartedAt.IsZero() || recorded.FinishedAt.Before(recorded.StartedAt) {
+		t.Errorf("expecting the bundle to record the SDK version and the timestamps, got %+v", recorded)
+	}
+
+	replayed, err := recorded.Replay(ctx, client)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if replayed.Request.Version != pesto.Version(recorded.Response.Version) || received[1].Version != recorded.Response.Version {
+		t.Errorf("expecting the replay to run on the resolved version %s, got %s", recorded.Response.Version, received[1].Version)
+	}
+
+	if replayed.Response.Runtime.Stdout != recorded.Response.Runtime.Stdout {
+		t.Errorf("expecting the same stdout, got %q", replayed.Response.Runtime.Stdout)
+	}
+
+	recorded, err = pesto.RecordBundle(ctx, client, pesto.CodeRequest{Language: "Rust", Version: pesto.VersionLatest, Code: "fn main() {}"})
+	if !errors.Is(err, pesto.ErrRuntimeNotFound) {
+		t.Errorf("expecting an error of ErrRuntimeNotFound, instead got %v", err)
+	}
+
+	if err != nil && recorded.Error != err.Error() {
+		t.Errorf("expecting the error to be recorded, got %q", recorded.Error)
+	}
+}
@@ -0,0 +1,82 @@
+package pesto_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/pestotest"
+)
+
+// TestClient_Cassette runs the client against the payloads of testdata/cassettes/synthetic.json.
+// The cassette is synthetic: it was recorded against the pestotest server, with its
+// DefaultRuntimes, rather than against the rce and auth services, so it only covers
+// how the client replays a cassette, not the payloads of Pesto's API.
+func TestClient_Cassette(t *testing.T) {
+	recorder, err := pestotest.NewRecorder(pestotest.RecorderConfig{
+		Cassette: "testdata/cassettes/synthetic.json",
+		Strict:   true,
+	})
+	if err != nil {
+		t.Fatalf("creating recorder: %s", err.Error())
+	}
+
+	client, err := pesto.NewClientWithConfig(pesto.Config{
+		Token:      token,
+		HttpClient: &http.Client{Transport: recorder},
+	})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	ping, err := client.Ping(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if ping.Message != "OK" {
+		t.Errorf("expecting ping message to be OK, got %s", ping.Message)
+	}
+
+	runtimes, err := client.ListRuntimes(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(runtimes.Runtime) != 3 || runtimes.Runtime[0].Language != "Python" {
+		t.Errorf("expecting 3 runtimes, starting with Python, got %+v", runtimes.Runtime)
+	}
+
+	response, err := client.Execute(ctx, pesto.CodeRequest{
+		Language: pesto.LanguagePython,
+		Version:  pesto.VersionLatest,
+		Code:     "print('Hello world!')",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if response.Version != "3.10.10" || response.Runtime.Stdout != "Hello world!\n" {
+		t.Errorf("expecting Python 3.10.10 to print 'Hello world!', got %+v", response)
+	}
+
+	_, err = client.Execute(ctx, pesto.CodeRequest{Language: "Rust", Version: pesto.VersionLatest, Code: "fn main() {}"})
+	if !errors.Is(err, pesto.ErrRuntimeNotFound) {
+		t.Errorf("expecting an error of ErrRuntimeNotFound, instead got %v", err)
+	}
+
+	if unplayed := recorder.Unplayed(); len(unplayed) != 0 {
+		t.Errorf("expecting every interaction to be played, got %d unplayed", len(unplayed))
+	}
+
+	_, err = client.Execute(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionLatest, Code: "print(1)"})
+	if !errors.Is(err, pestotest.ErrUnmatchedRequest) {
+		t.Errorf("expecting an error of ErrUnmatchedRequest, instead got %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package pesto
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CircuitState represents the state of the circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets every request through, while counting the failures.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails every request immediately with ErrCircuitOpen.
+	CircuitOpen
+	// CircuitHalfOpen is the state while the Ping probe is being sent
+	// to find out whether Pesto has recovered.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+
+	return "unknown"
+}
+
+// CircuitBreakerConfig provides configuration for the optional circuit breaker.
+//
+// Only transport errors and 5xx responses are counted as failures. Client errors
+// such as ErrMissingParameters or ErrRuntimeNotFound do not affect the circuit.
+type CircuitBreakerConfig struct {
+	// ConsecutiveFailures opens the circuit after the given amount of failures in a row.
+	// Defaults to 5 if ErrorRateThreshold is not set.
+	ConsecutiveFailures int
+	// ErrorRateThreshold opens the circuit when the ratio of failed requests within
+	// Window reaches the threshold. The value should be between 0 and 1.
+	// Defaults to 0 (disabled)
+	ErrorRateThreshold float64
+	// Window is the sliding window used by ErrorRateThreshold. It is split into
+	// 10 buckets, so it must be at least 10 nanoseconds, otherwise
+	// NewClientWithConfig returns ErrInvalidConfig.
+	// Defaults to 1 minute
+	Window time.Duration
+	// MinimumRequests is the minimum amount of requests within Window before
+	// ErrorRateThreshold is taken into account.
+	// Defaults to 10
+	MinimumRequests int
+	// OpenDuration is how long the circuit stays open before a Ping probe is sent.
+	// Defaults to 30 seconds
+	OpenDuration time.Duration
+	// OnStateChange is called after every state transition, useful for alerting.
+	// It is called synchronously, so keep it short.
+	OnStateChange func(from, to CircuitState)
+}
+
+const circuitBreakerBuckets = 10
+
+type circuitBucket struct {
+	start    time.Time
+	total    int
+	failures int
+}
+
+// circuitBreaker tracks the health of Pesto's API based on the outcome of
+// previous requests.
+type circuitBreaker struct {
+	config      CircuitBreakerConfig
+	bucketWidth time.Duration
+
+	mu                  sync.Mutex
+	state               CircuitState
+	openedAt            time.Time
+	consecutiveFailures int
+	buckets             [circuitBreakerBuckets]circuitBucket
+}
+
+// circuitProbeKey marks the context of the half-open probe, so the probe
+// itself is not blocked by the open circuit.
+type circuitProbeKey struct{}
+
+func newCircuitBreaker(config CircuitBreakerConfig) *circuitBreaker {
+	if config.ConsecutiveFailures == 0 && config.ErrorRateThreshold == 0 {
+		config.ConsecutiveFailures = 5
+	}
+
+	// NewClientPool cannot reject a window that is too short, it gets the default instead.
+	if config.Window < circuitBreakerBuckets {
+		config.Window = time.Minute
+	}
+
+	if config.MinimumRequests == 0 {
+		config.MinimumRequests = 10
+	}
+
+	if config.OpenDuration == 0 {
+		config.OpenDuration = time.Second * 30
+	}
+
+	return &circuitBreaker{
+		config:      config,
+		bucketWidth: config.Window / circuitBreakerBuckets,
+	}
+}
+
+// State returns the current state of the circuit.
+func (b *circuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// allow decides whether a request may be sent. Once the open duration has passed,
+// the first caller sends the probe to decide whether the circuit should be closed,
+// while every other caller keeps on failing fast. The probe reports whether it
+// failed the same way record is told, so only a transport error or a 5xx response
+// opens the circuit again.
+//
+// If ctx is done before the probe finishes, the circuit goes back to open without
+// restarting the open duration, so the next caller sends the probe again.
+func (b *circuitBreaker) allow(ctx context.Context, probe func(ctx context.Context) (failed bool)) error {
+	b.mu.Lock()
+	switch b.state {
+	case CircuitClosed:
+		b.mu.Unlock()
+		return nil
+	case CircuitHalfOpen:
+		b.mu.Unlock()
+		return ErrCircuitOpen
+	}
+
+	if time.Since(b.openedAt) < b.config.OpenDuration {
+		b.mu.Unlock()
+		return ErrCircuitOpen
+	}
+
+	b.transition(CircuitHalfOpen)
+	b.mu.Unlock()
+	b.notify(CircuitOpen, CircuitHalfOpen)
+
+	failed := probe(context.WithValue(ctx, circuitProbeKey{}, true))
+
+	b.mu.Lock()
+	if ctx.Err() != nil {
+		// The caller gave up, it says nothing about the server's health.
+		b.state = CircuitOpen
+		b.mu.Unlock()
+		b.notify(CircuitHalfOpen, CircuitOpen)
+		return ctx.Err()
+	}
+
+	if failed {
+		b.transition(CircuitOpen)
+		b.mu.Unlock()
+		b.notify(CircuitHalfOpen, CircuitOpen)
+		return ErrCircuitOpen
+	}
+
+	b.transition(CircuitClosed)
+	b.mu.Unlock()
+	b.notify(CircuitHalfOpen, CircuitClosed)
+	return nil
+}
+
+// record counts the outcome of a request and opens the circuit
+// once the configured threshold is reached.
+func (b *circuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	if b.state != CircuitClosed {
+		b.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	bucket := &b.buckets[(now.UnixNano()/int64(b.bucketWidth))%circuitBreakerBuckets]
+	if start := now.Truncate(b.bucketWidth); !bucket.start.Equal(start) {
+		*bucket = circuitBucket{start: start}
+	}
+	bucket.total++
+
+	if !failed {
+		b.consecutiveFailures = 0
+		b.mu.Unlock()
+		return
+	}
+
+	bucket.failures++
+	b.consecutiveFailures++
+
+	if !b.shouldTrip(now) {
+		b.mu.Unlock()
+		return
+	}
+
+	b.transition(CircuitOpen)
+	b.mu.Unlock()
+	b.notify(CircuitClosed, CircuitOpen)
+}
+
+func (b *circuitBreaker) shouldTrip(now time.Time) bool {
+	if b.config.ConsecutiveFailures > 0 && b.consecutiveFailures >= b.config.ConsecutiveFailures {
+		return true
+	}
+
+	if b.config.ErrorRateThreshold <= 0 {
+		return false
+	}
+
+	var total, failures int
+	for _, bucket := range b.buckets {
+		if now.Sub(bucket.start) >= b.config.Window {
+			continue
+		}
+
+		total += bucket.total
+		failures += bucket.failures
+	}
+
+	return total >= b.config.MinimumRequests && float64(failures)/float64(total) >= b.config.ErrorRateThreshold
+}
+
+// transition must be called while holding b.mu.
+func (b *circuitBreaker) transition(to CircuitState) {
+	b.state = to
+
+	switch to {
+	case CircuitOpen:
+		b.openedAt = time.Now()
+	case CircuitClosed:
+		b.consecutiveFailures = 0
+		b.buckets = [circuitBreakerBuckets]circuitBucket{}
+	}
+}
+
+func (b *circuitBreaker) notify(from, to CircuitState) {
+	if b.config.OnStateChange != nil {
+		b.config.OnStateChange(from, to)
+	}
+}
@@ -0,0 +1,342 @@
+package pesto_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+type stateChanges struct {
+	mu      sync.Mutex
+	changes [][2]pesto.CircuitState
+}
+
+func (s *stateChanges) record(from, to pesto.CircuitState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes = append(s.changes, [2]pesto.CircuitState{from, to})
+}
+
+func (s *stateChanges) get() [][2]pesto.CircuitState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][2]pesto.CircuitState{}, s.changes...)
+}
+
+func TestClient_CircuitBreaker(t *testing.T) {
+	t.Run("ConsecutiveFailures", func(t *testing.T) {
+		var failing atomic.Bool
+		var hits atomic.Int64
+		failing.Store(true)
+		server := ToggleMockServer(&failing, &hits)
+		defer server.Close()
+
+		changes := &stateChanges{}
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: mustParseURL(t, server.URL),
+			CircuitBreaker: &pesto.CircuitBreakerConfig{
+				ConsecutiveFailures: 3,
+				OpenDuration:        time.Hour,
+				OnStateChange:       changes.record,
+			},
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		for i := 0; i < 3; i++ {
+			_, err := client.Ping(ctx)
+			if !errors.Is(err, pesto.ErrInternalServerError) {
+				t.Errorf("expecting an error of ErrInternalServerError, instead got %v", err)
+			}
+		}
+
+		_, err = client.Ping(ctx)
+		if !errors.Is(err, pesto.ErrCircuitOpen) {
+			t.Errorf("expecting an error of ErrCircuitOpen, instead got %v", err)
+		}
+
+		if hits.Load() != 3 {
+			t.Errorf("expecting the server to receive 3 requests, got %d", hits.Load())
+		}
+
+		if client.CircuitState() != pesto.CircuitOpen {
+			t.Errorf("expecting circuit to be open, got %s", client.CircuitState())
+		}
+
+		got := changes.get()
+		if len(got) != 1 || got[0] != [2]pesto.CircuitState{pesto.CircuitClosed, pesto.CircuitOpen} {
+			t.Errorf("unexpected state changes: %v", got)
+		}
+	})
+
+	t.Run("ErrorRate", func(t *testing.T) {
+		var failing atomic.Bool
+		var hits atomic.Int64
+		server := ToggleMockServer(&failing, &hits)
+		defer server.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: mustParseURL(t, server.URL),
+			CircuitBreaker: &pesto.CircuitBreakerConfig{
+				ErrorRateThreshold: 0.5,
+				MinimumRequests:    4,
+				Window:             time.Minute,
+				OpenDuration:       time.Hour,
+			},
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		for i := 0; i < 4; i++ {
+			failing.Store(i%2 == 1)
+			_, _ = client.Ping(ctx)
+		}
+
+		if client.CircuitState() != pesto.CircuitOpen {
+			t.Errorf("expecting circuit to be open, got %s", client.CircuitState())
+		}
+	})
+
+	t.Run("HalfOpenProbe", func(t *testing.T) {
+		var failing atomic.Bool
+		var hits atomic.Int64
+		failing.Store(true)
+		server := ToggleMockServer(&failing, &hits)
+		defer server.Close()
+
+		changes := &stateChanges{}
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: mustParseURL(t, server.URL),
+			CircuitBreaker: &pesto.CircuitBreakerConfig{
+				ConsecutiveFailures: 1,
+				OpenDuration:        time.Millisecond * 50,
+				OnStateChange:       changes.record,
+			},
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, _ = client.Ping(ctx)
+
+		// The probe fails, so the circuit opens again.
+		time.Sleep(time.Millisecond * 60)
+		_, err = client.Ping(ctx)
+		if !errors.Is(err, pesto.ErrCircuitOpen) {
+			t.Errorf("expecting an error of ErrCircuitOpen, instead got %v", err)
+		}
+
+		// The probe succeeds, so the request goes through.
+		failing.Store(false)
+		time.Sleep(time.Millisecond * 60)
+		response, err := client.Ping(ctx)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+
+		if response.Message != "OK" {
+			t.Errorf("expecting response.Message to be 'OK', instead got %s", response.Message)
+		}
+
+		expected := [][2]pesto.CircuitState{
+			{pesto.CircuitClosed, pesto.CircuitOpen},
+			{pesto.CircuitOpen, pesto.CircuitHalfOpen},
+			{pesto.CircuitHalfOpen, pesto.CircuitOpen},
+			{pesto.CircuitOpen, pesto.CircuitHalfOpen},
+			{pesto.CircuitHalfOpen, pesto.CircuitClosed},
+		}
+		got := changes.get()
+		if len(got) != len(expected) {
+			t.Fatalf("expecting %d state changes, got %v", len(expected), got)
+		}
+
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Errorf("expecting state change #%d to be %v, got %v", i, expected[i], got[i])
+			}
+		}
+	})
+
+	t.Run("ClientErrorsDoNotTrip", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: happyMockServerURL,
+			CircuitBreaker: &pesto.CircuitBreakerConfig{
+				ConsecutiveFailures: 1,
+			},
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		for i := 0; i < 3; i++ {
+			_, err := client.Execute(ctx, pesto.CodeRequest{})
+			if !errors.Is(err, pesto.ErrMissingParameters) {
+				t.Errorf("expecting an error of ErrMissingParameters, instead got %v", err)
+			}
+		}
+
+		if client.CircuitState() != pesto.CircuitClosed {
+			t.Errorf("expecting circuit to be closed, got %s", client.CircuitState())
+		}
+	})
+
+	t.Run("ClientErrorProbeCloses", func(t *testing.T) {
+		var status atomic.Int64
+		status.Store(http.StatusInternalServerError)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(int(status.Load()))
+			w.Write([]byte(`{"message":"Too many requests"}`))
+		}))
+		defer server.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: mustParseURL(t, server.URL),
+			CircuitBreaker: &pesto.CircuitBreakerConfig{
+				ConsecutiveFailures: 1,
+				OpenDuration:        time.Millisecond * 50,
+			},
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, _ = client.Ping(ctx)
+
+		// The server is up, it only refuses the request, so the probe closes the circuit.
+		status.Store(http.StatusTooManyRequests)
+		time.Sleep(time.Millisecond * 60)
+		_, err = client.Ping(ctx)
+		if !errors.Is(err, pesto.ErrServerRateLimited) {
+			t.Errorf("expecting an error of ErrServerRateLimited, instead got %v", err)
+		}
+
+		if client.CircuitState() != pesto.CircuitClosed {
+			t.Errorf("expecting circuit to be closed, got %s", client.CircuitState())
+		}
+	})
+
+	t.Run("CanceledProbe", func(t *testing.T) {
+		var failing atomic.Bool
+		var slow atomic.Bool
+		failing.Store(true)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slow.Load() {
+				select {
+				case <-r.Context().Done():
+					return
+				case <-time.After(time.Second):
+				}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			if failing.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"message":"Something's wrong on our end"}`))
+				return
+			}
+
+			w.Write([]byte(`{"message":"OK"}`))
+		}))
+		defer server.Close()
+
+		changes := &stateChanges{}
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: mustParseURL(t, server.URL),
+			CircuitBreaker: &pesto.CircuitBreakerConfig{
+				ConsecutiveFailures: 1,
+				OpenDuration:        time.Millisecond * 50,
+				OnStateChange:       changes.record,
+			},
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, _ = client.Ping(ctx)
+
+		failing.Store(false)
+		slow.Store(true)
+		time.Sleep(time.Millisecond * 60)
+		probeCtx, probeCancel := context.WithTimeout(ctx, time.Millisecond*20)
+		_, err = client.Ping(probeCtx)
+		probeCancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expecting an error of context.DeadlineExceeded, instead got %v", err)
+		}
+
+		if client.CircuitState() != pesto.CircuitOpen {
+			t.Errorf("expecting circuit to be open, got %s", client.CircuitState())
+		}
+
+		// The open duration was not restarted, so the next call sends the probe right away.
+		slow.Store(false)
+		_, err = client.Ping(ctx)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+
+		expected := [][2]pesto.CircuitState{
+			{pesto.CircuitClosed, pesto.CircuitOpen},
+			{pesto.CircuitOpen, pesto.CircuitHalfOpen},
+			{pesto.CircuitHalfOpen, pesto.CircuitOpen},
+			{pesto.CircuitOpen, pesto.CircuitHalfOpen},
+			{pesto.CircuitHalfOpen, pesto.CircuitClosed},
+		}
+		got := changes.get()
+		if len(got) != len(expected) {
+			t.Fatalf("expecting %d state changes, got %v", len(expected), got)
+		}
+
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Errorf("expecting state change #%d to be %v, got %v", i, expected[i], got[i])
+			}
+		}
+	})
+
+	t.Run("InvalidWindow", func(t *testing.T) {
+		for _, window := range []time.Duration{-time.Minute, time.Nanosecond * 9} {
+			_, err := pesto.NewClientWithConfig(pesto.Config{
+				Token:          token,
+				CircuitBreaker: &pesto.CircuitBreakerConfig{ErrorRateThreshold: 0.5, Window: window},
+			})
+			if !errors.Is(err, pesto.ErrInvalidConfig) {
+				t.Errorf("expecting an error of ErrInvalidConfig for a window of %s, instead got %v", window, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package pesto
+
+import (
+	"context"
+	"sync"
+)
+
+// lifecycle keeps track of the calls that are in flight, so the Client
+// can wait for them, or cancel them, when it is being closed.
+type lifecycle struct {
+	mu       sync.Mutex
+	closed   bool
+	nextID   uint64
+	inFlight map[uint64]context.CancelFunc
+	idle     chan struct{}
+}
+
+// lifecycleKey is the context key of the lifecycle a call was registered on.
+type lifecycleKey struct{}
+
+// acquire registers a call, and returns a copy of ctx that is canceled if the
+// client is closed before the call finishes. release must be called once the call is done.
+// If the client is closed, it will return ErrClientClosed error.
+//
+// A call that was already registered through ctx is not registered again,
+// so it is not refused while the client is draining.
+func (l *lifecycle) acquire(ctx context.Context) (context.Context, func(), error) {
+	if registered, ok := ctx.Value(lifecycleKey{}).(*lifecycle); ok && registered == l {
+		return ctx, func() {}, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ctx, nil, ErrClientClosed
+	}
+
+	if l.inFlight == nil {
+		l.inFlight = make(map[uint64]context.CancelFunc)
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, lifecycleKey{}, l))
+	id := l.nextID
+	l.nextID++
+	l.inFlight[id] = cancel
+
+	release := func() {
+		cancel()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		delete(l.inFlight, id)
+		if l.closed && len(l.inFlight) == 0 {
+			l.closeIdle()
+		}
+	}
+
+	return ctx, release, nil
+}
+
+// close stops accepting new calls, and returns a channel that is closed
+// once every call in flight has been released.
+func (l *lifecycle) close() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.idle == nil {
+		l.idle = make(chan struct{})
+	}
+
+	if !l.closed {
+		l.closed = true
+		if len(l.inFlight) == 0 {
+			l.closeIdle()
+		}
+	}
+
+	return l.idle
+}
+
+// closeIdle closes the idle channel. It must be called with l.mu held,
+// once the lifecycle is closed and nothing is in flight.
+func (l *lifecycle) closeIdle() {
+	select {
+	case <-l.idle:
+	default:
+		close(l.idle)
+	}
+}
+
+// cancelAll cancels every call that is in flight.
+func (l *lifecycle) cancelAll() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, cancel := range l.inFlight {
+		cancel()
+	}
+}
+
+// Close stops the client from accepting new calls, which will return ErrClientClosed,
+// and waits for the calls that are in flight to finish. If ctx is done before they
+// finish, the calls in flight are canceled, and ctx.Err() is returned once they return.
+//
+// The jobs created by Submit are calls in flight as well, and Close waits
+// for their callbacks to run within the same ctx.
+//
+// Close also stops the health checker and closes the idle connections of the
+// HTTP client. It is safe to call Close more than once.
+func (c *Client) Close(ctx context.Context) error {
+	idle := c.lifecycle.close()
+
+	if c.healthChecker != nil {
+		c.healthChecker.close()
+	}
+
+	var err error
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		c.lifecycle.cancelAll()
+		<-idle
+		err = ctx.Err()
+	}
+
+	c.callbacks.wait(ctx)
+
+	c.logger.Info("pesto: client closed")
+	c.httpClient.CloseIdleConnections()
+	return err
+}
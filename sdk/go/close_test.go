@@ -0,0 +1,163 @@
+package pesto_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+// SlowMockServer responds to every request once release is closed, or once the
+// request is canceled. The requests that arrive are signaled on started, if it has room.
+func SlowMockServer(started chan<- struct{}, release <-chan struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"message":"OK"}`))
+	}))
+}
+
+func TestClient_Close(t *testing.T) {
+	t.Run("WaitsForInFlight", func(t *testing.T) {
+		started := make(chan struct{}, 1)
+		release := make(chan struct{})
+		server := SlowMockServer(started, release)
+		defer server.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{Token: token, BaseURL: mustParseURL(t, server.URL)})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		pingErr := make(chan error, 1)
+		go func() {
+			_, err := client.Ping(ctx)
+			pingErr <- err
+		}()
+		<-started
+
+		closed := make(chan error, 1)
+		go func() {
+			closed <- client.Close(ctx)
+		}()
+
+		// New calls are rejected as soon as Close is called. The calls that are
+		// sent before Close is called are stuck on the server, so they time out.
+		deadline := time.Now().Add(time.Second * 5)
+		for time.Now().Before(deadline) {
+			shortCtx, shortCancel := context.WithTimeout(ctx, time.Millisecond*10)
+			_, err = client.ListRuntimes(shortCtx)
+			shortCancel()
+			if errors.Is(err, pesto.ErrClientClosed) {
+				break
+			}
+		}
+		if !errors.Is(err, pesto.ErrClientClosed) {
+			t.Errorf("expecting an error of ErrClientClosed, instead got %v", err)
+		}
+
+		select {
+		case err := <-closed:
+			t.Fatalf("expecting Close to wait for the call in flight, returned %v", err)
+		case <-time.After(time.Millisecond * 50):
+		}
+
+		close(release)
+
+		if err := <-pingErr; err != nil {
+			t.Errorf("expecting the call in flight to succeed, instead got %v", err)
+		}
+
+		if err := <-closed; err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+
+		// Closing again is a no-op.
+		if err := client.Close(ctx); err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+	})
+
+	t.Run("CancelsInFlight", func(t *testing.T) {
+		started := make(chan struct{}, 1)
+		release := make(chan struct{})
+		server := SlowMockServer(started, release)
+		defer server.Close()
+		defer close(release)
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{Token: token, BaseURL: mustParseURL(t, server.URL)})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		executeErr := make(chan error, 1)
+		go func() {
+			_, err := client.Execute(ctx, pesto.CodeRequest{
+				Language: pesto.LanguagePython,
+				Version:  pesto.VersionPython,
+				Code:     "while True: pass",
+			})
+			executeErr <- err
+		}()
+		<-started
+
+		closeCtx, closeCancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+		defer closeCancel()
+
+		err = client.Close(closeCtx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expecting an error of context.DeadlineExceeded, instead got %v", err)
+		}
+
+		if err := <-executeErr; !errors.Is(err, context.Canceled) {
+			t.Errorf("expecting the call in flight to be canceled, instead got %v", err)
+		}
+	})
+
+	t.Run("Idle", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:               token,
+			BaseURL:             happyMockServerURL,
+			HealthCheckInterval: time.Millisecond * 10,
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		if err := client.Close(ctx); err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+
+		_, err = client.Execute(ctx, pesto.CodeRequest{
+			Language: pesto.LanguagePython,
+			Version:  pesto.VersionPython,
+			Code:     "print('Hello World')",
+		})
+		if !errors.Is(err, pesto.ErrClientClosed) {
+			t.Errorf("expecting an error of ErrClientClosed, instead got %v", err)
+		}
+	})
+}
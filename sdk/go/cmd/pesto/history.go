@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// history stores the submitted snippets on disk, in a file per language.
+// Every line of the file is a JSON string, since a snippet spans multiple lines.
+type history struct {
+	// dir is where the files are stored. History is disabled if dir is empty.
+	dir string
+}
+
+// defaultHistoryDir returns the pesto/history directory of the user's config directory.
+func defaultHistoryDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "pesto", "history")
+}
+
+func (h history) path(language string) string {
+	return filepath.Join(h.dir, url.PathEscape(strings.ToLower(language))+".jsonl")
+}
+
+// append adds the snippet to the history of the language.
+func (h history) append(language string, snippet string) error {
+	if h.dir == "" {
+		return nil
+	}
+
+	err := os.MkdirAll(h.dir, 0o700)
+	if err != nil {
+		return fmt.Errorf("creating history directory: %w", err)
+	}
+
+	line, err := json.Marshal(snippet)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(h.path(language), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening history: %w", err)
+	}
+
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("writing history: %w", err)
+	}
+
+	return file.Close()
+}
+
+// load returns the snippets of the language, the oldest first.
+func (h history) load(language string) ([]string, error) {
+	if h.dir == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(h.path(language))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening history: %w", err)
+	}
+	defer file.Close()
+
+	var snippets []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var snippet string
+		// A corrupted line is skipped instead of losing the whole history.
+		if json.Unmarshal(scanner.Bytes(), &snippet) == nil {
+			snippets = append(snippets, snippet)
+		}
+	}
+
+	return snippets, scanner.Err()
+}
@@ -0,0 +1,227 @@
+// Command pesto runs code on Pesto's API from the terminal.
+//
+// Usage:
+//
+//	pesto <command> [flags]
+//
+// The commands are:
+//
+//	repl    run snippets interactively
+//	watch   run a file or a directory every time it changes
+//	replay  run a recorded bundle again, and compare the results
+//
+// The token is read from the -token flag or the PESTO_TOKEN environment variable,
+// and the base URL from the -base-url flag or the PESTO_BASE_URL environment variable.
+// Colors are disabled with the -no-color flag, the NO_COLOR environment variable,
+// or when the output is not a terminal.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/diff"
+)
+
+// backend is what the commands need from pesto.Client.
+type backend interface {
+	pesto.Executor
+	ListRuntimes(ctx context.Context) (pesto.RuntimeResponse, error)
+}
+
+// command is a subcommand of the CLI.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string, stdio stdio) error
+}
+
+// stdio holds the standard streams, so the commands can be tested.
+type stdio struct {
+	in  io.Reader
+	out io.Writer
+	err io.Writer
+	// terminal tells whether out is a terminal, which enables the colors.
+	terminal bool
+}
+
+var commands = []command{
+	{name: "repl", summary: "run snippets interactively", run: runRepl},
+	{name: "watch", summary: "run a file or a directory every time it changes", run: runWatch},
+	{name: "replay", summary: "run a recorded bundle again, and compare the results", run: runReplay},
+}
+
+func main() {
+	streams := stdio{in: os.Stdin, out: os.Stdout, err: os.Stderr, terminal: isTerminal(os.Stdout)}
+
+	err := run(context.Background(), os.Args[1:], streams)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pesto: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, streams stdio) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(streams.err)
+		return flag.ErrHelp
+	}
+
+	for _, command := range commands {
+		if command.name == args[0] {
+			return command.run(ctx, args[1:], streams)
+		}
+	}
+
+	usage(streams.err)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: pesto <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, command := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", command.name, command.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "pesto <command> -h" for the flags of a command.`)
+}
+
+// clientFlags are the flags of every command that calls Pesto's API.
+type clientFlags struct {
+	token   string
+	baseURL string
+	noColor bool
+}
+
+func (f *clientFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.token, "token", os.Getenv("PESTO_TOKEN"), "token of Pesto's API, defaults to $PESTO_TOKEN")
+	flags.StringVar(&f.baseURL, "base-url", os.Getenv("PESTO_BASE_URL"), "base URL of Pesto's API, defaults to $PESTO_BASE_URL or the public API")
+	flags.BoolVar(&f.noColor, "no-color", false, "disable the colors")
+}
+
+func (f *clientFlags) client() (*pesto.Client, error) {
+	config := pesto.Config{Token: f.token}
+	if f.baseURL != "" {
+		baseURL, err := url.Parse(f.baseURL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing base url: %w", err)
+		}
+		config.BaseURL = baseURL
+	}
+
+	return pesto.NewClientWithConfig(config)
+}
+
+func (f *clientFlags) palette(streams stdio) palette {
+	return palette{enabled: streams.terminal && !f.noColor && os.Getenv("NO_COLOR") == ""}
+}
+
+// findRuntime finds the runtime of the language, by name or alias, and version.
+func findRuntime(runtimes pesto.RuntimeResponse, language string, version pesto.Version) (pesto.Runtime, error) {
+	name := pesto.Language(language)
+	for _, runtime := range runtimes.Runtime {
+		if strings.EqualFold(runtime.Language, language) || containsFold(runtime.Aliases, language) {
+			name = pesto.Language(runtime.Language)
+			break
+		}
+	}
+
+	runtime, ok := runtimes.Find(name, version)
+	if !ok {
+		return pesto.Runtime{}, fmt.Errorf("%w: %s %s", pesto.ErrRuntimeNotFound, language, version)
+	}
+
+	return runtime, nil
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+const (
+	colorRed    = "31"
+	colorGreen  = "32"
+	colorYellow = "33"
+	colorCyan   = "36"
+	colorDim    = "2"
+)
+
+// palette paints the text with ANSI colors, if it is enabled.
+type palette struct {
+	enabled bool
+}
+
+func (p palette) paint(color string, text string) string {
+	if !p.enabled || text == "" {
+		return text
+	}
+
+	return "\x1b[" + color + "m" + text + "\x1b[0m"
+}
+
+// printer writes the messages of the commands.
+type printer struct {
+	out   io.Writer
+	color palette
+}
+
+// print writes the text, ending with a new line.
+func (p printer) print(color string, text string) {
+	if text == "" {
+		return
+	}
+
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+
+	if color != "" {
+		text = p.color.paint(color, text)
+	}
+	fmt.Fprint(p.out, text)
+}
+
+func (p printer) info(format string, args ...any) {
+	fmt.Fprintln(p.out, p.color.paint(colorDim, fmt.Sprintf(format, args...)))
+}
+
+func (p printer) errorf(format string, args ...any) {
+	fmt.Fprintln(p.out, p.color.paint(colorRed, "error: "+fmt.Sprintf(format, args...)))
+}
+
+// printDiff writes the result as a unified diff between the two outputs.
+func (p printer) printDiff(result diff.Result, expectedLabel string, actualLabel string) {
+	fmt.Fprint(p.out, result.Unified(diff.Options{
+		Color:         p.color.enabled,
+		ExpectedLabel: expectedLabel,
+		ActualLabel:   actualLabel,
+	}))
+}
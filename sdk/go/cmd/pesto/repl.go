@@ -0,0 +1,515 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+const replHelp = `Type the code, then an empty line to run it. The commands are:
+  :lang <language>            switch the language, by name or alias
+  :version <version>          switch the version, or "latest"
+  :timeout [compile] <dur>    set the run (or compile) timeout, 0 for the default
+  :files                      list the files that are sent along the code
+  :files add <path>...        add files from the disk
+  :files remove <name>        remove a file
+  :files clear                remove every file
+  :replay [on|off]            run the previous cells before the code, for interpreted languages
+  :reset                      forget the previous cells and the code being typed
+  :history [n]                show the last n snippets of the language
+  :recall <n>                 put the snippet n of the history back in the editor
+  :help                       show this help
+  :quit                       exit`
+
+func runRepl(ctx context.Context, args []string, streams stdio) error {
+	flags := flag.NewFlagSet("repl", flag.ContinueOnError)
+	flags.SetOutput(streams.err)
+
+	var client clientFlags
+	client.register(flags)
+
+	language := flags.String("lang", "", "language to start with, by name or alias (required)")
+	version := flags.String("version", string(pesto.VersionLatest), "version of the language")
+	timeout := flags.Duration("timeout", 0, "run timeout, defaults to the server's")
+	replay := flags.Bool("replay", false, "run the previous cells before the code, for interpreted languages")
+	historyDir := flags.String("history-dir", defaultHistoryDir(), "directory of the history files, empty to disable")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *language == "" {
+		return errors.New("missing -lang")
+	}
+
+	executor, err := client.client()
+	if err != nil {
+		return err
+	}
+	defer executor.Close(context.Background())
+
+	r := &repl{
+		backend:    executor,
+		history:    history{dir: *historyDir},
+		printer:    printer{out: streams.out, color: client.palette(streams)},
+		in:         bufio.NewScanner(streams.in),
+		version:    pesto.Version(*version),
+		runTimeout: *timeout,
+		replay:     *replay,
+	}
+
+	return r.run(ctx, *language)
+}
+
+// repl reads snippets from the input, runs them and prints their output.
+type repl struct {
+	printer
+	backend backend
+	history history
+	in      *bufio.Scanner
+
+	// runtimes is the result of ListRuntimes, which is loaded once.
+	runtimes       *pesto.RuntimeResponse
+	language       pesto.Language
+	version        pesto.Version
+	runtime        pesto.Runtime
+	compileTimeout time.Duration
+	runTimeout     time.Duration
+	files          []pesto.File
+	// buffer holds the lines of the snippet being typed.
+	buffer []string
+
+	// replay runs the cells before the snippet. The output of the cells is
+	// removed from the output, so only the output of the snippet is printed.
+	replay   bool
+	cells    []string
+	previous pesto.Output
+}
+
+func (r *repl) run(ctx context.Context, language string) error {
+	err := r.switchLanguage(ctx, language)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(r.out, "%s %s. Type :help for the commands, an empty line runs the code.\n", r.language, r.version)
+
+	for {
+		prompt := ">>> "
+		if len(r.buffer) > 0 {
+			prompt = "... "
+		}
+		fmt.Fprint(r.out, r.color.paint(colorCyan, prompt))
+
+		if !r.in.Scan() {
+			fmt.Fprintln(r.out)
+			if len(r.buffer) > 0 {
+				r.submit(ctx)
+			}
+			return r.in.Err()
+		}
+
+		line := r.in.Text()
+		if name, args, ok := r.parseCommand(line); ok {
+			if name == "quit" || name == "exit" {
+				return nil
+			}
+
+			err := r.command(ctx, name, args)
+			if err != nil {
+				r.errorf("%s", err.Error())
+			}
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			if len(r.buffer) > 0 {
+				r.submit(ctx)
+			}
+			continue
+		}
+
+		r.buffer = append(r.buffer, line)
+	}
+}
+
+var replCommands = map[string]bool{
+	"lang": true, "version": true, "timeout": true, "files": true, "replay": true,
+	"reset": true, "history": true, "recall": true, "help": true, "quit": true, "exit": true,
+}
+
+// parseCommand reads a meta command. A line that starts with ':' but is not
+// a known command, such as a Ruby symbol, is treated as code.
+func (r *repl) parseCommand(line string) (string, []string, bool) {
+	if !strings.HasPrefix(line, ":") {
+		return "", nil, false
+	}
+
+	fields := strings.Fields(line[1:])
+	if len(fields) == 0 || !replCommands[fields[0]] {
+		return "", nil, false
+	}
+
+	return fields[0], fields[1:], true
+}
+
+func (r *repl) command(ctx context.Context, name string, args []string) error {
+	switch name {
+	case "help":
+		fmt.Fprintln(r.out, replHelp)
+	case "lang":
+		if len(args) != 1 {
+			return errors.New("usage: :lang <language>")
+		}
+
+		r.version = pesto.VersionLatest
+		err := r.switchLanguage(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		r.info("switched to %s %s", r.language, r.version)
+	case "version":
+		if len(args) != 1 {
+			return errors.New("usage: :version <version>")
+		}
+
+		previous := r.version
+		r.version = pesto.Version(args[0])
+		err := r.switchLanguage(ctx, string(r.language))
+		if err != nil {
+			r.version = previous
+			return err
+		}
+		r.info("switched to %s %s", r.language, r.version)
+	case "timeout":
+		return r.timeoutCommand(args)
+	case "files":
+		return r.filesCommand(args)
+	case "replay":
+		if len(args) > 1 || len(args) == 1 && args[0] != "on" && args[0] != "off" {
+			return errors.New("usage: :replay [on|off]")
+		}
+
+		if len(args) == 1 {
+			r.replay = args[0] == "on"
+			r.resetCells()
+		}
+
+		if r.replay && r.runtime.Compiled {
+			r.info("replay is on, but %s is compiled, so the cells are not replayed", r.language)
+			return nil
+		}
+		r.info("replay is %s", onOff(r.replay))
+	case "reset":
+		r.buffer = nil
+		r.resetCells()
+		r.info("the cells and the editor are cleared")
+	case "history":
+		return r.historyCommand(args)
+	case "recall":
+		return r.recallCommand(args)
+	}
+
+	return nil
+}
+
+func (r *repl) timeoutCommand(args []string) error {
+	target := &r.runTimeout
+	if len(args) > 0 && args[0] == "compile" {
+		target = &r.compileTimeout
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		r.info("compile timeout is %s, run timeout is %s", durationOrDefault(r.compileTimeout), durationOrDefault(r.runTimeout))
+		return nil
+	}
+
+	if len(args) != 1 {
+		return errors.New("usage: :timeout [compile] <duration>")
+	}
+
+	timeout, err := time.ParseDuration(args[0])
+	if err != nil || timeout < 0 {
+		return fmt.Errorf("invalid duration %q", args[0])
+	}
+
+	*target = timeout
+	r.info("compile timeout is %s, run timeout is %s", durationOrDefault(r.compileTimeout), durationOrDefault(r.runTimeout))
+	return nil
+}
+
+func (r *repl) filesCommand(args []string) error {
+	if len(args) == 0 {
+		if len(r.files) == 0 {
+			r.info("no files, the code is sent alone")
+			return nil
+		}
+
+		for _, file := range r.files {
+			r.info("%s (%d bytes)", file.Name, len(file.Code))
+		}
+		r.info("the code is sent as %s", r.mainFileName())
+		return nil
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) < 2 {
+			return errors.New("usage: :files add <path>...")
+		}
+
+		for _, path := range args[1:] {
+			code, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			name := filepath.Base(path)
+			r.removeFile(name)
+			r.files = append(r.files, pesto.File{Name: name, Code: string(code)})
+			r.info("added %s", name)
+		}
+	case "remove":
+		if len(args) != 2 {
+			return errors.New("usage: :files remove <name>")
+		}
+
+		if !r.removeFile(args[1]) {
+			return fmt.Errorf("no file named %q", args[1])
+		}
+		r.info("removed %s", args[1])
+	case "clear":
+		r.files = nil
+		r.info("removed every file")
+	default:
+		return errors.New("usage: :files [add <path>... | remove <name> | clear]")
+	}
+
+	return nil
+}
+
+func (r *repl) removeFile(name string) bool {
+	for i, file := range r.files {
+		if file.Name == name {
+			r.files = append(r.files[:i], r.files[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *repl) historyCommand(args []string) error {
+	n := 10
+	if len(args) == 1 {
+		var err error
+		n, err = strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid count %q", args[0])
+		}
+	}
+
+	snippets, err := r.history.load(string(r.language))
+	if err != nil {
+		return err
+	}
+
+	start := len(snippets) - n
+	if start < 0 {
+		start = 0
+	}
+
+	for i := start; i < len(snippets); i++ {
+		fmt.Fprintf(r.out, "%s %s\n", r.color.paint(colorDim, fmt.Sprintf("[%d]", i+1)), strings.ReplaceAll(snippets[i], "\n", "\n    "))
+	}
+
+	return nil
+}
+
+func (r *repl) recallCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: :recall <n>")
+	}
+
+	snippets, err := r.history.load(string(r.language))
+	if err != nil {
+		return err
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 || n > len(snippets) {
+		return fmt.Errorf("no snippet %q in the history", args[0])
+	}
+
+	r.buffer = strings.Split(snippets[n-1], "\n")
+	for _, line := range r.buffer {
+		fmt.Fprintf(r.out, "%s%s\n", r.color.paint(colorCyan, "... "), line)
+	}
+
+	return nil
+}
+
+// switchLanguage resolves the language, by name or alias, and r.version
+// with the runtimes of the server. If the runtimes cannot be listed,
+// the language and version are used as they are.
+func (r *repl) switchLanguage(ctx context.Context, language string) error {
+	if r.runtimes == nil {
+		runtimes, err := r.backend.ListRuntimes(ctx)
+		if err != nil {
+			r.errorf("listing the runtimes: %s", err.Error())
+			r.language = pesto.Language(language)
+			r.runtime = pesto.Runtime{Language: language, Version: string(r.version)}
+			r.resetCells()
+			return nil
+		}
+		r.runtimes = &runtimes
+	}
+
+	runtime, err := findRuntime(*r.runtimes, language, r.version)
+	if err != nil {
+		return err
+	}
+
+	name := pesto.Language(runtime.Language)
+	if name != r.language {
+		r.resetCells()
+	}
+
+	r.language = name
+	r.runtime = runtime
+	if r.version == "" {
+		r.version = pesto.VersionLatest
+	}
+
+	return nil
+}
+
+// submit runs the snippet in the buffer and prints its output.
+func (r *repl) submit(ctx context.Context) {
+	snippet := strings.Join(r.buffer, "\n")
+	r.buffer = nil
+
+	err := r.history.append(string(r.language), snippet)
+	if err != nil {
+		r.errorf("saving history: %s", err.Error())
+	}
+
+	replay := r.replay && !r.runtime.Compiled
+	code := snippet
+	if replay && len(r.cells) > 0 {
+		code = strings.Join(append(r.cells[:len(r.cells):len(r.cells)], snippet), "\n")
+	}
+
+	request := pesto.CodeRequest{
+		Language:       r.language,
+		Version:        r.version,
+		CompileTimeout: r.compileTimeout,
+		RunTimeout:     r.runTimeout,
+	}
+
+	if len(r.files) > 0 {
+		request.Files = append(request.Files, r.files...)
+		request.Files = append(request.Files, pesto.File{Name: r.mainFileName(), Code: code, Entrypoint: true})
+	} else {
+		request.Code = code
+	}
+
+	err = request.Validate(r.runtime)
+	if err != nil {
+		r.errorf("%s", err.Error())
+		return
+	}
+
+	// Ctrl+C cancels the execution instead of exiting the REPL.
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	response, err := r.backend.Execute(ctx, request)
+	if err != nil {
+		r.errorf("%s", err.Error())
+		return
+	}
+
+	if response.Compile.ExitCode != 0 {
+		r.print(colorYellow, response.Compile.Stdout)
+		r.print(colorYellow, response.Compile.Stderr)
+		r.status(response, response.Compile, "compilation failed")
+		return
+	}
+
+	output := response.Runtime
+	if replay {
+		output.Stdout = strings.TrimPrefix(output.Stdout, r.previous.Stdout)
+		output.Stderr = strings.TrimPrefix(output.Stderr, r.previous.Stderr)
+	}
+
+	r.print("", output.Stdout)
+	r.print(colorRed, output.Stderr)
+	r.status(response, response.Runtime, "exit code "+strconv.Itoa(response.Runtime.ExitCode))
+
+	if replay && response.Runtime.ExitCode == 0 && !response.Metadata.TimedOut {
+		r.cells = append(r.cells, snippet)
+		r.previous = response.Runtime
+	}
+}
+
+// status prints why the execution failed, if it did.
+func (r *repl) status(response pesto.CodeResponse, output pesto.Output, failure string) {
+	switch {
+	case response.Metadata.TimedOut:
+		r.errorf("timed out")
+	case output.ExitCode != 0:
+		r.errorf("%s", failure)
+	}
+
+	if output.Truncated {
+		r.info("the output was truncated")
+	}
+}
+
+// mainFileName is the name of the file the snippet is sent as, when there are files.
+func (r *repl) mainFileName() string {
+	extension := r.runtime.Extension
+	if extension == "" && len(r.files) > 0 {
+		extension = strings.TrimPrefix(filepath.Ext(r.files[0].Name), ".")
+	}
+
+	if extension == "" {
+		return "main"
+	}
+
+	return "main." + extension
+}
+
+func (r *repl) resetCells() {
+	r.cells = nil
+	r.previous = pesto.Output{}
+}
+
+func durationOrDefault(d time.Duration) string {
+	if d == 0 {
+		return "the default"
+	}
+
+	return d.String()
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+
+	return "off"
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/pestotest"
+)
+
+const testToken = "testing-token"
+
+var shouldNotLimitMemory = false
+
+var testRuntimes = []pesto.Runtime{
+	{Language: "Python", Version: "3.10.2", Aliases: []string{"py", "python3"}, Extension: "py", ShouldLimitMemory: &shouldNotLimitMemory, AllowedEntrypoints: 1},
+	{Language: "Python", Version: "3.9.0", Aliases: []string{"py", "python3"}, Extension: "py", AllowedEntrypoints: 1},
+	{Language: "Go", Version: "1.20.2", Aliases: []string{"golang"}, Compiled: true, Extension: "go", AllowedEntrypoints: 1},
+}
+
+// echoCode is an ExecuteFunc that prints the code, like an interpreter that
+// prints every line it runs.
+func echoCode(runtime pesto.Runtime, request pestotest.ExecuteRequest) pesto.CodeResponse {
+	code := ""
+	if request.Code != nil {
+		code = *request.Code
+	}
+
+	for _, file := range request.Files {
+		if file.Entrypoint {
+			code = file.Code
+		}
+	}
+
+	if strings.Contains(code, "fail") {
+		return pesto.CodeResponse{Runtime: pesto.Output{Stdout: code + "\n", Stderr: "failed\n", ExitCode: 1}}
+	}
+
+	return pesto.CodeResponse{Runtime: pesto.Output{Stdout: code + "\n"}}
+}
+
+type testRepl struct {
+	repl     *repl
+	out      *bytes.Buffer
+	requests *[]pestotest.ExecuteRequest
+}
+
+func newTestRepl(t *testing.T, input string) testRepl {
+	t.Helper()
+
+	var requests []pestotest.ExecuteRequest
+	server := pestotest.NewServer(
+		pestotest.WithToken(testToken, 100),
+		pestotest.WithRuntimes(testRuntimes...),
+		pestotest.WithExecuteFunc(func(runtime pesto.Runtime, request pestotest.ExecuteRequest) pesto.CodeResponse {
+			requests = append(requests, request)
+			return echoCode(runtime, request)
+		}),
+	)
+	t.Cleanup(server.Close)
+
+	client, err := pesto.NewClientWithConfig(pesto.Config{Token: testToken, BaseURL: server.BaseURL()})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	out := &bytes.Buffer{}
+	return testRepl{
+		repl: &repl{
+			printer: printer{out: out},
+			backend: client,
+			history: history{dir: t.TempDir()},
+			in:      bufio.NewScanner(strings.NewReader(input)),
+			version: pesto.VersionLatest,
+		},
+		out:      out,
+		requests: &requests,
+	}
+}
+
+func (r testRepl) run(t *testing.T, language string) string {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	err := r.repl.run(ctx, language)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	return r.out.String()
+}
+
+func TestRepl(t *testing.T) {
+	t.Run("Submit", func(t *testing.T) {
+		r := newTestRepl(t, "print('a')\nprint('b')\n\n:quit\nprint('never')\n\n")
+		out := r.run(t, "PY")
+
+		if !strings.HasPrefix(out, "Python latest.") {
+			t.Errorf("expecting the alias to be resolved, got %q", out)
+		}
+
+		if len(*r.requests) != 1 {
+			t.Fatalf("expecting 1 request, got %d", len(*r.requests))
+		}
+
+		request := (*r.requests)[0]
+		if request.Language != "Python" || request.Version != "latest" || *request.Code != "print('a')\nprint('b')" {
+			t.Errorf("unexpected request: %+v", request)
+		}
+
+		if !strings.Contains(out, "print('a')\nprint('b')\n") {
+			t.Errorf("expecting the output to be printed, got %q", out)
+		}
+	})
+
+	t.Run("SubmitOnEOF", func(t *testing.T) {
+		r := newTestRepl(t, "print('a')")
+		r.run(t, "python")
+
+		if len(*r.requests) != 1 {
+			t.Errorf("expecting the buffer to be submitted on EOF, got %d requests", len(*r.requests))
+		}
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		r := newTestRepl(t, "fail()\n\n")
+		out := r.run(t, "python")
+
+		if !strings.Contains(out, "failed\n") || !strings.Contains(out, "error: exit code 1") {
+			t.Errorf("expecting stderr and the exit code to be printed, got %q", out)
+		}
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		r := newTestRepl(t, "a = 1\n\nfail()\n\nb = 2\n\n:replay off\nc = 3\n\n")
+		r.repl.replay = true
+		out := r.run(t, "python")
+
+		codes := make([]string, 0, len(*r.requests))
+		for _, request := range *r.requests {
+			codes = append(codes, *request.Code)
+		}
+
+		expected := []string{"a = 1", "a = 1\nfail()", "a = 1\nb = 2", "c = 3"}
+		if !reflect.DeepEqual(codes, expected) {
+			t.Errorf("expecting codes %q, got %q", expected, codes)
+		}
+
+		if strings.Count(out, "a = 1\n") != 1 {
+			t.Errorf("expecting the output of the replayed cells to be hidden, got %q", out)
+		}
+	})
+
+	t.Run("NoReplayForCompiled", func(t *testing.T) {
+		r := newTestRepl(t, "a := 1\n\nb := 2\n\n")
+		r.repl.replay = true
+		r.run(t, "golang")
+
+		if code := *(*r.requests)[1].Code; code != "b := 2" {
+			t.Errorf("expecting compiled languages to not replay, got %q", code)
+		}
+	})
+
+	t.Run("LangAndVersion", func(t *testing.T) {
+		r := newTestRepl(t, ":version 3.9.0\n:lang rust\n:lang golang\nfmt.Println()\n\n:version 0.1\n")
+		out := r.run(t, "python")
+
+		if !strings.Contains(out, "switched to Python 3.9.0") {
+			t.Errorf("expecting the version to be switched, got %q", out)
+		}
+
+		if !strings.Contains(out, "error: runtime not found: rust latest") {
+			t.Errorf("expecting an unknown language to be refused, got %q", out)
+		}
+
+		if !strings.Contains(out, "error: runtime not found: Go 0.1") {
+			t.Errorf("expecting an unknown version to be refused, got %q", out)
+		}
+
+		if request := (*r.requests)[0]; request.Language != "Go" || request.Version != "latest" {
+			t.Errorf("expecting the language to be switched, got %+v", request)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		r := newTestRepl(t, ":timeout 5s\n:timeout compile 2s\nprint()\n\n:timeout 45s\nprint()\n\n")
+		out := r.run(t, "python")
+
+		if len(*r.requests) != 1 {
+			t.Fatalf("expecting the invalid request to not be sent, got %d requests", len(*r.requests))
+		}
+
+		if request := (*r.requests)[0]; *request.RunTimeout != 5000 || *request.CompileTimeout != 2000 {
+			t.Errorf("expecting the timeouts to be sent, got %+v", request)
+		}
+
+		if !strings.Contains(out, "error: invalid request: run timeout of 45s exceeds 30s") {
+			t.Errorf("expecting the validation error to be printed, got %q", out)
+		}
+	})
+
+	t.Run("Files", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "greet.py")
+		err := os.WriteFile(path, []byte("def greet(): print('hi')"), 0o600)
+		if err != nil {
+			t.Fatalf("writing file: %s", err.Error())
+		}
+
+		r := newTestRepl(t, ":files add "+path+"\n:files\nimport greet\n\n:files remove greet.py\n:files remove greet.py\nprint()\n\n")
+		out := r.run(t, "python")
+
+		expected := []pestotest.ExecuteFile{
+			{Name: "greet.py", Code: "def greet(): print('hi')"},
+			{Name: "main.py", Code: "import greet", Entrypoint: true},
+		}
+		if files := (*r.requests)[0].Files; !reflect.DeepEqual(files, expected) {
+			t.Errorf("expecting files %+v, got %+v", expected, files)
+		}
+
+		if (*r.requests)[1].Files != nil {
+			t.Errorf("expecting the file to be removed, got %+v", (*r.requests)[1].Files)
+		}
+
+		if !strings.Contains(out, "the code is sent as main.py") || !strings.Contains(out, `error: no file named "greet.py"`) {
+			t.Errorf("unexpected output: %q", out)
+		}
+	})
+
+	t.Run("History", func(t *testing.T) {
+		r := newTestRepl(t, "first\n\nsecond\nline\n\n:lang golang\n:history\n:lang python\n:history 1\n:recall 1\n\n")
+		out := r.run(t, "python")
+
+		snippets, err := r.repl.history.load("Python")
+		if err != nil {
+			t.Fatalf("loading history: %s", err.Error())
+		}
+
+		expected := []string{"first", "second\nline", "first"}
+		if !reflect.DeepEqual(snippets, expected) {
+			t.Errorf("expecting history %q, got %q", expected, snippets)
+		}
+
+		if !strings.Contains(out, "[2] second\n    line\n") || strings.Contains(out, "[1] first\n[2]") {
+			t.Errorf("expecting the last snippet of Python to be listed, got %q", out)
+		}
+
+		if codes := len(*r.requests); codes != 3 || *(*r.requests)[2].Code != "first" {
+			t.Errorf("expecting the recalled snippet to run, got %d requests", codes)
+		}
+	})
+
+	t.Run("UnknownCommandIsCode", func(t *testing.T) {
+		r := newTestRepl(t, ":symbol\n\n")
+		r.run(t, "python")
+
+		if code := *(*r.requests)[0].Code; code != ":symbol" {
+			t.Errorf("expecting an unknown command to be sent as code, got %q", code)
+		}
+	})
+}
+
+func TestRun(t *testing.T) {
+	var stderr bytes.Buffer
+	streams := stdio{in: strings.NewReader(""), out: &bytes.Buffer{}, err: &stderr}
+
+	err := run(context.Background(), []string{"unknown"}, streams)
+	if err == nil || err.Error() != `unknown command "unknown"` {
+		t.Errorf("expecting an unknown command error, instead got %v", err)
+	}
+
+	if !strings.Contains(stderr.String(), "repl") {
+		t.Errorf("expecting the usage to list the commands, got %q", stderr.String())
+	}
+
+	err = run(context.Background(), []string{"repl", "-token", testToken}, streams)
+	if err == nil || err.Error() != "missing -lang" {
+		t.Errorf("expecting an error of missing -lang, instead got %v", err)
+	}
+
+	err = run(context.Background(), nil, streams)
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("expecting an error of flag.ErrHelp, instead got %v", err)
+	}
+}
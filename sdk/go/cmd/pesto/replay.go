@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/diff"
+)
+
+// errReplayDiffers makes the replay command exit with a failure,
+// after the differences were printed.
+var errReplayDiffers = errors.New("the replay differs from the recorded run")
+
+func runReplay(ctx context.Context, args []string, streams stdio) error {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.SetOutput(streams.err)
+	flags.Usage = func() {
+		fmt.Fprintln(streams.err, "Usage: pesto replay [flags] <bundle.json or bundle.tar>")
+		flags.PrintDefaults()
+	}
+
+	var client clientFlags
+	client.register(flags)
+
+	save := flags.String("save", "", "file to save the bundle of the replay to, as JSON, or as tar if it ends with .tar")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expecting a single bundle")
+	}
+
+	recorded, err := readBundleFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	executor, err := client.client()
+	if err != nil {
+		return err
+	}
+	defer executor.Close(context.Background())
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	p := printer{out: streams.out, color: client.palette(streams)}
+	replayed, matches := replay(ctx, p, executor, recorded)
+
+	if *save != "" {
+		err = writeBundleFile(*save, replayed)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !matches {
+		return errReplayDiffers
+	}
+
+	return nil
+}
+
+// replay runs the recorded bundle again, and prints the differences between
+// the recorded run and the replay. It reports whether they match.
+func replay(ctx context.Context, p printer, executor pesto.Executor, recorded pesto.Bundle) (pesto.Bundle, bool) {
+	request := recorded.ReplayRequest()
+	p.info("replaying %s %s, recorded at %s with SDK %s", request.Language, request.Version, recorded.StartedAt.Format(time.RFC3339), recorded.SDKVersion)
+
+	// The error is recorded in the replayed bundle, and compared below.
+	replayed, _ := recorded.Replay(ctx, executor)
+
+	before, after := recorded.Response, replayed.Response
+	matches := true
+	for _, field := range []struct {
+		name              string
+		recorded, current string
+	}{
+		{name: "error", recorded: recorded.Error, current: replayed.Error},
+		{name: "version", recorded: before.Version, current: after.Version},
+		{name: "compile exit code", recorded: fmt.Sprint(before.Compile.ExitCode), current: fmt.Sprint(after.Compile.ExitCode)},
+		{name: "runtime exit code", recorded: fmt.Sprint(before.Runtime.ExitCode), current: fmt.Sprint(after.Runtime.ExitCode)},
+	} {
+		if field.recorded != field.current {
+			p.errorf("%s differs: recorded %q, replayed %q", field.name, field.recorded, field.current)
+			matches = false
+		}
+	}
+
+	for _, output := range []struct {
+		name              string
+		recorded, current string
+	}{
+		{name: "compile stdout", recorded: before.Compile.Stdout, current: after.Compile.Stdout},
+		{name: "compile stderr", recorded: before.Compile.Stderr, current: after.Compile.Stderr},
+		{name: "runtime stdout", recorded: before.Runtime.Stdout, current: after.Runtime.Stdout},
+		{name: "runtime stderr", recorded: before.Runtime.Stderr, current: after.Runtime.Stderr},
+	} {
+		result := diff.Compare(output.recorded, output.current)
+		if !result.Equal {
+			p.errorf("%s differs:", output.name)
+			p.printDiff(result, "recorded", "replayed")
+			matches = false
+		}
+	}
+
+	if matches {
+		p.print(colorGreen, "the replay matches the recorded run")
+	}
+
+	return replayed, matches
+}
+
+func readBundleFile(path string) (pesto.Bundle, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return pesto.Bundle{}, err
+	}
+	defer file.Close()
+
+	bundle, err := pesto.ReadBundle(file)
+	if err != nil {
+		return pesto.Bundle{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	return bundle, nil
+}
+
+func writeBundleFile(path string, bundle pesto.Bundle) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(path, ".tar") {
+		err = bundle.WriteTar(file)
+	} else {
+		err = bundle.WriteJSON(file)
+	}
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	return file.Close()
+}
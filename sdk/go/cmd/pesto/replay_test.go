@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/pestotest"
+)
+
+func TestReplay(t *testing.T) {
+	server := pestotest.NewServer(
+		pestotest.WithToken(testToken, 100),
+		pestotest.WithRuntimes(testRuntimes...),
+		pestotest.WithExecuteFunc(echoCode),
+	)
+	defer server.Close()
+
+	recorded := pesto.Bundle{
+		Request:    pesto.CodeRequest{Language: "Python", Version: pesto.VersionLatest, Code: "print('hi')"},
+		Response:   pesto.CodeResponse{Language: "Python", Version: "3.9.0", Runtime: pesto.Output{Stdout: "print('hi')\n"}},
+		StartedAt:  time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC),
+		SDKVersion: pesto.SDKVersion,
+	}
+
+	tests := []struct {
+		name     string
+		recorded func(b *pesto.Bundle)
+		file     string
+		err      error
+		expected []string
+	}{
+		{
+			name:     "Matches",
+			file:     "bundle.json",
+			expected: []string{"replaying Python 3.9.0, recorded at 2023-03-01T10:00:00Z with SDK " + pesto.SDKVersion, "the replay matches the recorded run"},
+		},
+		{
+			name:     "Tar",
+			file:     "bundle.tar",
+			expected: []string{"the replay matches the recorded run"},
+		},
+		{
+			name: "Differs",
+			recorded: func(b *pesto.Bundle) {
+				b.Response.Runtime.Stdout = "print('hello')\n"
+				b.Response.Runtime.ExitCode = 1
+			},
+			file: "bundle.json",
+			err:  errReplayDiffers,
+			expected: []string{
+				`error: runtime exit code differs: recorded "1", replayed "0"`,
+				"error: runtime stdout differs:\n--- recorded\n+++ replayed\n@@ -1 +1 @@\n-print('h[-ello-]')\n+print('h{+i+}')\n",
+			},
+		},
+		{
+			name:     "Error",
+			recorded: func(b *pesto.Bundle) { b.Request.Language = "Rust" },
+			file:     "bundle.json",
+			err:      errReplayDiffers,
+			expected: []string{`error: error differs: recorded "", replayed "runtime not found"`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bundle := recorded
+			if test.recorded != nil {
+				test.recorded(&bundle)
+			}
+
+			dir := t.TempDir()
+			path := filepath.Join(dir, test.file)
+			err := writeBundleFile(path, bundle)
+			if err != nil {
+				t.Fatalf("writing bundle: %s", err.Error())
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			var out bytes.Buffer
+			saved := filepath.Join(dir, "replayed.json")
+			args := []string{"replay", "-token", testToken, "-base-url", server.BaseURL().String(), "-save", saved, path}
+			err = run(ctx, args, stdio{in: strings.NewReader(""), out: &out, err: &out})
+			if !errors.Is(err, test.err) {
+				t.Errorf("expecting an error of %v, instead got %v", test.err, err)
+			}
+
+			for _, expected := range test.expected {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("expecting the output to contain %q, got %q", expected, out.String())
+				}
+			}
+
+			replayed, err := readBundleFile(saved)
+			if err != nil {
+				t.Fatalf("reading the saved bundle: %s", err.Error())
+			}
+
+			if replayed.Request.Version != pesto.Version(bundle.Response.Version) {
+				t.Errorf("expecting the replay to run on the resolved version, got %s", replayed.Request.Version)
+			}
+		})
+	}
+}
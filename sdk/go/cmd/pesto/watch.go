@@ -0,0 +1,498 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/diff"
+)
+
+func runWatch(ctx context.Context, args []string, streams stdio) error {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	flags.SetOutput(streams.err)
+	flags.Usage = func() {
+		fmt.Fprintln(streams.err, "Usage: pesto watch [flags] <file or directory>")
+		flags.PrintDefaults()
+	}
+
+	var client clientFlags
+	client.register(flags)
+
+	language := flags.String("lang", "", "language of the code, by name or alias, defaults to the one of the entrypoint's extension")
+	version := flags.String("version", string(pesto.VersionLatest), "version of the language")
+	entrypoints := flags.String("entrypoint", "", "comma separated entrypoints of a directory, defaults to its main file")
+	expected := flags.String("expected", "", "file with the expected output of the code")
+	interval := flags.Duration("interval", 500*time.Millisecond, "how often the files are checked for changes")
+	debounce := flags.Duration("debounce", 200*time.Millisecond, "how long to wait for the changes to settle before running")
+	timeout := flags.Duration("timeout", 0, "run timeout, defaults to the server's")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expecting a single file or directory")
+	}
+
+	executor, err := client.client()
+	if err != nil {
+		return err
+	}
+	defer executor.Close(context.Background())
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	w := &watcher{
+		printer:    printer{out: streams.out, color: client.palette(streams)},
+		backend:    executor,
+		path:       flags.Arg(0),
+		language:   *language,
+		version:    pesto.Version(*version),
+		expected:   *expected,
+		interval:   *interval,
+		debounce:   *debounce,
+		runTimeout: *timeout,
+	}
+
+	if *entrypoints != "" {
+		w.entrypoints = strings.Split(*entrypoints, ",")
+	}
+
+	return w.run(ctx)
+}
+
+// watcher polls a file or a directory, and runs it every time it changes.
+// A run that is still in flight when a newer change arrives is canceled.
+type watcher struct {
+	printer
+	backend backend
+	// path is a file, which is sent as the code, or a directory, whose files are sent.
+	path        string
+	language    string
+	version     pesto.Version
+	entrypoints []string
+	// expected is a file with the expected output of the code.
+	expected   string
+	interval   time.Duration
+	debounce   time.Duration
+	runTimeout time.Duration
+
+	runtimes *pesto.RuntimeResponse
+	// previous is the last run that got a response.
+	previous *watchResult
+}
+
+// watchResult is the outcome of a run.
+type watchResult struct {
+	number   int
+	response pesto.CodeResponse
+	err      error
+	// expected is the content of the expected output file, if there is one.
+	expected *string
+}
+
+// fileState is what tells a file has changed.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func (w *watcher) run(ctx context.Context) error {
+	snapshot, err := w.snapshot()
+	if err != nil {
+		return err
+	}
+
+	// The runtimes are loaded once, before the runs that share them.
+	runtimes, err := w.backend.ListRuntimes(ctx)
+	if err != nil {
+		return fmt.Errorf("listing the runtimes: %w", err)
+	}
+	w.runtimes = &runtimes
+
+	w.info("watching %s, press Ctrl+C to stop", w.path)
+
+	results := make(chan watchResult)
+	cancelRun := context.CancelFunc(func() {})
+	defer func() { cancelRun() }()
+
+	runs := 0
+	running := false
+	start := func() {
+		cancelRun()
+		if running {
+			w.info("canceled run #%d, the files changed", runs)
+		}
+
+		runs++
+		running = true
+
+		var runCtx context.Context
+		runCtx, cancelRun = context.WithCancel(ctx)
+		go func(number int) {
+			result := w.execute(runCtx)
+			result.number = number
+			select {
+			case results <- result:
+			case <-ctx.Done():
+			}
+		}(runs)
+	}
+	start()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	debounce := time.NewTimer(w.debounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			next, err := w.snapshot()
+			if err != nil {
+				w.errorf("%s", err.Error())
+				continue
+			}
+
+			if !sameSnapshot(snapshot, next) {
+				snapshot = next
+				if !debounce.Stop() {
+					select {
+					case <-debounce.C:
+					default:
+					}
+				}
+				debounce.Reset(w.debounce)
+			}
+		case <-debounce.C:
+			start()
+		case result := <-results:
+			// A canceled run is stale, a newer run has started.
+			if result.number != runs {
+				continue
+			}
+
+			running = false
+			w.report(result)
+		}
+	}
+}
+
+// snapshot returns the state of the watched files.
+func (w *watcher) snapshot() (map[string]fileState, error) {
+	snapshot := make(map[string]fileState)
+	err := filepath.WalkDir(w.path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path != w.path && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		snapshot[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+
+	if w.expected != "" {
+		info, err := os.Stat(w.expected)
+		if err == nil {
+			snapshot[w.expected] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+
+	return snapshot, err
+}
+
+func sameSnapshot(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for path, state := range a {
+		if other, ok := b[path]; !ok || !other.modTime.Equal(state.modTime) || other.size != state.size {
+			return false
+		}
+	}
+
+	return true
+}
+
+// execute builds the request from the files on the disk, and runs it.
+func (w *watcher) execute(ctx context.Context) watchResult {
+	var result watchResult
+	if w.expected != "" {
+		expected, err := os.ReadFile(w.expected)
+		if err != nil {
+			result.err = err
+			return result
+		}
+
+		s := string(expected)
+		result.expected = &s
+	}
+
+	request, runtime, err := w.request()
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	err = request.Validate(runtime)
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	result.response, result.err = w.backend.Execute(ctx, request)
+	return result
+}
+
+// request builds the CodeRequest. A file is sent as the code, while the files
+// of a directory are sent with their paths relative to the directory.
+func (w *watcher) request() (pesto.CodeRequest, pesto.Runtime, error) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return pesto.CodeRequest{}, pesto.Runtime{}, err
+	}
+
+	var files []pesto.File
+	if info.IsDir() {
+		files, err = w.readDir()
+	} else {
+		var code []byte
+		code, err = os.ReadFile(w.path)
+		files = []pesto.File{{Name: filepath.Base(w.path), Code: string(code), Entrypoint: true}}
+	}
+	if err != nil {
+		return pesto.CodeRequest{}, pesto.Runtime{}, err
+	}
+
+	entrypoint := ""
+	for _, file := range files {
+		if file.Entrypoint {
+			entrypoint = file.Name
+			break
+		}
+	}
+
+	language := w.language
+	if language == "" {
+		language = w.languageOf(entrypoint)
+		if language == "" {
+			return pesto.CodeRequest{}, pesto.Runtime{}, fmt.Errorf("cannot tell the language of %s, use -lang", entrypoint)
+		}
+	}
+
+	runtime, err := findRuntime(*w.runtimes, language, w.version)
+	if err != nil {
+		return pesto.CodeRequest{}, pesto.Runtime{}, err
+	}
+
+	request := pesto.CodeRequest{
+		Language:   pesto.Language(runtime.Language),
+		Version:    w.version,
+		RunTimeout: w.runTimeout,
+	}
+
+	if info.IsDir() {
+		request.Files = files
+	} else {
+		request.Code = files[0].Code
+	}
+
+	return request, runtime, nil
+}
+
+// readDir reads the files of the directory, skipping the hidden ones and the
+// expected output file. The entrypoints are the -entrypoint files, or the
+// main file of the directory.
+func (w *watcher) readDir() ([]pesto.File, error) {
+	var files []pesto.File
+	err := filepath.WalkDir(w.path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path != w.path && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if entry.IsDir() || w.expected != "" && sameFile(path, w.expected) {
+			return nil
+		}
+
+		code, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(w.path, path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, pesto.File{Name: filepath.ToSlash(name), Code: string(code)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files in %s", w.path)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	if len(w.entrypoints) > 0 {
+		for _, entrypoint := range w.entrypoints {
+			found := false
+			for i := range files {
+				if files[i].Name == entrypoint {
+					files[i].Entrypoint = true
+					found = true
+				}
+			}
+
+			if !found {
+				return nil, fmt.Errorf("entrypoint %s is not in %s", entrypoint, w.path)
+			}
+		}
+
+		return files, nil
+	}
+
+	var mains []int
+	for i, file := range files {
+		if strings.TrimSuffix(file.Name, filepath.Ext(file.Name)) == "main" {
+			mains = append(mains, i)
+		}
+	}
+
+	if len(mains) != 1 {
+		return nil, fmt.Errorf("cannot tell the entrypoint of %s, use -entrypoint", w.path)
+	}
+
+	files[mains[0]].Entrypoint = true
+	return files, nil
+}
+
+// languageOf returns the language of the file from its extension, which is
+// either the extension or an alias of a runtime.
+func (w *watcher) languageOf(name string) string {
+	extension := strings.TrimPrefix(filepath.Ext(name), ".")
+	if extension == "" {
+		return ""
+	}
+
+	for _, runtime := range w.runtimes.Runtime {
+		if strings.EqualFold(runtime.Extension, extension) {
+			return runtime.Language
+		}
+	}
+
+	for _, runtime := range w.runtimes.Runtime {
+		if containsFold(runtime.Aliases, extension) {
+			return runtime.Language
+		}
+	}
+
+	return ""
+}
+
+// report prints the output of the run, along with the changes since the
+// previous run and the differences from the expected output.
+func (w *watcher) report(result watchResult) {
+	w.info("--- run #%d at %s ---", result.number, time.Now().Format("15:04:05"))
+	if result.err != nil {
+		w.errorf("%s", result.err.Error())
+		return
+	}
+
+	response := result.response
+	if response.Compile.ExitCode != 0 {
+		w.print(colorYellow, response.Compile.Stdout)
+		w.print(colorYellow, response.Compile.Stderr)
+		w.errorf("compilation failed")
+	} else {
+		w.print("", response.Runtime.Stdout)
+		w.print(colorRed, response.Runtime.Stderr)
+
+		switch {
+		case response.Metadata.TimedOut:
+			w.errorf("timed out")
+		case response.Runtime.ExitCode != 0:
+			w.errorf("exit code %d", response.Runtime.ExitCode)
+		}
+	}
+
+	stdout := response.Runtime.Stdout
+	if w.previous != nil {
+		changes := diff.Compare(w.previous.response.Runtime.Stdout, stdout)
+		if changes.Equal {
+			w.info("output unchanged since run #%d", w.previous.number)
+		} else {
+			w.info("changes since run #%d:", w.previous.number)
+			w.printDiff(changes, fmt.Sprintf("run #%d", w.previous.number), fmt.Sprintf("run #%d", result.number))
+		}
+	}
+
+	if result.expected != nil {
+		differences := diff.Compare(*result.expected, stdout)
+		if differences.Equal {
+			w.print(colorGreen, "output matches the expected output")
+		} else {
+			w.info("differences from the expected output:")
+			w.printDiff(differences, "expected", "actual")
+		}
+	}
+
+	w.previous = &result
+}
+
+func sameFile(a, b string) bool {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(infoA, infoB)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/pestotest"
+)
+
+// syncBuffer is a bytes.Buffer that is safe to read while the watcher writes to it.
+type syncBuffer struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.String()
+}
+
+type testWatcher struct {
+	watcher *watcher
+	out     *syncBuffer
+	// requests receives the requests the server gets.
+	requests chan pestotest.ExecuteRequest
+	done     chan error
+	cancel   context.CancelFunc
+}
+
+// newTestWatcher starts watching the path. Executing code that contains "slow"
+// blocks until the test ends.
+func newTestWatcher(t *testing.T, path string, configure func(w *watcher)) testWatcher {
+	t.Helper()
+
+	release := make(chan struct{})
+	requests := make(chan pestotest.ExecuteRequest, 100)
+	server := pestotest.NewServer(
+		pestotest.WithToken(testToken, 100),
+		pestotest.WithRuntimes(testRuntimes...),
+		pestotest.WithExecuteFunc(func(runtime pesto.Runtime, request pestotest.ExecuteRequest) pesto.CodeResponse {
+			requests <- request
+			response := echoCode(runtime, request)
+			if strings.Contains(response.Runtime.Stdout, "slow") {
+				<-release
+			}
+			return response
+		}),
+	)
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	client, err := pesto.NewClientWithConfig(pesto.Config{Token: testToken, BaseURL: server.BaseURL()})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	out := &syncBuffer{}
+	w := &watcher{
+		printer:  printer{out: out},
+		backend:  client,
+		path:     path,
+		version:  pesto.VersionLatest,
+		interval: 10 * time.Millisecond,
+		debounce: 30 * time.Millisecond,
+	}
+	if configure != nil {
+		configure(w)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.run(ctx)
+	}()
+
+	tw := testWatcher{watcher: w, out: out, requests: requests, done: done, cancel: cancel}
+	t.Cleanup(func() { tw.stop(t) })
+	return tw
+}
+
+// waitFor waits until the output contains s.
+func (w testWatcher) waitFor(t *testing.T, s string) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !strings.Contains(w.out.String(), s) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expecting the output to contain %q, got %q", s, w.out.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (w testWatcher) stop(t *testing.T) {
+	w.cancel()
+	select {
+	case err := <-w.done:
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+	case <-time.After(10 * time.Second):
+		t.Error("expecting the watcher to stop")
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		t.Fatalf("creating directory: %s", err.Error())
+	}
+
+	err = os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("writing file: %s", err.Error())
+	}
+}
+
+func TestWatcher_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.py")
+	writeFile(t, path, "a\nb")
+
+	w := newTestWatcher(t, path, nil)
+	w.waitFor(t, "--- run #1")
+
+	request := <-w.requests
+	if request.Language != "Python" || *request.Code != "a\nb" || request.Files != nil {
+		t.Errorf("expecting the file to be sent as Python code, got %+v", request)
+	}
+
+	writeFile(t, path, "a\nc")
+	w.waitFor(t, "--- run #2")
+	w.waitFor(t, "changes since run #1:\n--- run #1\n+++ run #2\n@@ -1,2 +1,2 @@\n a\n-[-b-]\n+{+c+}\n")
+
+	writeFile(t, path, "a\nc\n")
+	w.waitFor(t, "--- run #3")
+	w.waitFor(t, "changes since run #2:\n--- run #2\n+++ run #3\n@@ -1,2 +1,3 @@\n a\n c\n+\n")
+}
+
+func TestWatcher_Directory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.py"), "import lib")
+	writeFile(t, filepath.Join(dir, "lib", "greet.py"), "print('hi')")
+	writeFile(t, filepath.Join(dir, ".cache", "ignored"), "ignored")
+	expected := filepath.Join(dir, "expected.txt")
+	writeFile(t, expected, "import lib\n")
+
+	w := newTestWatcher(t, dir, func(w *watcher) { w.expected = expected })
+	w.waitFor(t, "output matches the expected output")
+
+	request := <-w.requests
+	files := []pestotest.ExecuteFile{
+		{Name: "lib/greet.py", Code: "print('hi')"},
+		{Name: "main.py", Code: "import lib", Entrypoint: true},
+	}
+	if !reflect.DeepEqual(request.Files, files) {
+		t.Errorf("expecting files %+v, got %+v", files, request.Files)
+	}
+
+	writeFile(t, expected, "import greet\n")
+	w.waitFor(t, "--- run #2")
+	w.waitFor(t, "output unchanged since run #1")
+	w.waitFor(t, "differences from the expected output:\n--- expected\n+++ actual\n@@ -1 +1 @@\n-import [-greet-]\n+import {+lib+}\n")
+}
+
+func TestWatcher_CancelsInFlight(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.py")
+	writeFile(t, path, "slow")
+
+	w := newTestWatcher(t, path, nil)
+	<-w.requests
+
+	writeFile(t, path, "faster")
+	w.waitFor(t, "canceled run #1, the files changed")
+	w.waitFor(t, "--- run #2")
+
+	if out := w.out.String(); strings.Contains(out, "run #1 at") || strings.Contains(out, "slow") {
+		t.Errorf("expecting the canceled run to not be reported, got %q", out)
+	}
+}
+
+func TestWatcher_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		configure func(w *watcher)
+		expected  string
+	}{
+		{
+			name:     "NoEntrypoint",
+			files:    map[string]string{"a.py": "", "b.py": ""},
+			expected: "error: cannot tell the entrypoint",
+		},
+		{
+			name:      "MissingEntrypoint",
+			files:     map[string]string{"a.py": ""},
+			configure: func(w *watcher) { w.entrypoints = []string{"b.py"} },
+			expected:  "error: entrypoint b.py is not in",
+		},
+		{
+			name:     "UnknownLanguage",
+			files:    map[string]string{"main.rs": "fn main() {}"},
+			expected: "error: cannot tell the language of main.rs, use -lang",
+		},
+		{
+			name:      "Validation",
+			files:     map[string]string{"a.py": "a", "b.py": "b"},
+			configure: func(w *watcher) { w.entrypoints = []string{"a.py", "b.py"} },
+			expected:  "error: invalid request: 2 entrypoints exceed the 1 allowed by Python 3.10.2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range test.files {
+				writeFile(t, filepath.Join(dir, name), content)
+			}
+
+			w := newTestWatcher(t, dir, test.configure)
+			w.waitFor(t, test.expected)
+		})
+	}
+}
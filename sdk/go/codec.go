@@ -0,0 +1,228 @@
+package pesto
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Codec encodes the request bodies and decodes the response bodies
+// for the Content-Type it handles.
+type Codec interface {
+	// ContentType returns the media type that is sent on the
+	// Content-Type and Accept request headers.
+	ContentType() string
+	// Marshal encodes v into a request body.
+	Marshal(v any) ([]byte, error)
+	// Unmarshal decodes the response body into v.
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec sends and receives application/json bodies. This is the default codec.
+type JSONCodec struct{}
+
+// ContentType implements Codec.
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+// Marshal implements Codec.
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements Codec.
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// FormCodec sends and receives application/x-www-form-urlencoded bodies.
+// It only supports the request and response types of this package.
+//
+// Every value of a form body is a string, while Pesto's API validates
+// CompileTimeout, RunTimeout and MemoryLimit as numbers, and CodeRequest.Files
+// can't be represented in a form body at all. Requests that set any of them
+// are not sent, and fail with a *ValidationError instead.
+type FormCodec struct{}
+
+// ContentType implements Codec.
+func (FormCodec) ContentType() string {
+	return "application/x-www-form-urlencoded"
+}
+
+// Marshal implements Codec.
+func (FormCodec) Marshal(v any) ([]byte, error) {
+	marshaler, ok := v.(formMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("form codec does not support %T", v)
+	}
+
+	values, err := marshaler.marshalForm()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(values.Encode()), nil
+}
+
+// Unmarshal implements Codec.
+func (FormCodec) Unmarshal(data []byte, v any) error {
+	unmarshaler, ok := v.(formUnmarshaler)
+	if !ok {
+		return fmt.Errorf("form codec does not support %T", v)
+	}
+
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+
+	return unmarshaler.unmarshalForm(values)
+}
+
+type formMarshaler interface {
+	marshalForm() (url.Values, error)
+}
+
+type formUnmarshaler interface {
+	unmarshalForm(values url.Values) error
+}
+
+// codecFor picks the codec that decodes the response body. The server does not
+// always respect the Accept header (authentication errors are always sent as JSON),
+// so the Content-Type of the response takes precedence over the client's codec.
+func (c *Client) codecFor(header http.Header) Codec {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	switch mediaType {
+	case JSONCodec{}.ContentType():
+		return JSONCodec{}
+	case FormCodec{}.ContentType():
+		return FormCodec{}
+	}
+
+	return c.codec
+}
+
+func (r codeRequestSimplified) marshalForm() (url.Values, error) {
+	var problems []string
+	if len(r.Files) > 0 {
+		problems = append(problems, "files can't be sent in a form body")
+	}
+
+	if r.CompileTimeout != 0 {
+		problems = append(problems, "compile timeout can't be sent in a form body")
+	}
+
+	if r.RunTimeout != 0 {
+		problems = append(problems, "run timeout can't be sent in a form body")
+	}
+
+	if r.MemoryLimit != 0 {
+		problems = append(problems, "memory limit can't be sent in a form body")
+	}
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+
+	values := url.Values{}
+	values.Set("language", r.Language)
+	values.Set("version", r.Version)
+	values.Set("code", r.Code)
+	return values, nil
+}
+
+func (o *Output) unmarshalForm(values url.Values) error {
+	o.Stdout = values.Get("stdout")
+	o.Stderr = values.Get("stderr")
+	o.Output = values.Get("output")
+
+	if exitCode := values.Get("exitCode"); exitCode != "" {
+		value, err := strconv.Atoi(exitCode)
+		if err != nil {
+			return fmt.Errorf("parsing exitCode: %w", err)
+		}
+
+		o.ExitCode = value
+	}
+
+	return nil
+}
+
+func (r *codeResponseBody) unmarshalForm(values url.Values) error {
+	r.Language = values.Get("language")
+	r.Version = values.Get("version")
+
+	// HACK: the server appends the runtime output under the "compile" key,
+	// so the second "compile" value is the runtime output.
+	// A "runtime" key is preferred, should the server ever send one.
+	outputs := values["compile"]
+	if runtime := values.Get("runtime"); runtime != "" {
+		outputs = []string{values.Get("compile"), runtime}
+	}
+
+	targets := []*Output{&r.Compile, &r.Runtime}
+	for i, output := range outputs {
+		if i >= len(targets) {
+			break
+		}
+
+		childValues, err := url.ParseQuery(output)
+		if err != nil {
+			return err
+		}
+
+		err = targets[i].unmarshalForm(childValues)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *PingResponse) unmarshalForm(values url.Values) error {
+	p.Message = values.Get("message")
+	return nil
+}
+
+func (r *RuntimeResponse) unmarshalForm(values url.Values) error {
+	r.Runtime = nil
+	for _, runtime := range values["runtimes"] {
+		childValues, err := url.ParseQuery(runtime)
+		if err != nil {
+			return err
+		}
+
+		runtime := Runtime{
+			Language:  childValues.Get("language"),
+			Version:   childValues.Get("version"),
+			Aliases:   childValues["aliases"],
+			Compiled:  childValues.Get("compiled") == "true",
+			Extension: childValues.Get("extension"),
+		}
+
+		if childValues.Has("shouldLimitMemory") {
+			shouldLimitMemory := childValues.Get("shouldLimitMemory") == "true"
+			runtime.ShouldLimitMemory = &shouldLimitMemory
+		}
+
+		// A missing or malformed limit is left as unknown.
+		runtime.MemoryLimit, _ = strconv.ParseInt(childValues.Get("memoryLimit"), 10, 64)
+		runtime.ProcessLimit, _ = strconv.ParseInt(childValues.Get("processLimit"), 10, 64)
+		runtime.AllowedEntrypoints, _ = strconv.ParseInt(childValues.Get("allowedEntrypoints"), 10, 64)
+
+		r.Runtime = append(r.Runtime, runtime)
+	}
+
+	return nil
+}
+
+func (e *errorResponse) unmarshalForm(values url.Values) error {
+	e.Message = values.Get("message")
+	e.Msg = values.Get("msg")
+	return nil
+}
@@ -0,0 +1,303 @@
+package pesto_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/pestotest"
+)
+
+func TestFormCodec(t *testing.T) {
+	client, err := pesto.NewClientWithConfig(pesto.Config{
+		Token:   token,
+		BaseURL: happyMockServerURL,
+		Codec:   pesto.FormCodec{},
+	})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	t.Run("Ping", func(t *testing.T) {
+		response, err := client.Ping(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if response.Message != "OK" {
+			t.Errorf("expecting response.Message to be 'OK', got %s", response.Message)
+		}
+	})
+
+	t.Run("ListRuntimes", func(t *testing.T) {
+		response, err := client.ListRuntimes(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if len(response.Runtime) != 1 {
+			t.Fatalf("expecting 1 runtime, got %d", len(response.Runtime))
+		}
+
+		runtime := response.Runtime[0]
+		if runtime.Language != "Go" || runtime.Version != "1.18.2" || !runtime.Compiled {
+			t.Errorf("unexpected runtime: %+v", runtime)
+		}
+
+		if len(runtime.Aliases) != 2 || runtime.Aliases[0] != "go" || runtime.Aliases[1] != "golang" {
+			t.Errorf("expecting aliases to be [go golang], got %v", runtime.Aliases)
+		}
+
+		if runtime.ShouldLimitMemory != nil || runtime.MemoryLimit != 0 || runtime.AllowedEntrypoints != 0 {
+			t.Errorf("expecting the fields the server does not provide to be unknown, got %+v", runtime)
+		}
+	})
+
+	t.Run("Execute", func(t *testing.T) {
+		response, err := client.Execute(ctx, pesto.CodeRequest{
+			Language: pesto.LanguagePython,
+			Version:  pesto.VersionPython,
+			Code:     "print('Hello World')",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if response.Language != "Python" || response.Version != "3.10.2" {
+			t.Errorf("unexpected language and version: %s %s", response.Language, response.Version)
+		}
+
+		if response.Compile.Output != "" {
+			t.Errorf("expecting response.Compile.Output to be empty, got %s", response.Compile.Output)
+		}
+
+		if response.Runtime.Stdout != "Hello World" || response.Runtime.ExitCode != 0 {
+			t.Errorf("unexpected runtime output: %+v", response.Runtime)
+		}
+	})
+
+	t.Run("RuntimeNotFound", func(t *testing.T) {
+		_, err := client.Execute(ctx, pesto.CodeRequest{
+			Language: pesto.Language("Rust"),
+			Version:  pesto.Version("1.64.0"),
+			Code:     "fn main() {}",
+		})
+		if !errors.Is(err, pesto.ErrRuntimeNotFound) {
+			t.Errorf("expecting an error of ErrRuntimeNotFound, instead got %v", err)
+		}
+	})
+
+	t.Run("FilesAndLimits", func(t *testing.T) {
+		tests := []pesto.CodeRequest{
+			{Language: pesto.LanguageGo, Version: pesto.VersionLatest, Files: []pesto.File{{Name: "main.go", Code: "package main", Entrypoint: true}}},
+			{Language: pesto.LanguagePython, Version: pesto.VersionPython, Code: "print(1)", CompileTimeout: 5 * time.Second},
+			{Language: pesto.LanguagePython, Version: pesto.VersionPython, Code: "print(1)", RunTimeout: 3 * time.Second},
+			{Language: pesto.LanguagePython, Version: pesto.VersionPython, Code: "print(1)", MemoryLimit: 128 * 1024 * 1024},
+		}
+
+		for _, request := range tests {
+			_, err := client.Execute(ctx, request)
+			if !errors.Is(err, pesto.ErrInvalidRequest) {
+				t.Errorf("expecting an error of ErrInvalidRequest, instead got %v", err)
+			}
+
+			var validationError *pesto.ValidationError
+			if !errors.As(err, &validationError) || len(validationError.Problems) != 1 {
+				t.Errorf("expecting a *ValidationError with a single problem, instead got %v", err)
+			}
+		}
+	})
+
+	t.Run("JSONErrorResponse", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   "invalid-token",
+			BaseURL: happyMockServerURL,
+			Codec:   pesto.FormCodec{},
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		_, err = client.Execute(ctx, pesto.CodeRequest{
+			Language: pesto.LanguagePython,
+			Version:  pesto.VersionPython,
+			Code:     "print('Hello World')",
+		})
+		if !errors.Is(err, pesto.ErrTokenNotRegistered) {
+			t.Errorf("expecting an error of ErrTokenNotRegistered, instead got %v", err)
+		}
+	})
+}
+
+func TestCodec_Headers(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec pesto.Codec
+	}{
+		{name: "Default", codec: nil},
+		{name: "JSON", codec: pesto.JSONCodec{}},
+		{name: "Form", codec: pesto.FormCodec{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expected := "application/json"
+			if test.codec != nil {
+				expected = test.codec.ContentType()
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Content-Type") != expected || r.Header.Get("Accept") != expected {
+					t.Errorf("expecting Content-Type and Accept to be %s, got %s and %s", expected, r.Header.Get("Content-Type"), r.Header.Get("Accept"))
+				}
+
+				w.Header().Set("Content-Type", expected)
+				if expected == "application/json" {
+					w.Write([]byte(`{"message":"OK"}`))
+					return
+				}
+
+				w.Write([]byte("message=OK"))
+			}))
+			defer server.Close()
+
+			client, err := pesto.NewClientWithConfig(pesto.Config{
+				Token:   token,
+				BaseURL: mustParseURL(t, server.URL),
+				Codec:   test.codec,
+			})
+			if err != nil {
+				t.Fatalf("creating client: %s", err.Error())
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			response, err := client.Ping(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if response.Message != "OK" {
+				t.Errorf("expecting response.Message to be 'OK', got %s", response.Message)
+			}
+		})
+	}
+}
+
+func TestCodec_RuntimeExtendedFields(t *testing.T) {
+	child := url.Values{
+		"language":           {"Python"},
+		"version":            {"3.10.2"},
+		"aliases":            {"py", "python3"},
+		"compiled":           {"false"},
+		"extension":          {"py"},
+		"shouldLimitMemory":  {"true"},
+		"memoryLimit":        {"268435456"},
+		"processLimit":       {"256"},
+		"allowedEntrypoints": {"-1"},
+	}
+
+	tests := []struct {
+		name  string
+		codec pesto.Codec
+		body  string
+	}{
+		{
+			name:  "JSON",
+			codec: pesto.JSONCodec{},
+			body:  `{"runtime":[{"language":"Python","version":"3.10.2","aliases":["py","python3"],"compiled":false,"extension":"py","shouldLimitMemory":true,"memoryLimit":268435456,"processLimit":256,"allowedEntrypoints":-1}]}`,
+		},
+		{
+			name:  "Form",
+			codec: pesto.FormCodec{},
+			body:  url.Values{"runtimes": {child.Encode()}}.Encode(),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", test.codec.ContentType())
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			client, err := pesto.NewClientWithConfig(pesto.Config{
+				Token:   token,
+				BaseURL: mustParseURL(t, server.URL),
+				Codec:   test.codec,
+			})
+			if err != nil {
+				t.Fatalf("creating client: %s", err.Error())
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			response, err := client.ListRuntimes(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if len(response.Runtime) != 1 {
+				t.Fatalf("expecting 1 runtime, got %d", len(response.Runtime))
+			}
+
+			runtime := response.Runtime[0]
+			if runtime.Extension != "py" || runtime.MemoryLimit != 268435456 || runtime.ProcessLimit != 256 || runtime.AllowedEntrypoints != -1 {
+				t.Errorf("unexpected runtime: %+v", runtime)
+			}
+
+			if runtime.ShouldLimitMemory == nil || !*runtime.ShouldLimitMemory {
+				t.Errorf("expecting ShouldLimitMemory to be true, got %v", runtime.ShouldLimitMemory)
+			}
+		})
+	}
+}
+
+func TestClient_ExecuteFiles(t *testing.T) {
+	var received pestotest.ExecuteRequest
+	server := pestotest.NewServer(
+		pestotest.WithToken(token, 100),
+		pestotest.WithExecuteFunc(func(runtime pesto.Runtime, request pestotest.ExecuteRequest) pesto.CodeResponse {
+			received = request
+			return pesto.CodeResponse{}
+		}),
+	)
+	defer server.Close()
+
+	client, err := pesto.NewClientWithConfig(pesto.Config{Token: token, BaseURL: server.BaseURL()})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	files := []pesto.File{
+		{Name: "main.py", Code: "import greet", Entrypoint: true},
+		{Name: "greet.py", Code: "print('Hello World')"},
+	}
+	_, err = client.Execute(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionLatest, Files: files})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expected := []pestotest.ExecuteFile{
+		{Name: "main.py", Code: "import greet", Entrypoint: true},
+		{Name: "greet.py", Code: "print('Hello World')"},
+	}
+	if !reflect.DeepEqual(received.Files, expected) {
+		t.Errorf("expecting files %+v, got %+v", expected, received.Files)
+	}
+}
@@ -0,0 +1,208 @@
+package pesto_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+// TestClient_Concurrency is meant to be run with the -race flag.
+func TestClient_Concurrency(t *testing.T) {
+	client, err := pesto.NewClientWithConfig(pesto.Config{
+		Token:   token,
+		BaseURL: happyMockServerURL,
+	})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	const goroutines = 32
+	const iterations = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines*iterations*3)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < iterations; j++ {
+				_, err := client.Execute(ctx, pesto.CodeRequest{
+					Language: pesto.LanguagePython,
+					Version:  pesto.VersionPython,
+					Code:     "print('Hello World')",
+				})
+				if err != nil {
+					errs <- err
+				}
+
+				_, err = client.Ping(ctx)
+				if err != nil {
+					errs <- err
+				}
+
+				_, err = client.ListRuntimes(ctx)
+				if err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+
+	// Change the configuration while the requests are in flight,
+	// to the same values so every request is still expected to succeed.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for j := 0; j < iterations; j++ {
+			if err := client.SetToken(token); err != nil {
+				errs <- err
+			}
+
+			if err := client.SetBaseURL(happyMockServerURL); err != nil {
+				errs <- err
+			}
+
+			client.SetDefaultTimeout(time.Minute)
+			_ = client.Quota()
+			_ = client.CircuitState()
+		}
+	}()
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("unexpected error: %s", err.Error())
+	}
+}
+
+func TestClient_SetToken(t *testing.T) {
+	client, err := pesto.NewClientWithConfig(pesto.Config{
+		Token:   "wrong-token",
+		BaseURL: happyMockServerURL,
+	})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	codeRequest := pesto.CodeRequest{
+		Language: pesto.LanguagePython,
+		Version:  pesto.VersionPython,
+		Code:     "print('Hello World')",
+	}
+
+	_, err = client.Execute(ctx, codeRequest)
+	if !errors.Is(err, pesto.ErrTokenNotRegistered) {
+		t.Errorf("expecting an error of ErrTokenNotRegistered, instead got %v", err)
+	}
+
+	err = client.SetToken("")
+	if !errors.Is(err, pesto.ErrEmptyToken) {
+		t.Errorf("expecting an error of ErrEmptyToken, instead got %v", err)
+	}
+
+	err = client.SetToken(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	_, err = client.Execute(ctx, codeRequest)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+	}
+}
+
+func TestClient_SetBaseURL(t *testing.T) {
+	first, firstHits := InstanceMockServer(http.StatusOK)
+	defer first.Close()
+	second, secondHits := InstanceMockServer(http.StatusOK)
+	defer second.Close()
+
+	client, err := pesto.NewClientWithConfig(pesto.Config{
+		Token:   token,
+		BaseURL: mustParseURL(t, first.URL),
+	})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err = client.Ping(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	err = client.SetBaseURL(mustParseURL(t, second.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	_, err = client.Ping(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if hitCount(firstHits, "/api/ping") != 1 || hitCount(secondHits, "/api/ping") != 1 {
+		t.Errorf("expecting 1 hit on each instance, got %d and %d", hitCount(firstHits, "/api/ping"), hitCount(secondHits, "/api/ping"))
+	}
+
+	err = client.SetBaseURLs(nil, nil)
+	if !errors.Is(err, pesto.ErrEmptyBaseURL) {
+		t.Errorf("expecting an error of ErrEmptyBaseURL, instead got %v", err)
+	}
+}
+
+func TestClient_DefaultTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second * 5):
+		}
+	}))
+	defer server.Close()
+
+	client, err := pesto.NewClientWithConfig(pesto.Config{
+		Token:          token,
+		BaseURL:        mustParseURL(t, server.URL),
+		DefaultTimeout: time.Millisecond * 50,
+	})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err = client.Ping(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expecting an error of context.DeadlineExceeded, instead got %v", err)
+	}
+
+	client.SetDefaultTimeout(time.Millisecond * 100)
+
+	start := time.Now()
+	_, err = client.Ping(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expecting an error of context.DeadlineExceeded, instead got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Millisecond*100 || elapsed > time.Second*4 {
+		t.Errorf("expecting the request to time out after 100ms, took %s", elapsed)
+	}
+}
@@ -0,0 +1,300 @@
+package pesto_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/pestotest"
+)
+
+// contract is the API contract of testdata/contract/rce.json, written after the
+// handlers of the rce and auth services. The SDK and the mock servers are tested
+// against it, so a drift from the real API fails the tests.
+type contract struct {
+	Version int `json:"version"`
+	// Requests are the bodies the SDK must send for a request.
+	Requests []struct {
+		Name    string          `json:"name"`
+		Request contractRequest `json:"request"`
+		JSON    json.RawMessage `json:"json"`
+		// Form is empty if the request can't be sent as a form.
+		Form string `json:"form"`
+		// FormError is the error the SDK must return instead of sending
+		// the request as a form, since the API would not run it as is.
+		FormError string `json:"formError"`
+	} `json:"requests"`
+	// Responses are the error responses of the API, and the errors they map to.
+	Responses []struct {
+		Name        string `json:"name"`
+		Endpoint    string `json:"endpoint"`
+		Status      int    `json:"status"`
+		ContentType string `json:"contentType"`
+		Body        string `json:"body"`
+		Error       string `json:"error"`
+		Message     string `json:"message"`
+	} `json:"responses"`
+	// Scenarios are the raw requests to the execute endpoint, and how the API responds to them.
+	Scenarios []struct {
+		Name        string `json:"name"`
+		ContentType string `json:"contentType"`
+		Body        string `json:"body"`
+		Status      int    `json:"status"`
+		Message     string `json:"message"`
+	} `json:"scenarios"`
+}
+
+// contractRequest is a request with the timeouts in milliseconds, like the API.
+type contractRequest struct {
+	Language       string       `json:"language"`
+	Version        string       `json:"version"`
+	Code           string       `json:"code"`
+	Files          []pesto.File `json:"files"`
+	CompileTimeout int64        `json:"compileTimeout"`
+	RunTimeout     int64        `json:"runTimeout"`
+	MemoryLimit    int32        `json:"memoryLimit"`
+}
+
+func (r contractRequest) codeRequest() pesto.CodeRequest {
+	return pesto.CodeRequest{
+		Language:       pesto.Language(r.Language),
+		Version:        pesto.Version(r.Version),
+		Code:           r.Code,
+		Files:          r.Files,
+		CompileTimeout: time.Duration(r.CompileTimeout) * time.Millisecond,
+		RunTimeout:     time.Duration(r.RunTimeout) * time.Millisecond,
+		MemoryLimit:    r.MemoryLimit,
+	}
+}
+
+// contractErrors are the errors the responses of the contract refer to.
+var contractErrors = map[string]error{
+	"ErrMissingToken":         pesto.ErrMissingToken,
+	"ErrTokenNotRegistered":   pesto.ErrTokenNotRegistered,
+	"ErrTokenRevoked":         pesto.ErrTokenRevoked,
+	"ErrMonthlyLimitExceeded": pesto.ErrMonthlyLimitExceeded,
+	"ErrServerRateLimited":    pesto.ErrServerRateLimited,
+	"ErrInternalServerError":  pesto.ErrInternalServerError,
+	"ErrRuntimeNotFound":      pesto.ErrRuntimeNotFound,
+	"ErrMissingParameters":    pesto.ErrMissingParameters,
+	"ErrInvalidRequest":       pesto.ErrInvalidRequest,
+}
+
+func loadContract(t *testing.T) contract {
+	t.Helper()
+
+	content, err := os.ReadFile("testdata/contract/rce.json")
+	if err != nil {
+		t.Fatalf("reading contract: %s", err.Error())
+	}
+
+	var c contract
+	err = json.Unmarshal(content, &c)
+	if err != nil {
+		t.Fatalf("parsing contract: %s", err.Error())
+	}
+
+	if c.Version != 1 {
+		t.Fatalf("unsupported contract version %d", c.Version)
+	}
+
+	return c
+}
+
+func TestContract_RequestEncoding(t *testing.T) {
+	c := loadContract(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for _, test := range c.Requests {
+		codecs := map[string]pesto.Codec{"JSON": pesto.JSONCodec{}}
+		if test.Form != "" || test.FormError != "" {
+			codecs["Form"] = pesto.FormCodec{}
+		}
+
+		for codecName, codec := range codecs {
+			t.Run(test.Name+"/"+codecName, func(t *testing.T) {
+				var body []byte
+				sent := false
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					sent = true
+					body, _ = io.ReadAll(r.Body)
+					w.Header().Set("Content-Type", "application/json")
+					w.Write([]byte(`{"language":"Python","version":"3.10.10","compile":{},"runtime":{}}`))
+				}))
+				defer server.Close()
+
+				client, err := pesto.NewClientWithConfig(pesto.Config{
+					Token:   token,
+					BaseURL: mustParseURL(t, server.URL),
+					Codec:   codec,
+				})
+				if err != nil {
+					t.Fatalf("creating client: %s", err.Error())
+				}
+
+				_, err = client.Execute(ctx, test.Request.codeRequest())
+				if codecName == "Form" && test.FormError != "" {
+					expected, ok := contractErrors[test.FormError]
+					if !ok {
+						t.Fatalf("unknown error %s", test.FormError)
+					}
+
+					if !errors.Is(err, expected) {
+						t.Errorf("expecting an error of %s, instead got %v", test.FormError, err)
+					}
+
+					if sent {
+						t.Error("expecting the request to not be sent")
+					}
+					return
+				}
+
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err.Error())
+				}
+
+				if codecName == "Form" {
+					expected, _ := url.ParseQuery(test.Form)
+					actual, err := url.ParseQuery(string(body))
+					if err != nil || !reflect.DeepEqual(expected, actual) {
+						t.Errorf("expecting the form body %s, got %s", test.Form, body)
+					}
+					return
+				}
+
+				var expected, actual any
+				_ = json.Unmarshal(test.JSON, &expected)
+				err = json.Unmarshal(body, &actual)
+				if err != nil || !reflect.DeepEqual(expected, actual) {
+					t.Errorf("expecting the JSON body %s, got %s", test.JSON, body)
+				}
+			})
+		}
+	}
+}
+
+func TestContract_ErrorMapping(t *testing.T) {
+	c := loadContract(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for _, test := range c.Responses {
+		t.Run(test.Name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != test.Endpoint {
+					t.Errorf("expecting a request to %s, got %s", test.Endpoint, r.URL.Path)
+				}
+
+				w.Header().Set("Content-Type", test.ContentType)
+				w.WriteHeader(test.Status)
+				w.Write([]byte(test.Body))
+			}))
+			defer server.Close()
+
+			client, err := pesto.NewClientWithConfig(pesto.Config{
+				Token:   token,
+				BaseURL: mustParseURL(t, server.URL),
+			})
+			if err != nil {
+				t.Fatalf("creating client: %s", err.Error())
+			}
+
+			switch test.Endpoint {
+			case "/api/ping":
+				_, err = client.Ping(ctx)
+			case "/api/list-runtimes":
+				_, err = client.ListRuntimes(ctx)
+			case "/api/execute":
+				_, err = client.Execute(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionLatest, Code: "print(1)"})
+			default:
+				t.Fatalf("unknown endpoint %s", test.Endpoint)
+			}
+			if err == nil {
+				t.Fatal("expecting an error, got nil")
+			}
+
+			if test.Error != "" {
+				expected, ok := contractErrors[test.Error]
+				if !ok {
+					t.Fatalf("unknown error %s", test.Error)
+				}
+
+				if !errors.Is(err, expected) {
+					t.Errorf("expecting an error of %s, instead got %v", test.Error, err)
+				}
+			}
+
+			if !strings.Contains(err.Error(), test.Message) {
+				t.Errorf("expecting the error to contain %q, got %q", test.Message, err.Error())
+			}
+		})
+	}
+}
+
+func TestContract_MockServers(t *testing.T) {
+	c := loadContract(t)
+
+	fake := pestotest.NewServer(pestotest.WithToken(token, 1000))
+	defer fake.Close()
+
+	servers := map[string]string{
+		"HappyMockServer": happyMockServerURL.String(),
+		"pestotest":       fake.BaseURL().String(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for serverName, baseURL := range servers {
+		for _, test := range c.Scenarios {
+			t.Run(serverName+"/"+test.Name, func(t *testing.T) {
+				request, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/api/execute", strings.NewReader(test.Body))
+				if err != nil {
+					t.Fatalf("creating request: %s", err.Error())
+				}
+				request.Header.Set("Content-Type", test.ContentType)
+				request.Header.Set("X-Pesto-Token", token)
+
+				response, err := http.DefaultClient.Do(request)
+				if err != nil {
+					t.Fatalf("sending request: %s", err.Error())
+				}
+				defer response.Body.Close()
+
+				if response.StatusCode != test.Status {
+					t.Errorf("expecting status %d, got %d", test.Status, response.StatusCode)
+				}
+
+				if test.Message == "" {
+					return
+				}
+
+				var body struct {
+					Message string `json:"message"`
+					Msg     string `json:"msg"`
+				}
+				err = json.NewDecoder(response.Body).Decode(&body)
+				if err != nil {
+					t.Fatalf("decoding response body: %s", err.Error())
+				}
+
+				if message := body.Message + body.Msg; message != test.Message {
+					t.Errorf("expecting the message %q, got %q", test.Message, message)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Package diff compares the expected output of a code with its actual output,
+// such as CodeResponse.Runtime.Stdout, to show why a test case failed.
+//
+// Compare produces the line-level differences, along with the character-level
+// differences of the lines that were changed. The Result is rendered as a
+// unified diff, side by side, or as JSON through encoding/json:
+//
+//	result := diff.Compare(expected, response.Runtime.Stdout)
+//	if !result.Equal {
+//		fmt.Print(result.Unified(diff.Options{}))
+//	}
+//
+// The renderers make the whitespace differences visible, and mark the lines
+// that miss the new line at the end of the output.
+package diff
+
+import "strings"
+
+// maxCells bounds the table of the longest common subsequence. Longer inputs
+// are compared as a removal of every old item and an insertion of every new item.
+const maxCells = 4 << 20
+
+// Op is the operation of a line or a span.
+type Op int
+
+const (
+	// Equal is kept in both outputs.
+	Equal Op = iota
+	// Delete is only in the expected output.
+	Delete
+	// Insert is only in the actual output.
+	Insert
+)
+
+func (o Op) String() string {
+	switch o {
+	case Equal:
+		return "equal"
+	case Delete:
+		return "delete"
+	case Insert:
+		return "insert"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the Op as its name, for the JSON format.
+func (o Op) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// Span is a part of a changed line.
+type Span struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// Line is a line of the diff.
+type Line struct {
+	Op Op `json:"op"`
+	// Expected and Actual are the 1-based line numbers in each output,
+	// or 0 if the line is not in that output.
+	Expected int    `json:"expected,omitempty"`
+	Actual   int    `json:"actual,omitempty"`
+	Text     string `json:"text"`
+	// NoNewline marks the last line of an output that does not end with a new line.
+	NoNewline bool `json:"noNewline,omitempty"`
+	// Spans holds the character-level differences of a changed line against
+	// the line that replaces it, or that it replaces. It is nil for the
+	// equal lines, and for the changed lines without a counterpart.
+	Spans []Span `json:"spans,omitempty"`
+}
+
+// Result is the difference between the expected and the actual output.
+type Result struct {
+	// Equal reports whether the outputs are exactly the same.
+	Equal bool   `json:"equal"`
+	Lines []Line `json:"lines"`
+}
+
+// Compare returns the differences between the expected and the actual output.
+// A missing new line at the end of one of the outputs is a difference.
+func Compare(expected, actual string) Result {
+	linesA, linesB := splitLines(expected), splitLines(actual)
+
+	result := Result{Equal: expected == actual}
+	numberA, numberB := 0, 0
+	for _, op := range edits(linesA, linesB) {
+		var line Line
+		switch op {
+		case Equal:
+			line = linesA[numberA].line(Equal)
+			numberA++
+			numberB++
+			line.Expected, line.Actual = numberA, numberB
+		case Delete:
+			line = linesA[numberA].line(Delete)
+			numberA++
+			line.Expected = numberA
+		case Insert:
+			line = linesB[numberB].line(Insert)
+			numberB++
+			line.Actual = numberB
+		}
+
+		result.Lines = append(result.Lines, line)
+	}
+
+	pairLines(result.Lines)
+	return result
+}
+
+// rawLine is a line along with whether it ends with a new line, so the last
+// lines of the outputs only match if both end with a new line or both do not.
+type rawLine struct {
+	text    string
+	newline bool
+}
+
+func (l rawLine) line(op Op) Line {
+	return Line{Op: op, Text: l.text, NoNewline: !l.newline}
+}
+
+func splitLines(s string) []rawLine {
+	var lines []rawLine
+	for s != "" {
+		text, rest, found := strings.Cut(s, "\n")
+		lines = append(lines, rawLine{text: text, newline: found})
+		s = rest
+	}
+
+	return lines
+}
+
+// pairLines computes the character-level differences of every block of deleted
+// lines that is followed by a block of inserted lines, pairing them in order.
+func pairLines(lines []Line) {
+	for i := 0; i < len(lines); {
+		if lines[i].Op != Delete {
+			i++
+			continue
+		}
+
+		deletes := i
+		for i < len(lines) && lines[i].Op == Delete {
+			i++
+		}
+		inserts := i
+		for i < len(lines) && lines[i].Op == Insert {
+			i++
+		}
+
+		for j := 0; deletes+j < inserts && inserts+j < i; j++ {
+			lines[deletes+j].Spans, lines[inserts+j].Spans = compareRunes(lines[deletes+j].Text, lines[inserts+j].Text)
+		}
+	}
+}
+
+// compareRunes returns the spans of the old line, made of the equal and the
+// deleted runes, and the spans of the new line, made of the equal and the inserted runes.
+func compareRunes(a, b string) ([]Span, []Span) {
+	runesA, runesB := []rune(a), []rune(b)
+
+	var spansA, spansB []Span
+	i, j := 0, 0
+	for _, op := range edits(runesA, runesB) {
+		switch op {
+		case Equal:
+			spansA = appendSpan(spansA, Equal, runesA[i])
+			spansB = appendSpan(spansB, Equal, runesB[j])
+			i++
+			j++
+		case Delete:
+			spansA = appendSpan(spansA, Delete, runesA[i])
+			i++
+		case Insert:
+			spansB = appendSpan(spansB, Insert, runesB[j])
+			j++
+		}
+	}
+
+	return spansA, spansB
+}
+
+func appendSpan(spans []Span, op Op, r rune) []Span {
+	if len(spans) > 0 && spans[len(spans)-1].Op == op {
+		spans[len(spans)-1].Text += string(r)
+		return spans
+	}
+
+	return append(spans, Span{Op: op, Text: string(r)})
+}
+
+// edits returns the operations that turn a into b, keeping their longest
+// common subsequence. The deletions come before the insertions they replace.
+func edits[T comparable](a, b []T) []Op {
+	n, m := len(a), len(b)
+	ops := make([]Op, 0, n+m)
+
+	if (n+1)*(m+1) > maxCells {
+		for i := 0; i < n; i++ {
+			ops = append(ops, Delete)
+		}
+		for j := 0; j < m; j++ {
+			ops = append(ops, Insert)
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, Equal)
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, Delete)
+			i++
+		default:
+			ops = append(ops, Insert)
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, Delete)
+	}
+	for ; j < m; j++ {
+		ops = append(ops, Insert)
+	}
+
+	return ops
+}
@@ -0,0 +1,229 @@
+package diff_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/teknologi-umum/pesto/sdk/go/diff"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		actual   string
+		equal    bool
+		lines    []diff.Line
+	}{
+		{
+			name:     "Equal",
+			expected: "a\nb\n",
+			actual:   "a\nb\n",
+			equal:    true,
+			lines: []diff.Line{
+				{Op: diff.Equal, Expected: 1, Actual: 1, Text: "a"},
+				{Op: diff.Equal, Expected: 2, Actual: 2, Text: "b"},
+			},
+		},
+		{
+			name:     "Empty",
+			expected: "",
+			actual:   "",
+			equal:    true,
+		},
+		{
+			name:     "Changed",
+			expected: "a\nb\n",
+			actual:   "a\nc\n",
+			lines: []diff.Line{
+				{Op: diff.Equal, Expected: 1, Actual: 1, Text: "a"},
+				{Op: diff.Delete, Expected: 2, Text: "b", Spans: []diff.Span{{Op: diff.Delete, Text: "b"}}},
+				{Op: diff.Insert, Actual: 2, Text: "c", Spans: []diff.Span{{Op: diff.Insert, Text: "c"}}},
+			},
+		},
+		{
+			name:     "TrailingNewline",
+			expected: "a\n",
+			actual:   "a",
+			lines: []diff.Line{
+				{Op: diff.Delete, Expected: 1, Text: "a", Spans: []diff.Span{{Op: diff.Equal, Text: "a"}}},
+				{Op: diff.Insert, Actual: 1, Text: "a", NoNewline: true, Spans: []diff.Span{{Op: diff.Equal, Text: "a"}}},
+			},
+		},
+		{
+			name:     "Whitespace",
+			expected: "a b\n",
+			actual:   "a  b\n",
+			lines: []diff.Line{
+				{Op: diff.Delete, Expected: 1, Text: "a b", Spans: []diff.Span{{Op: diff.Equal, Text: "a b"}}},
+				{Op: diff.Insert, Actual: 1, Text: "a  b", Spans: []diff.Span{{Op: diff.Equal, Text: "a "}, {Op: diff.Insert, Text: " "}, {Op: diff.Equal, Text: "b"}}},
+			},
+		},
+		{
+			name:     "Unpaired",
+			expected: "a\n",
+			actual:   "a\nb\n",
+			lines: []diff.Line{
+				{Op: diff.Equal, Expected: 1, Actual: 1, Text: "a"},
+				{Op: diff.Insert, Actual: 2, Text: "b"},
+			},
+		},
+		{
+			name:     "Unicode",
+			expected: "héllo\n",
+			actual:   "hèllo\n",
+			lines: []diff.Line{
+				{Op: diff.Delete, Expected: 1, Text: "héllo", Spans: []diff.Span{{Op: diff.Equal, Text: "h"}, {Op: diff.Delete, Text: "é"}, {Op: diff.Equal, Text: "llo"}}},
+				{Op: diff.Insert, Actual: 1, Text: "hèllo", Spans: []diff.Span{{Op: diff.Equal, Text: "h"}, {Op: diff.Insert, Text: "è"}, {Op: diff.Equal, Text: "llo"}}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := diff.Compare(test.expected, test.actual)
+			if result.Equal != test.equal {
+				t.Errorf("expecting Equal to be %t, got %t", test.equal, result.Equal)
+			}
+
+			if !reflect.DeepEqual(result.Lines, test.lines) {
+				t.Errorf("expecting lines %+v, got %+v", test.lines, result.Lines)
+			}
+		})
+	}
+}
+
+func TestResult_Unified(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		actual   string
+		options  diff.Options
+		output   string
+	}{
+		{
+			name:     "Equal",
+			expected: "a\n",
+			actual:   "a\n",
+			output:   "",
+		},
+		{
+			name:     "Changed",
+			expected: "one\ntwo\nthree\n",
+			actual:   "one\n2\nthree",
+			output: "--- expected\n+++ actual\n@@ -1,3 +1,3 @@\n" +
+				" one\n" +
+				"-[-two-]\n" +
+				"-three\n" +
+				"+{+2+}\n" +
+				"+three\n" +
+				"\\ No newline at end of file\n",
+		},
+		{
+			name:     "Whitespace",
+			expected: "a b\nc\n",
+			actual:   "a\tb\nc \n",
+			output: "--- expected\n+++ actual\n@@ -1,2 +1,2 @@\n" +
+				"-a[-·-]b\n" +
+				"-c\n" +
+				"+a{+→+}b\n" +
+				"+c{+·+}\n",
+		},
+		{
+			name:     "Hunks",
+			expected: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			actual:   "1\nx\n3\n4\n5\n6\n7\n8\ny\n10\n",
+			options:  diff.Options{Context: 1, ExpectedLabel: "want", ActualLabel: "got"},
+			output: "--- want\n+++ got\n" +
+				"@@ -1,3 +1,3 @@\n 1\n-[-2-]\n+{+x+}\n 3\n" +
+				"@@ -8,3 +8,3 @@\n 8\n-[-9-]\n+{+y+}\n 10\n",
+		},
+		{
+			name:     "NoContext",
+			expected: "a\nb\nc\n",
+			actual:   "a\nc\n",
+			options:  diff.Options{Context: -1},
+			output:   "--- expected\n+++ actual\n@@ -2 +1,0 @@\n-b\n",
+		},
+		{
+			name:     "FromEmpty",
+			expected: "",
+			actual:   "a\n",
+			output:   "--- expected\n+++ actual\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name:     "Color",
+			expected: "ab\n",
+			actual:   "ac\n",
+			options:  diff.Options{Color: true},
+			output: "\x1b[2m--- expected\x1b[0m\n\x1b[2m+++ actual\x1b[0m\n\x1b[36m@@ -1 +1 @@\x1b[0m\n" +
+				"\x1b[31m-a\x1b[7mb\x1b[27m\x1b[0m\n" +
+				"\x1b[32m+a\x1b[7mc\x1b[27m\x1b[0m\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := diff.Compare(test.expected, test.actual).Unified(test.options)
+			if output != test.output {
+				t.Errorf("expecting:\n%s\ngot:\n%s", test.output, output)
+			}
+		})
+	}
+}
+
+func TestResult_SideBySide(t *testing.T) {
+	result := diff.Compare("a\nb\nthis line is too long\n", "a\nc\nd\n")
+	output := result.SideBySide(diff.Options{Width: 23})
+
+	expected := strings.Join([]string{
+		"expected     actual",
+		"-----------+-----------",
+		"a            a",
+		"[-b-]      | {+c+}",
+		"[-this·li… | {+d+}",
+		"",
+	}, "\n")
+	if output != expected {
+		t.Errorf("expecting:\n%s\ngot:\n%s", expected, output)
+	}
+
+	output = diff.Compare("a\n", "a\nb").SideBySide(diff.Options{Width: 40})
+	if !strings.HasPrefix(output, "expected") || !strings.Contains(output, "  > b (no new line)\n") {
+		t.Errorf("expecting the missing new line to be marked, got:\n%s", output)
+	}
+
+	output = diff.Compare("ab\n", "ac\n").SideBySide(diff.Options{Width: 23, Color: true})
+	if !strings.Contains(output, "\x1b[31ma\x1b[7mb\x1b[27m\x1b[0m         | \x1b[32ma\x1b[7mc\x1b[27m\x1b[0m\n") {
+		t.Errorf("expecting the changes to be painted, got %q", output)
+	}
+}
+
+func TestResult_JSON(t *testing.T) {
+	output, err := json.Marshal(diff.Compare("a\n", "b"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expected := `{"equal":false,"lines":[` +
+		`{"op":"delete","expected":1,"text":"a","spans":[{"op":"delete","text":"a"}]},` +
+		`{"op":"insert","actual":1,"text":"b","noNewline":true,"spans":[{"op":"insert","text":"b"}]}]}`
+	if string(output) != expected {
+		t.Errorf("expecting %s, got %s", expected, output)
+	}
+}
+
+func ExampleCompare() {
+	result := diff.Compare("Hello World\n", "Hello world")
+	fmt.Print(result.Unified(diff.Options{}))
+	// Output:
+	// --- expected
+	// +++ actual
+	// @@ -1 +1 @@
+	// -Hello [-W-]orld
+	// +Hello {+w+}orld
+	// \ No newline at end of file
+}
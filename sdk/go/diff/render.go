@@ -0,0 +1,396 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultContext = 3
+	defaultWidth   = 120
+	minColumnWidth = 10
+
+	noNewlineMarker = `\ No newline at end of file`
+	noNewlineSuffix = " (no new line)"
+)
+
+const (
+	colorReset   = "\x1b[0m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorCyan    = "\x1b[36m"
+	colorDim     = "\x1b[2m"
+	colorReverse = "\x1b[7m"
+	colorNormal  = "\x1b[27m"
+)
+
+// Options configures the renderers of a Result.
+type Options struct {
+	// Context is the amount of equal lines shown around the changes of a unified diff.
+	// A negative value shows no context.
+	// Defaults to 3
+	Context int
+	// Color highlights the changes with ANSI colors, for terminals. Otherwise the
+	// changed characters are marked as [-deleted-] and {+inserted+}.
+	// Defaults to false
+	Color bool
+	// ExpectedLabel and ActualLabel name the outputs in the headers.
+	// Defaults to "expected" and "actual"
+	ExpectedLabel string
+	ActualLabel   string
+	// Width is the width of the side-by-side format, in characters.
+	// Defaults to 120
+	Width int
+}
+
+func (o Options) withDefaults() Options {
+	if o.Context == 0 {
+		o.Context = defaultContext
+	} else if o.Context < 0 {
+		o.Context = 0
+	}
+
+	if o.ExpectedLabel == "" {
+		o.ExpectedLabel = "expected"
+	}
+
+	if o.ActualLabel == "" {
+		o.ActualLabel = "actual"
+	}
+
+	if o.Width <= 0 {
+		o.Width = defaultWidth
+	}
+
+	return o
+}
+
+// Unified renders the result as a unified diff, like diff -u does.
+// It returns an empty string if the outputs are equal.
+func (r Result) Unified(options Options) string {
+	if r.Equal {
+		return ""
+	}
+	options = options.withDefaults()
+
+	var sb strings.Builder
+	sb.WriteString(options.paint(colorDim, "--- "+options.ExpectedLabel) + "\n")
+	sb.WriteString(options.paint(colorDim, "+++ "+options.ActualLabel) + "\n")
+
+	for _, h := range r.hunks(options.Context) {
+		sb.WriteString(options.paint(colorCyan, h.header()) + "\n")
+		for _, line := range r.Lines[h.start:h.end] {
+			sb.WriteString(options.line(line))
+			sb.WriteByte('\n')
+			if line.NoNewline {
+				sb.WriteString(noNewlineMarker + "\n")
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// hunk is a group of changed lines of the unified diff, along with their context.
+type hunk struct {
+	start, end                   int
+	expectedStart, expectedCount int
+	actualStart, actualCount     int
+}
+
+func (h hunk) header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.expectedStart, h.expectedCount), hunkRange(h.actualStart, h.actualCount))
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func (r Result) hunks(context int) []hunk {
+	var hunks []hunk
+	for i := 0; i < len(r.Lines); i++ {
+		if r.Lines[i].Op == Equal {
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		// Extend the hunk while the next change is close enough to share the context.
+		end := i
+		for j := i; j < len(r.Lines); j++ {
+			if r.Lines[j].Op != Equal {
+				end = j + 1
+			} else if j-end >= 2*context {
+				break
+			}
+		}
+
+		end += context
+		if end > len(r.Lines) {
+			end = len(r.Lines)
+		}
+
+		if len(hunks) > 0 && start <= hunks[len(hunks)-1].end {
+			start = hunks[len(hunks)-1].start
+			hunks = hunks[:len(hunks)-1]
+		}
+
+		hunks = append(hunks, r.hunk(start, end))
+		i = end - 1
+	}
+
+	return hunks
+}
+
+func (r Result) hunk(start, end int) hunk {
+	h := hunk{start: start, end: end}
+
+	// The lines before the hunk tell where it starts, even if it has no line of one output.
+	for _, line := range r.Lines[:start] {
+		if line.Op != Insert {
+			h.expectedStart = line.Expected
+		}
+		if line.Op != Delete {
+			h.actualStart = line.Actual
+		}
+	}
+
+	for _, line := range r.Lines[start:end] {
+		if line.Op != Insert {
+			h.expectedCount++
+		}
+		if line.Op != Delete {
+			h.actualCount++
+		}
+	}
+
+	if h.expectedCount > 0 {
+		h.expectedStart++
+	}
+	if h.actualCount > 0 {
+		h.actualStart++
+	}
+
+	return h
+}
+
+// line renders a line of the unified diff.
+func (o Options) line(line Line) string {
+	switch line.Op {
+	case Delete:
+		return o.paint(colorRed, "-"+o.text(line))
+	case Insert:
+		return o.paint(colorGreen, "+"+o.text(line))
+	default:
+		return " " + line.Text
+	}
+}
+
+// text renders the text of a line, marking its changed characters.
+func (o Options) text(line Line) string {
+	if line.Op == Equal {
+		return line.Text
+	}
+
+	if line.Spans == nil {
+		return visibleTrailing(line.Text)
+	}
+
+	var sb strings.Builder
+	for _, span := range line.Spans {
+		switch {
+		case span.Op == Equal:
+			sb.WriteString(span.Text)
+		case o.Color:
+			sb.WriteString(colorReverse + visible(span.Text) + colorNormal)
+		case span.Op == Delete:
+			sb.WriteString("[-" + visible(span.Text) + "-]")
+		default:
+			sb.WriteString("{+" + visible(span.Text) + "+}")
+		}
+	}
+
+	return sb.String()
+}
+
+func (o Options) paint(color string, text string) string {
+	if !o.Color {
+		return text
+	}
+
+	return color + text + colorReset
+}
+
+// SideBySide renders the result in two columns, the expected output on the left
+// and the actual output on the right, like diff -y does. The gutter between them
+// is '|' for a changed line, '<' for a deleted line and '>' for an inserted line.
+func (r Result) SideBySide(options Options) string {
+	options = options.withDefaults()
+
+	width := (options.Width - 3) / 2
+	if width < minColumnWidth {
+		width = minColumnWidth
+	}
+
+	var sb strings.Builder
+	sb.WriteString(options.row(width, cell{text: options.ExpectedLabel}, " ", cell{text: options.ActualLabel}))
+	sb.WriteString(strings.Repeat("-", width) + "-+-" + strings.Repeat("-", width) + "\n")
+
+	for i := 0; i < len(r.Lines); {
+		if r.Lines[i].Op == Equal {
+			line := options.cell(r.Lines[i])
+			sb.WriteString(options.row(width, line, " ", line))
+			i++
+			continue
+		}
+
+		var deletes, inserts []Line
+		for i < len(r.Lines) && r.Lines[i].Op == Delete {
+			deletes = append(deletes, r.Lines[i])
+			i++
+		}
+		for i < len(r.Lines) && r.Lines[i].Op == Insert {
+			inserts = append(inserts, r.Lines[i])
+			i++
+		}
+
+		for j := 0; j < len(deletes) || j < len(inserts); j++ {
+			switch {
+			case j < len(deletes) && j < len(inserts):
+				sb.WriteString(options.row(width, options.cell(deletes[j]), "|", options.cell(inserts[j])))
+			case j < len(deletes):
+				sb.WriteString(options.row(width, options.cell(deletes[j]), "<", cell{}))
+			default:
+				sb.WriteString(options.row(width, cell{}, ">", options.cell(inserts[j])))
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// cell is a column of the side-by-side format, whose text is painted after it is
+// cut to the width of the column, so the colors do not count towards the width.
+type cell struct {
+	text  string
+	color string
+	// highlights are the byte ranges of text that are highlighted.
+	highlights [][2]int
+}
+
+func (o Options) cell(line Line) cell {
+	c := cell{}
+	switch line.Op {
+	case Delete:
+		c.color = colorRed
+	case Insert:
+		c.color = colorGreen
+	}
+
+	if line.Op == Equal || line.Spans == nil {
+		c.text = line.Text
+		if line.Op != Equal {
+			c.text = visibleTrailing(line.Text)
+		}
+	} else {
+		var sb strings.Builder
+		for _, span := range line.Spans {
+			switch {
+			case span.Op == Equal:
+				sb.WriteString(span.Text)
+			case o.Color:
+				start := sb.Len()
+				sb.WriteString(visible(span.Text))
+				c.highlights = append(c.highlights, [2]int{start, sb.Len()})
+			case span.Op == Delete:
+				sb.WriteString("[-" + visible(span.Text) + "-]")
+			default:
+				sb.WriteString("{+" + visible(span.Text) + "+}")
+			}
+		}
+		c.text = sb.String()
+	}
+
+	if line.NoNewline {
+		c.text += noNewlineSuffix
+	}
+
+	return c
+}
+
+func (o Options) row(width int, left cell, gutter string, right cell) string {
+	return o.renderCell(left, width, true) + " " + gutter + " " + o.renderCell(right, width, false) + "\n"
+}
+
+// renderCell cuts the cell to the width, marking the cut with '…', and pads
+// the left column with spaces.
+func (o Options) renderCell(c cell, width int, pad bool) string {
+	text := c.text
+	if utf8.RuneCountInString(text) > width {
+		cut := 0
+		for i := 0; i < width-1; i++ {
+			_, size := utf8.DecodeRuneInString(text[cut:])
+			cut += size
+		}
+		text = text[:cut] + "…"
+	}
+
+	length := utf8.RuneCountInString(text)
+	if o.Color {
+		text = paintCell(text, c)
+	}
+
+	if pad && length < width {
+		text += strings.Repeat(" ", width-length)
+	}
+
+	return text
+}
+
+func paintCell(text string, c cell) string {
+	var sb strings.Builder
+	sb.WriteString(c.color)
+	position := 0
+	for _, highlight := range c.highlights {
+		if highlight[0] >= len(text) {
+			break
+		}
+
+		end := highlight[1]
+		if end > len(text) {
+			end = len(text)
+		}
+
+		sb.WriteString(text[position:highlight[0]])
+		sb.WriteString(colorReverse + text[highlight[0]:end] + colorNormal)
+		position = end
+	}
+	sb.WriteString(text[position:])
+
+	if c.color != "" {
+		sb.WriteString(colorReset)
+	}
+
+	return sb.String()
+}
+
+var whitespaces = strings.NewReplacer(" ", "·", "\t", "→", "\r", "␍", "\u00a0", "⍽")
+
+// visible makes the whitespaces of s visible.
+func visible(s string) string {
+	return whitespaces.Replace(s)
+}
+
+// visibleTrailing makes the trailing whitespaces of s visible.
+func visibleTrailing(s string) string {
+	trimmed := strings.TrimRight(s, " \t\r\u00a0")
+	return trimmed + visible(s[len(trimmed):])
+}
@@ -1,6 +1,11 @@
 package pesto
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+)
 
 var (
 	// ErrMisingParameters indicates some parameters are missing
@@ -12,6 +17,9 @@ var (
 	// ErrEmptyToken indicates that token was empty during the
 	// client creation.
 	ErrEmptyToken = errors.New("empty token")
+	// ErrEmptyBaseURL indicates that no base URL was provided
+	// when replacing the base URLs of the client.
+	ErrEmptyBaseURL = errors.New("empty base url")
 	// ErrMissingToken indicates that token was not sent during
 	// HTTP request.
 	ErrMissingToken = errors.New("missing token")
@@ -29,4 +37,88 @@ var (
 	// ErrRuntimeNotFound indicates the provided Language-Version combination
 	// does not exists as a runtime on Pesto's API,
 	ErrRuntimeNotFound = errors.New("runtime not found")
+	// ErrCircuitOpen indicates the circuit breaker is open because Pesto's API
+	// has been failing recently. The request was not sent to the server.
+	ErrCircuitOpen = errors.New("circuit open")
+	// ErrTenantNotFound indicates the tenant is not registered on the ClientPool.
+	ErrTenantNotFound = errors.New("tenant not found")
+	// ErrTenantDisabled indicates the tenant was disabled on the ClientPool,
+	// because its token was revoked or not registered. Rotate the token
+	// to enable the tenant again.
+	ErrTenantDisabled = errors.New("tenant disabled")
+	// ErrResponseTooLarge indicates the response body exceeds Config.MaxResponseBytes,
+	// and no partial response could be salvaged from it.
+	ErrResponseTooLarge = errors.New("response too large")
+	// ErrClientClosed indicates the call was made after Client.Close was called.
+	ErrClientClosed = errors.New("client closed")
+	// ErrQueueTimeout indicates the execution waited in the Config.Scheduler queue
+	// for longer than SchedulerConfig.MaxQueueTime. The request was not sent to the server.
+	ErrQueueTimeout = errors.New("queue timeout")
+	// ErrJobNotFinished indicates the result of a Job was requested
+	// before the job is completed or failed.
+	ErrJobNotFinished = errors.New("job not finished")
+	// ErrInvalidRequest indicates CodeRequest.Validate found problems on the request,
+	// or the server rejected the entrypoints of CodeRequest.Files.
+	// The error is a *ValidationError that lists them.
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrInvalidBundle indicates ReadBundle could not read the bundle,
+	// because it is malformed or was written by a newer format.
+	ErrInvalidBundle = errors.New("invalid bundle")
+	// ErrInvalidConfig indicates NewClientWithConfig was given a Config
+	// with a value that cannot be used.
+	ErrInvalidConfig = errors.New("invalid config")
 )
+
+// sentinelErrors is every sentinel error of the package, which ErrorClass tells apart.
+var sentinelErrors = []error{
+	ErrMissingParameters,
+	ErrInternalServerError,
+	ErrEmptyToken,
+	ErrEmptyBaseURL,
+	ErrMissingToken,
+	ErrTokenNotRegistered,
+	ErrTokenRevoked,
+	ErrMonthlyLimitExceeded,
+	ErrServerRateLimited,
+	ErrRuntimeNotFound,
+	ErrCircuitOpen,
+	ErrTenantNotFound,
+	ErrTenantDisabled,
+	ErrResponseTooLarge,
+	ErrClientClosed,
+	ErrQueueTimeout,
+	ErrJobNotFinished,
+	ErrInvalidRequest,
+	ErrInvalidBundle,
+	ErrInvalidConfig,
+}
+
+// ErrorClass returns a short, low-cardinality name of err, which is what the
+// client logs and the metrics package records for a failed call. It is the
+// sentinel error of this package that err wraps, in snake case like
+// "monthly_limit_exceeded", or "canceled", "deadline_exceeded" and "transport"
+// for the errors of the context and the network, or "other" for the rest.
+// It returns an empty string if err is nil.
+func ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	for _, sentinel := range sentinelErrors {
+		if errors.Is(err, sentinel) {
+			return strings.ReplaceAll(sentinel.Error(), " ", "_")
+		}
+	}
+
+	var urlErr *url.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	case errors.As(err, &urlErr):
+		return "transport"
+	}
+
+	return "other"
+}
@@ -0,0 +1,35 @@
+package pesto_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{err: nil, expected: ""},
+		{err: pesto.ErrMonthlyLimitExceeded, expected: "monthly_limit_exceeded"},
+		{err: fmt.Errorf("%w: language is required", pesto.ErrMissingParameters), expected: "missing_parameters"},
+		{err: &pesto.ValidationError{Problems: []string{"language is empty"}}, expected: "invalid_request"},
+		{err: pesto.ErrEmptyBaseURL, expected: "empty_base_url"},
+		{err: fmt.Errorf("%w: unsupported format 2", pesto.ErrInvalidBundle), expected: "invalid_bundle"},
+		{err: pesto.ErrQueueTimeout, expected: "queue_timeout"},
+		{err: context.DeadlineExceeded, expected: "deadline_exceeded"},
+		{err: &url.Error{Op: "Get", URL: "https://pesto.teknologiumum.com", Err: errors.New("connection refused")}, expected: "transport"},
+		{err: errors.New("something else"), expected: "other"},
+	}
+
+	for _, test := range tests {
+		if class := pesto.ErrorClass(test.err); class != test.expected {
+			t.Errorf("expecting class of %v to be %q, got %q", test.err, test.expected, class)
+		}
+	}
+}
@@ -1,12 +1,7 @@
 package pesto
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"io"
 	"net/http"
 	"time"
 )
@@ -68,18 +63,45 @@ var (
 )
 
 type CodeRequest struct {
-	Language       Language
-	Version        Version
-	Code           string
+	Language Language
+	Version  Version
+	Code     string
+	// Files sends multiple files instead of Code, for runtimes that accept
+	// more than one file. Use Validate to check them against the runtime.
+	// Defaults to nil (Code is sent as the only file)
+	Files          []File
 	CompileTimeout time.Duration
 	RunTimeout     time.Duration
 	MemoryLimit    int32
+	// MaxOutputBytes limits the size of each output field (stdout, stderr and output)
+	// of the response. Longer outputs are cut on a UTF-8 boundary and marked as truncated.
+	// The whole response is still bounded by Config.MaxResponseBytes.
+	// Defaults to 0 (unlimited)
+	MaxOutputBytes int
+	// Priority orders the queued executions when Config.Scheduler is enabled,
+	// an execution with a higher priority leaves the queue sooner.
+	// Defaults to 0
+	Priority int
+	// Key identifies the tenant or the user the execution belongs to, so
+	// Config.Scheduler can share the concurrency fairly across the keys.
+	// Defaults to "" (every execution without a key shares the same key)
+	Key string
+}
+
+// File is a single file of a multi-file CodeRequest.
+type File struct {
+	Name string `json:"name"`
+	Code string `json:"code"`
+	// Entrypoint marks the file that is passed to the compiler or the interpreter.
+	// The amount of entrypoints is limited per runtime.
+	Entrypoint bool `json:"entrypoint"`
 }
 
 type codeRequestSimplified struct {
 	Language       string `json:"language"`
 	Version        string `json:"version"`
 	Code           string `json:"code"`
+	Files          []File `json:"files,omitempty"`
 	CompileTimeout int32  `json:"compileTimeout,omitempty"`
 	RunTimeout     int32  `json:"runTimeout,omitempty"`
 	MemoryLimit    int32  `json:"memoryLimit,omitempty"`
@@ -90,6 +112,10 @@ type Output struct {
 	Stderr   string `json:"stderr"`
 	Output   string `json:"output"`
 	ExitCode int    `json:"exitCode"`
+	// Truncated is true if the output was cut off, either by CodeRequest.MaxOutputBytes
+	// or because the response exceeded Config.MaxResponseBytes. A truncated output that
+	// was cut off by the response size might miss the exit code.
+	Truncated bool `json:"-"`
 }
 
 type CodeResponse struct {
@@ -97,8 +123,30 @@ type CodeResponse struct {
 	Version  string `json:"version"`
 	Compile  Output `json:"compile"`
 	Runtime  Output `json:"runtime"`
+	// Quota is parsed from the quota headers of the response, which Pesto's API
+	// does not send yet. See HeaderMonthlyLimit.
+	Quota Quota `json:"-"`
+	// Metadata is populated from the response body and headers, which Pesto's
+	// API does not send yet, along with the latency measured on the client.
+	// See Metadata.
+	Metadata Metadata `json:"-"`
+}
+
+// codeResponseBody is the execute response body, with the optional metadata object.
+type codeResponseBody struct {
+	CodeResponse
+	Metadata *metadataBody `json:"metadata"`
 }
 
+// Executor executes code. Client is the Executor of Pesto's API, while the
+// local package provides an Executor that runs the code on the local machine,
+// so the code that depends on Executor can run against either of them.
+type Executor interface {
+	Execute(ctx context.Context, codeRequest CodeRequest) (CodeResponse, error)
+}
+
+var _ Executor = (*Client)(nil)
+
 // Execute calls the execute endpoint, and execute the given code from the codeRequest parameter.
 //
 // Custom language and version outside of the defined ones are allowed through:
@@ -111,63 +159,76 @@ type CodeResponse struct {
 // Make sure that you put the correct language and version combination, otherwise, an error
 // of ErrRuntimeNotFound will be returned.
 //
-// If language, version, or code is empty, ErrMissingParameters will be returned.
+// If language is empty, or both code and files are empty, ErrMissingParameters will be returned.
+// If the server rejects the entrypoints of the files, a *ValidationError will be returned.
 // If the combination between language and version is not found on the server,
 // ErrRuntimeNotFound will be returned.
+//
+// If the response exceeds Config.MaxResponseBytes, the outputs that were received
+// are returned and marked as truncated. ErrResponseTooLarge is returned only if
+// nothing could be salvaged from the response.
+//
+// If Config.Scheduler is enabled, the execution might wait in the queue first.
+// If it waits longer than SchedulerConfig.MaxQueueTime, ErrQueueTimeout will be returned.
 func (c *Client) Execute(ctx context.Context, codeRequest CodeRequest) (CodeResponse, error) {
-	body := codeRequestSimplified{
-		Language:    string(codeRequest.Language),
-		Version:     string(codeRequest.Version),
-		Code:        codeRequest.Code,
-		MemoryLimit: codeRequest.MemoryLimit,
-	}
+	return c.execute(ctx, codeRequest, nil)
+}
 
-	if codeRequest.CompileTimeout > 1000000 {
-		body.CompileTimeout = int32(codeRequest.CompileTimeout) / 1000000
+// execute is Execute with a hook that is called once the execution leaves the queue.
+func (c *Client) execute(ctx context.Context, codeRequest CodeRequest, onStart func()) (CodeResponse, error) {
+	// The call is registered before it is queued, so Close can cancel the queued executions.
+	ctx, release, err := c.lifecycle.acquire(ctx)
+	if err != nil {
+		return CodeResponse{}, err
 	}
-
-	if codeRequest.RunTimeout > 1000000 {
-		body.RunTimeout = int32(codeRequest.RunTimeout) / 1000000
+	defer release()
+
+	var queueWait time.Duration
+	if c.scheduler != nil {
+		var free func()
+		free, queueWait, err = c.scheduler.acquire(ctx, codeRequest.Key, codeRequest.Priority)
+		if err != nil {
+			return CodeResponse{}, err
+		}
+		defer free()
 	}
 
-	requestBody, err := json.Marshal(body)
-	if err != nil {
-		return CodeResponse{}, fmt.Errorf("marshalling json body: %w", err)
+	if onStart != nil {
+		onStart()
 	}
 
-	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL.JoinPath("/api/execute").String(), bytes.NewReader(requestBody))
-	if err != nil {
-		return CodeResponse{}, fmt.Errorf("creating request: %w", err)
-	}
+	tracer := newLatencyTracer()
+	ctx = tracer.withContext(ctx)
 
-	response, err := c.sendRequest(ctx, request)
-	if err != nil {
-		return CodeResponse{}, fmt.Errorf("sending request: %w", err)
+	body := codeRequestSimplified{
+		Language:    string(codeRequest.Language),
+		Version:     string(codeRequest.Version),
+		Code:        codeRequest.Code,
+		Files:       codeRequest.Files,
+		MemoryLimit: codeRequest.MemoryLimit,
 	}
 
-	if response.StatusCode != 200 {
-		var errResponse errorResponse
-		// HACK: the error is intentionally not handled, we wanted to leave the empty errorResponse struct
-		// if there is any non-json response being sent from the server
-		_ = json.NewDecoder(response.Body).Decode(&errResponse)
-		err = response.Body.Close()
-		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrClosedPipe) && !errors.Is(err, http.ErrBodyReadAfterClose) {
-			return CodeResponse{}, fmt.Errorf("closing response body: %w", err)
-		}
+	if codeRequest.CompileTimeout > time.Millisecond {
+		body.CompileTimeout = int32(codeRequest.CompileTimeout / time.Millisecond)
+	}
 
-		return CodeResponse{}, c.handleErrorCode(response.StatusCode, errResponse)
+	if codeRequest.RunTimeout > time.Millisecond {
+		body.RunTimeout = int32(codeRequest.RunTimeout / time.Millisecond)
 	}
 
-	var codeResponse CodeResponse
-	err = json.NewDecoder(response.Body).Decode(&codeResponse)
+	result, err := do[codeResponseBody](ctx, c, http.MethodPost, "/api/execute", body)
 	if err != nil {
-		return CodeResponse{}, fmt.Errorf("reading json body: %w", err)
+		return CodeResponse{}, err
 	}
 
-	err = response.Body.Close()
-	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrClosedPipe) && !errors.Is(err, http.ErrBodyReadAfterClose) {
-		return CodeResponse{}, fmt.Errorf("closing response body: %w", err)
+	codeResponse := result.body.CodeResponse
+	if codeRequest.MaxOutputBytes > 0 {
+		codeResponse.Compile.truncate(codeRequest.MaxOutputBytes)
+		codeResponse.Runtime.truncate(codeRequest.MaxOutputBytes)
 	}
-
+	codeResponse.Quota = result.quota
+	codeResponse.Metadata = newMetadata(result.body.Metadata, result.header)
+	codeResponse.Metadata.Latency = tracer.finish()
+	codeResponse.Metadata.QueueWait = queueWait
 	return codeResponse, nil
 }
@@ -2,8 +2,11 @@ package pesto_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
@@ -30,8 +33,8 @@ func TestClient_Execute(t *testing.T) {
 				Language:       "Python",
 				Code:           "print('Hello World')",
 				Version:        "3.10.2",
-				CompileTimeout: time.Minute,
-				RunTimeout:     time.Minute,
+				CompileTimeout: 30 * time.Second,
+				RunTimeout:     30 * time.Second,
 			},
 		)
 		if err != nil {
@@ -213,6 +216,47 @@ func TestClient_Execute(t *testing.T) {
 	})
 }
 
+func TestClient_Execute_Timeouts(t *testing.T) {
+	var body struct {
+		CompileTimeout int32 `json:"compileTimeout"`
+		RunTimeout     int32 `json:"runTimeout"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"language":"Python","version":"3.10.2","compile":{},"runtime":{}}`))
+	}))
+	defer server.Close()
+
+	client, err := pesto.NewClientWithConfig(pesto.Config{
+		Token:   token,
+		BaseURL: mustParseURL(t, server.URL),
+	})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// A timeout longer than math.MaxInt32 nanoseconds, about 2.1 seconds,
+	// must not overflow when it is converted to milliseconds.
+	_, err = client.Execute(ctx, pesto.CodeRequest{
+		Language:       pesto.LanguagePython,
+		Version:        pesto.VersionLatest,
+		Code:           "print(1)",
+		CompileTimeout: 5 * time.Second,
+		RunTimeout:     2500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if body.CompileTimeout != 5000 || body.RunTimeout != 2500 {
+		t.Errorf("expecting the timeouts to be 5000 and 2500 milliseconds, got %d and %d", body.CompileTimeout, body.RunTimeout)
+	}
+}
+
 func ExampleClient_Execute() {
 	client, err := pesto.NewClientWithConfig(pesto.Config{
 		Token:   token,
@@ -232,8 +276,8 @@ func ExampleClient_Execute() {
 			Language:       "Python",
 			Code:           "print('Hello World')",
 			Version:        "3.10.2",
-			CompileTimeout: time.Minute,
-			RunTimeout:     time.Minute,
+			CompileTimeout: 30 * time.Second,
+			RunTimeout:     30 * time.Second,
 		},
 	)
 	if err != nil {
@@ -0,0 +1,512 @@
+// Package toml parses the subset of TOML that is used by the rce package
+// definitions: tables, bare and quoted keys, strings of every kind, integers,
+// floats, booleans, arrays and inline tables. Dates and array of tables are not supported.
+package toml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Parse parses the TOML document into a map. The values are string, int64,
+// float64, bool, []any or map[string]any.
+func Parse(data []byte) (map[string]any, error) {
+	p := &parser{data: string(data), line: 1}
+	root := make(map[string]any)
+	current := root
+
+	for {
+		p.skipTrivia()
+		if p.eof() {
+			return root, nil
+		}
+
+		if p.peek() == '[' {
+			p.pos++
+			if p.peek() == '[' {
+				return nil, p.errorf("array of tables is not supported")
+			}
+
+			path, err := p.parseKey()
+			if err != nil {
+				return nil, err
+			}
+
+			p.skipSpaces()
+			if !p.consume(']') {
+				return nil, p.errorf("expecting ']' after the table name")
+			}
+
+			current, err = table(root, path)
+			if err != nil {
+				return nil, p.errorf("%s", err.Error())
+			}
+		} else {
+			if err := p.parseKeyValue(current); err != nil {
+				return nil, err
+			}
+		}
+
+		p.skipSpaces()
+		p.skipComment()
+		if p.consume('\n') || p.consumeString("\r\n") {
+			p.line++
+		} else if !p.eof() {
+			return nil, p.errorf("expecting a new line, got %q", p.peek())
+		}
+	}
+}
+
+type parser struct {
+	data string
+	pos  int
+	line int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("toml: line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+
+	return p.data[p.pos]
+}
+
+func (p *parser) consume(c byte) bool {
+	if p.peek() == c && !p.eof() {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) consumeString(s string) bool {
+	if strings.HasPrefix(p.data[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) skipSpaces() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+func (p *parser) skipComment() {
+	if p.peek() != '#' {
+		return
+	}
+
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+}
+
+// skipTrivia skips the whitespaces, the new lines and the comments.
+func (p *parser) skipTrivia() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) parseKeyValue(target map[string]any) error {
+	path, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+
+	p.skipSpaces()
+	if !p.consume('=') {
+		return p.errorf("expecting '=' after the key %q", strings.Join(path, "."))
+	}
+	p.skipSpaces()
+
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	parent, err := table(target, path[:len(path)-1])
+	if err != nil {
+		return p.errorf("%s", err.Error())
+	}
+
+	key := path[len(path)-1]
+	if _, ok := parent[key]; ok {
+		return p.errorf("duplicate key %q", strings.Join(path, "."))
+	}
+
+	parent[key] = value
+	return nil
+}
+
+// parseKey parses a dotted key, made of bare and quoted keys.
+func (p *parser) parseKey() ([]string, error) {
+	var path []string
+	for {
+		p.skipSpaces()
+
+		var key string
+		switch p.peek() {
+		case '"':
+			s, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		case '\'':
+			s, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expecting a key, got %q", p.peek())
+			}
+			key = p.data[start:p.pos]
+		}
+
+		path = append(path, key)
+
+		p.skipSpaces()
+		if !p.consume('.') {
+			return path, nil
+		}
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *parser) parseValue() (any, error) {
+	switch {
+	case strings.HasPrefix(p.data[p.pos:], `"""`):
+		return p.parseMultilineBasicString()
+	case strings.HasPrefix(p.data[p.pos:], `'''`):
+		return p.parseMultilineLiteralString()
+	case p.peek() == '"':
+		return p.parseBasicString()
+	case p.peek() == '\'':
+		return p.parseLiteralString()
+	case p.peek() == '[':
+		return p.parseArray()
+	case p.peek() == '{':
+		return p.parseInlineTable()
+	case p.consumeString("true"):
+		return true, nil
+	case p.consumeString("false"):
+		return false, nil
+	}
+
+	return p.parseNumber()
+}
+
+func (p *parser) parseBasicString() (string, error) {
+	p.pos++ // opening quote
+
+	var sb strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+
+		c := p.data[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			return sb.String(), nil
+		case '\\':
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *parser) parseMultilineBasicString() (string, error) {
+	p.pos += 3
+	p.skipFirstNewLine()
+
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated multi-line string")
+		}
+
+		if p.consumeString(`"""`) {
+			// Up to two quotes are allowed right before the closing delimiter.
+			for i := 0; i < 2 && p.consume('"'); i++ {
+				sb.WriteByte('"')
+			}
+			return sb.String(), nil
+		}
+
+		c := p.data[p.pos]
+		switch c {
+		case '\\':
+			// A backslash at the end of a line trims the following whitespaces and new lines.
+			rest := strings.TrimLeft(p.data[p.pos+1:], " \t\r")
+			if strings.HasPrefix(rest, "\n") {
+				p.pos = len(p.data) - len(rest)
+				for !p.eof() && strings.ContainsRune(" \t\r\n", rune(p.peek())) {
+					if p.peek() == '\n' {
+						p.line++
+					}
+					p.pos++
+				}
+				continue
+			}
+
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+		case '\n':
+			p.line++
+			fallthrough
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *parser) parseLiteralString() (string, error) {
+	p.pos++ // opening quote
+
+	start := p.pos
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+
+		if p.peek() == '\'' {
+			s := p.data[start:p.pos]
+			p.pos++
+			return s, nil
+		}
+
+		p.pos++
+	}
+}
+
+func (p *parser) parseMultilineLiteralString() (string, error) {
+	p.pos += 3
+	p.skipFirstNewLine()
+
+	end := strings.Index(p.data[p.pos:], `'''`)
+	if end < 0 {
+		return "", p.errorf("unterminated multi-line string")
+	}
+	end += p.pos
+
+	// Up to two quotes are allowed right before the closing delimiter.
+	for i := 0; i < 2 && end+3 < len(p.data) && p.data[end+3] == '\''; i++ {
+		end++
+	}
+
+	s := p.data[p.pos:end]
+	p.line += strings.Count(s, "\n")
+	p.pos = end + 3
+	return s, nil
+}
+
+// skipFirstNewLine skips a new line right after the opening delimiter of a multi-line string.
+func (p *parser) skipFirstNewLine() {
+	if p.consume('\n') || p.consumeString("\r\n") {
+		p.line++
+	}
+}
+
+func (p *parser) parseEscape(sb *strings.Builder) error {
+	p.pos++ // backslash
+	if p.eof() {
+		return p.errorf("unterminated escape sequence")
+	}
+
+	c := p.data[p.pos]
+	p.pos++
+
+	switch c {
+	case 'b':
+		sb.WriteByte('\b')
+	case 't':
+		sb.WriteByte('\t')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'r':
+		sb.WriteByte('\r')
+	case 'e':
+		sb.WriteByte(0x1b)
+	case '"':
+		sb.WriteByte('"')
+	case '\\':
+		sb.WriteByte('\\')
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+
+		if p.pos+size > len(p.data) {
+			return p.errorf("unterminated unicode escape sequence")
+		}
+
+		code, err := strconv.ParseUint(p.data[p.pos:p.pos+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorf("invalid unicode escape sequence %q", p.data[p.pos-2:p.pos+size])
+		}
+
+		sb.WriteRune(rune(code))
+		p.pos += size
+	default:
+		return p.errorf("invalid escape sequence \\%c", c)
+	}
+
+	return nil
+}
+
+func (p *parser) parseArray() ([]any, error) {
+	p.pos++ // opening bracket
+
+	values := []any{}
+	for {
+		p.skipTrivia()
+		if p.consume(']') {
+			return values, nil
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		p.skipTrivia()
+		if p.consume(']') {
+			return values, nil
+		}
+
+		if !p.consume(',') {
+			return nil, p.errorf("expecting ',' or ']' in the array, got %q", p.peek())
+		}
+	}
+}
+
+func (p *parser) parseInlineTable() (map[string]any, error) {
+	p.pos++ // opening brace
+
+	values := make(map[string]any)
+	p.skipSpaces()
+	if p.consume('}') {
+		return values, nil
+	}
+
+	for {
+		if err := p.parseKeyValue(values); err != nil {
+			return nil, err
+		}
+
+		p.skipSpaces()
+		if p.consume('}') {
+			return values, nil
+		}
+
+		if !p.consume(',') {
+			return nil, p.errorf("expecting ',' or '}' in the inline table, got %q", p.peek())
+		}
+	}
+}
+
+func (p *parser) parseNumber() (any, error) {
+	start := p.pos
+	for !p.eof() && strings.IndexByte("+-0123456789_.eExobabcdefABCDEFinf", p.peek()) >= 0 {
+		p.pos++
+	}
+
+	raw := p.data[start:p.pos]
+	if raw == "" {
+		return nil, p.errorf("expecting a value, got %q", p.peek())
+	}
+
+	switch strings.TrimLeft(raw, "+-") {
+	case "inf", "nan":
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, p.errorf("invalid float %q", raw)
+		}
+		return f, nil
+	}
+
+	// strconv accepts the underscores and the 0x, 0o and 0b prefixes when the base is 0.
+	if i, err := strconv.ParseInt(raw, 0, 64); err == nil {
+		if len(raw) > 1 && raw[0] == '0' && raw[1] >= '0' && raw[1] <= '9' {
+			return nil, p.errorf("leading zeros are not allowed in %q", raw)
+		}
+		return i, nil
+	}
+
+	if strings.ContainsAny(raw, ".eE") && !strings.HasPrefix(raw, "0x") {
+		f, err := strconv.ParseFloat(strings.ReplaceAll(raw, "_", ""), 64)
+		if err == nil {
+			return f, nil
+		}
+	}
+
+	return nil, p.errorf("invalid value %q", raw)
+}
+
+// table returns the table at the given path, creating the missing ones.
+func table(root map[string]any, path []string) (map[string]any, error) {
+	current := root
+	for i, key := range path {
+		value, ok := current[key]
+		if !ok {
+			next := make(map[string]any)
+			current[key] = next
+			current = next
+			continue
+		}
+
+		next, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("key %q is not a table", strings.Join(path[:i+1], "."))
+		}
+		current = next
+	}
+
+	return current, nil
+}
@@ -0,0 +1,173 @@
+package toml_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/teknologi-umum/pesto/sdk/go/internal/toml"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]any
+	}{
+		{
+			name: "Scalars",
+			input: `# a comment
+language = "Python" # trailing comment
+compiled = false
+memory_limit = 256
+allowed_entrypoints = -1
+big = 1_000
+hex = 0xff
+ratio = 0.5
+`,
+			expected: map[string]any{
+				"language":            "Python",
+				"compiled":            false,
+				"memory_limit":        int64(256),
+				"allowed_entrypoints": int64(-1),
+				"big":                 int64(1000),
+				"hex":                 int64(255),
+				"ratio":               0.5,
+			},
+		},
+		{
+			name:  "Strings",
+			input: "basic = \"tab\\there \\\"quoted\\\" \\u00e9\"\nliteral = 'C:\\path'\n",
+			expected: map[string]any{
+				"basic":   "tab\there \"quoted\" é",
+				"literal": `C:\path`,
+			},
+		},
+		{
+			name: "MultilineStrings",
+			input: `basic = """
+bash -c 'cat <<EOF
+<Project/>
+EOF'"""
+trimmed = """one \
+    two"""
+literal = '''
+raw \n'''
+`,
+			expected: map[string]any{
+				"basic":   "bash -c 'cat <<EOF\n<Project/>\nEOF'",
+				"trimmed": "one two",
+				"literal": "raw \\n",
+			},
+		},
+		{
+			name: "Arrays",
+			input: `build_command = [
+    "g++", # the compiler
+    "-o",
+    "code",
+]
+empty = []
+nested = [[1, 2], ["a"]]
+`,
+			expected: map[string]any{
+				"build_command": []any{"g++", "-o", "code"},
+				"empty":         []any{},
+				"nested":        []any{[]any{int64(1), int64(2)}, []any{"a"}},
+			},
+		},
+		{
+			name: "Tables",
+			input: `name = "root"
+inline = { a = 1, "b c" = true }
+
+[limits]
+memory = 256
+
+[limits.process]
+max = 4
+`,
+			expected: map[string]any{
+				"name":   "root",
+				"inline": map[string]any{"a": int64(1), "b c": true},
+				"limits": map[string]any{
+					"memory":  int64(256),
+					"process": map[string]any{"max": int64(4)},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := toml.Parse([]byte(test.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expecting %#v, got %#v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "UnterminatedString", input: "a = \"abc\nb = 1", expected: "line 1: unterminated string"},
+		{name: "DuplicateKey", input: "a = 1\n\na = 2", expected: "line 3: duplicate key \"a\""},
+		{name: "MissingEquals", input: "a 1", expected: "line 1: expecting '=' after the key \"a\""},
+		{name: "UnterminatedArray", input: "a = [1, 2", expected: "line 1: expecting ',' or ']' in the array"},
+		{name: "TwoValuesOnALine", input: "a = 1 b = 2", expected: "line 1: expecting a new line"},
+		{name: "LeadingZero", input: "a = 012", expected: "line 1: leading zeros are not allowed"},
+		{name: "ArrayOfTables", input: "[[runtime]]", expected: "array of tables is not supported"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := toml.Parse([]byte(test.input))
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("expecting an error containing %q, instead got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestParse_RcePackages(t *testing.T) {
+	paths, err := filepath.Glob("../../../../rce/packages/*/config.toml")
+	if err != nil {
+		t.Fatalf("listing packages: %s", err.Error())
+	}
+
+	if len(paths) == 0 {
+		t.Skip("rce packages are not available")
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(filepath.Dir(path)), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("reading %s: %s", path, err.Error())
+			}
+
+			got, err := toml.Parse(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if _, ok := got["language"].(string); !ok {
+				t.Errorf("expecting language to be a string, got %#v", got["language"])
+			}
+
+			if _, ok := got["run_command"].([]any); !ok {
+				t.Errorf("expecting run_command to be an array, got %#v", got["run_command"])
+			}
+		})
+	}
+}
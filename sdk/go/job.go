@@ -0,0 +1,382 @@
+package pesto
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// JobState is the state of a Job.
+type JobState int
+
+const (
+	// JobQueued means the job was submitted, but the code is not being executed yet,
+	// for example because it is waiting in the Config.Scheduler queue.
+	JobQueued JobState = iota
+	// JobRunning means the code is being executed.
+	JobRunning
+	// JobCompleted means the code was executed, and the result is available.
+	// Note that the code itself might have failed, check the exit codes of the result.
+	JobCompleted
+	// JobFailed means the execute call returned an error, or the job was canceled.
+	JobFailed
+)
+
+// String returns the name of the state.
+func (s JobState) String() string {
+	switch s {
+	case JobQueued:
+		return "queued"
+	case JobRunning:
+		return "running"
+	case JobCompleted:
+		return "completed"
+	case JobFailed:
+		return "failed"
+	}
+
+	return fmt.Sprintf("JobState(%d)", int(s))
+}
+
+// JobCallback is called once the job is completed or failed.
+type JobCallback func(job *Job)
+
+// Job is an execution that was submitted through Client.Submit.
+// Every method of Job is safe for concurrent use.
+type Job struct {
+	id          string
+	request     CodeRequest
+	submittedAt time.Time
+	cancel      context.CancelFunc
+	done        chan struct{}
+
+	mu         sync.Mutex
+	state      JobState
+	startedAt  time.Time
+	finishedAt time.Time
+	response   CodeResponse
+	err        error
+}
+
+// JobInfo is a snapshot of a Job, for monitoring.
+type JobInfo struct {
+	ID          string
+	State       JobState
+	Language    Language
+	Version     Version
+	SubmittedAt time.Time
+	// StartedAt is empty if the job is still queued.
+	StartedAt time.Time
+	// FinishedAt is empty if the job is not finished yet.
+	FinishedAt time.Time
+	// Err is the error of a failed job.
+	Err error
+}
+
+// ID returns the unique identifier of the job.
+func (j *Job) ID() string {
+	return j.id
+}
+
+// State returns the current state of the job.
+func (j *Job) State() JobState {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.state
+}
+
+// Done returns a channel that is closed once the job is completed or failed.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait blocks until the job is completed or failed, and returns its result.
+func (j *Job) Wait() (CodeResponse, error) {
+	<-j.done
+	return j.Result()
+}
+
+// Result returns the result of the job without blocking. If the job is not
+// finished yet, it will return ErrJobNotFinished error.
+func (j *Job) Result() (CodeResponse, error) {
+	select {
+	case <-j.done:
+	default:
+		return CodeResponse{}, ErrJobNotFinished
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.response, j.err
+}
+
+// Cancel cancels the job. A queued job fails without being executed, while
+// a running job fails once the execute call returns. Canceling a finished
+// job does nothing.
+func (j *Job) Cancel() {
+	j.cancel()
+}
+
+// Info returns a snapshot of the job.
+func (j *Job) Info() JobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return JobInfo{
+		ID:          j.id,
+		State:       j.state,
+		Language:    j.request.Language,
+		Version:     j.request.Version,
+		SubmittedAt: j.submittedAt,
+		StartedAt:   j.startedAt,
+		FinishedAt:  j.finishedAt,
+		Err:         j.err,
+	}
+}
+
+func (j *Job) start() {
+	j.mu.Lock()
+	j.state = JobRunning
+	j.startedAt = time.Now()
+	j.mu.Unlock()
+}
+
+func (j *Job) finish(response CodeResponse, err error) {
+	j.mu.Lock()
+	j.response = response
+	j.err = err
+	j.state = JobCompleted
+	if err != nil {
+		j.state = JobFailed
+	}
+	j.finishedAt = time.Now()
+	j.mu.Unlock()
+}
+
+// Submit executes the code in the background, and returns immediately.
+// The job is canceled if ctx is done, so ctx should outlive the job,
+// rather than being bound to the request that submitted it.
+//
+// The callbacks are called in the given order once the job is finished, on a
+// worker pool of Config.CallbackWorkers goroutines that is shared by every job
+// of the client. A slow callback therefore delays the callbacks of the other jobs.
+// A callback may submit another job.
+//
+// If the client is closed, the returned job is already failed with ErrClientClosed,
+// and its callbacks are called on the worker pool like those of any other job.
+func (c *Client) Submit(ctx context.Context, codeRequest CodeRequest, callbacks ...JobCallback) *Job {
+	ctx, cancel := context.WithCancel(ctx)
+	job := &Job{
+		id:          newJobID(),
+		request:     codeRequest,
+		submittedAt: time.Now(),
+		cancel:      cancel,
+		done:        make(chan struct{}),
+		state:       JobQueued,
+	}
+	c.jobs.add(job)
+
+	// The job is registered as a call in flight, so Close waits for the callbacks
+	// to be handed to the worker pool.
+	ctx, release, err := c.lifecycle.acquire(ctx)
+	if err != nil {
+		cancel()
+		c.finishJob(job, CodeResponse{}, err, callbacks)
+		return job
+	}
+
+	go func() {
+		defer release()
+		defer cancel()
+
+		if ctx.Err() != nil {
+			c.finishJob(job, CodeResponse{}, ctx.Err(), callbacks)
+			return
+		}
+
+		response, err := c.execute(ctx, codeRequest, job.start)
+		c.finishJob(job, response, err, callbacks)
+	}()
+
+	return job
+}
+
+// Jobs returns a snapshot of the jobs that are queued or running, followed by
+// the most recently finished jobs, up to Config.JobHistory of them.
+func (c *Client) Jobs() []JobInfo {
+	return c.jobs.snapshot()
+}
+
+func (c *Client) finishJob(job *Job, response CodeResponse, err error, callbacks []JobCallback) {
+	job.finish(response, err)
+	// The job is moved to the history before it is reported as done,
+	// so Jobs is consistent with the waiters.
+	c.jobs.finish(job)
+	close(job.done)
+
+	if len(callbacks) > 0 {
+		c.callbacks.enqueue(job, callbacks)
+	}
+}
+
+func newJobID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// jobRegistry keeps track of the unfinished jobs, and a bounded history of the finished ones.
+type jobRegistry struct {
+	mu         sync.Mutex
+	historyCap int
+	active     []*Job
+	history    []*Job
+}
+
+func (r *jobRegistry) add(job *Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.active = append(r.active, job)
+}
+
+func (r *jobRegistry) finish(job *Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, j := range r.active {
+		if j == job {
+			r.active = append(r.active[:i], r.active[i+1:]...)
+			break
+		}
+	}
+
+	if r.historyCap <= 0 {
+		return
+	}
+
+	r.history = append(r.history, job)
+	if len(r.history) > r.historyCap {
+		r.history = append(r.history[:0], r.history[len(r.history)-r.historyCap:]...)
+	}
+}
+
+func (r *jobRegistry) snapshot() []JobInfo {
+	r.mu.Lock()
+	jobs := make([]*Job, 0, len(r.active)+len(r.history))
+	jobs = append(jobs, r.active...)
+	for i := len(r.history) - 1; i >= 0; i-- {
+		jobs = append(jobs, r.history[i])
+	}
+	r.mu.Unlock()
+
+	infos := make([]JobInfo, len(jobs))
+	for i, job := range jobs {
+		infos[i] = job.Info()
+	}
+
+	return infos
+}
+
+// callbackPool runs the job callbacks on a bounded amount of goroutines. The
+// goroutines are started when there are callbacks to run, and exit once the
+// queue is empty, so an idle pool holds no goroutine and needs no closing.
+//
+// The queue is unbounded, so enqueue never blocks, even when it is called from
+// a callback that submits another job.
+type callbackPool struct {
+	workers int
+	logger  Logger
+
+	mu      sync.Mutex
+	running int
+	queue   []callbackTask
+	// idle is closed once the last worker exits.
+	idle chan struct{}
+}
+
+type callbackTask struct {
+	job       *Job
+	callbacks []JobCallback
+}
+
+func newCallbackPool(workers int, logger Logger) *callbackPool {
+	return &callbackPool{
+		workers: workers,
+		logger:  logger,
+	}
+}
+
+// enqueue hands the callbacks to the workers, and starts another worker
+// if fewer than p.workers are running.
+func (p *callbackPool) enqueue(job *Job, callbacks []JobCallback) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.queue = append(p.queue, callbackTask{job: job, callbacks: callbacks})
+	if p.running < p.workers {
+		p.running++
+		go p.work()
+	}
+}
+
+func (p *callbackPool) work() {
+	for {
+		p.mu.Lock()
+		if len(p.queue) == 0 {
+			p.running--
+			if p.running == 0 && p.idle != nil {
+				close(p.idle)
+				p.idle = nil
+			}
+			p.mu.Unlock()
+			return
+		}
+
+		task := p.queue[0]
+		p.queue[0] = callbackTask{}
+		p.queue = p.queue[1:]
+		p.mu.Unlock()
+
+		p.run(task)
+	}
+}
+
+func (p *callbackPool) run(task callbackTask) {
+	for _, callback := range task.callbacks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					p.logger.Error("pesto: job callback panicked", "job", task.job.id, "panic", fmt.Sprint(r))
+				}
+			}()
+
+			callback(task.job)
+		}()
+	}
+}
+
+// wait waits for the queued callbacks to be done, until ctx is done.
+func (p *callbackPool) wait(ctx context.Context) {
+	p.mu.Lock()
+	if p.running == 0 {
+		p.mu.Unlock()
+		return
+	}
+
+	if p.idle == nil {
+		p.idle = make(chan struct{})
+	}
+	idle := p.idle
+	p.mu.Unlock()
+
+	select {
+	case <-idle:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,268 @@
+package pesto_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+func TestClient_Submit(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{Token: token, BaseURL: happyMockServerURL})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		called := make(chan pesto.JobState, 1)
+		job := client.Submit(ctx, pesto.CodeRequest{
+			Language: pesto.LanguagePython,
+			Version:  pesto.VersionPython,
+			Code:     "print('Hello World')",
+		}, func(job *pesto.Job) {
+			called <- job.State()
+		})
+
+		if job.ID() == "" {
+			t.Errorf("expecting job to have an ID")
+		}
+
+		response, err := job.Wait()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if response.Language != "Python" {
+			t.Errorf("unexpected response: %+v", response)
+		}
+
+		select {
+		case state := <-called:
+			if state != pesto.JobCompleted {
+				t.Errorf("expecting callback to see a completed job, got %s", state)
+			}
+		case <-ctx.Done():
+			t.Fatal("expecting the callback to be called")
+		}
+
+		jobs := client.Jobs()
+		if len(jobs) != 1 || jobs[0].ID != job.ID() || jobs[0].State != pesto.JobCompleted || jobs[0].Language != pesto.LanguagePython {
+			t.Errorf("unexpected jobs snapshot: %+v", jobs)
+		}
+
+		if jobs[0].StartedAt.IsZero() || jobs[0].FinishedAt.Before(jobs[0].StartedAt) {
+			t.Errorf("unexpected job timestamps: %+v", jobs[0])
+		}
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{Token: token, BaseURL: happyMockServerURL})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		job := client.Submit(ctx, pesto.CodeRequest{Language: "Rust", Version: "1.64.0", Code: "fn main() {}"})
+
+		_, err = job.Wait()
+		if !errors.Is(err, pesto.ErrRuntimeNotFound) {
+			t.Errorf("expecting an error of ErrRuntimeNotFound, instead got %v", err)
+		}
+
+		if job.State() != pesto.JobFailed {
+			t.Errorf("expecting job to be failed, got %s", job.State())
+		}
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		started := make(chan struct{}, 1)
+		release := make(chan struct{})
+		server := SlowMockServer(started, release)
+		defer server.Close()
+		defer close(release)
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{Token: token, BaseURL: mustParseURL(t, server.URL)})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		job := client.Submit(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionPython, Code: "sleep"})
+
+		select {
+		case <-started:
+		case <-ctx.Done():
+			t.Fatal("expecting the request to reach the server")
+		}
+
+		if job.State() != pesto.JobRunning {
+			t.Errorf("expecting job to be running, got %s", job.State())
+		}
+
+		_, err = job.Result()
+		if !errors.Is(err, pesto.ErrJobNotFinished) {
+			t.Errorf("expecting an error of ErrJobNotFinished, instead got %v", err)
+		}
+
+		job.Cancel()
+
+		_, err = job.Wait()
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expecting an error of context.Canceled, instead got %v", err)
+		}
+
+		if job.State() != pesto.JobFailed {
+			t.Errorf("expecting job to be failed, got %s", job.State())
+		}
+	})
+
+	t.Run("ClosedClient", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{Token: token, BaseURL: happyMockServerURL})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		err = client.Close(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		called := make(chan struct{})
+		job := client.Submit(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionPython, Code: "print(1)"}, func(*pesto.Job) {
+			close(called)
+		})
+
+		_, err = job.Result()
+		if !errors.Is(err, pesto.ErrClientClosed) {
+			t.Errorf("expecting an error of ErrClientClosed, instead got %v", err)
+		}
+
+		select {
+		case <-called:
+		case <-ctx.Done():
+			t.Fatal("expecting the callback to be called")
+		}
+	})
+
+	t.Run("SubmitFromCallback", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{Token: token, BaseURL: happyMockServerURL, CallbackWorkers: 1})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		request := pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionPython, Code: "print(1)"}
+		done := make(chan struct{}, 50)
+		for i := 0; i < cap(done); i++ {
+			client.Submit(ctx, request, func(*pesto.Job) {
+				// The worker is busy with this callback, while the
+				// callback of the new job is queued behind the others.
+				client.Submit(ctx, request, func(*pesto.Job) {
+					done <- struct{}{}
+				})
+			})
+		}
+
+		for i := 0; i < cap(done); i++ {
+			select {
+			case <-done:
+			case <-ctx.Done():
+				t.Fatalf("expecting every callback to be called, got %d", i)
+			}
+		}
+	})
+
+	t.Run("CloseWaitsForCallbacks", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{Token: token, BaseURL: happyMockServerURL, CallbackWorkers: 1})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		done := make(chan struct{}, 3)
+		for i := 0; i < 3; i++ {
+			client.Submit(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionPython, Code: "print(1)"}, func(*pesto.Job) {
+				time.Sleep(10 * time.Millisecond)
+				done <- struct{}{}
+			})
+		}
+
+		err = client.Close(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if len(done) != 3 {
+			t.Errorf("expecting 3 callbacks to be done, got %d", len(done))
+		}
+	})
+
+	t.Run("CallbackPanic", func(t *testing.T) {
+		logger := &recordingLogger{}
+		client, err := pesto.NewClientWithConfig(pesto.Config{Token: token, BaseURL: happyMockServerURL, Logger: logger})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		called := make(chan struct{})
+		client.Submit(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionPython, Code: "print(1)"},
+			func(*pesto.Job) { panic("boom") },
+			func(*pesto.Job) { close(called) },
+		)
+
+		select {
+		case <-called:
+		case <-ctx.Done():
+			t.Fatal("expecting the next callback to be called after a panic")
+		}
+
+		entry, ok := logger.find("pesto: job callback panicked")
+		if !ok || entry.level != "ERROR" || entry.attrs["panic"] != "boom" {
+			t.Errorf("unexpected panic log: %+v", entry)
+		}
+	})
+
+	t.Run("History", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{Token: token, BaseURL: happyMockServerURL, JobHistory: 2})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		var last *pesto.Job
+		for i := 0; i < 3; i++ {
+			last = client.Submit(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionPython, Code: "print(1)"})
+			_, _ = last.Wait()
+		}
+
+		jobs := client.Jobs()
+		if len(jobs) != 2 {
+			t.Fatalf("expecting 2 jobs in the history, got %d", len(jobs))
+		}
+
+		if jobs[0].ID != last.ID() {
+			t.Errorf("expecting the most recent job first, got %+v", jobs)
+		}
+	})
+}
@@ -2,11 +2,9 @@ package pesto
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"io"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type Runtime struct {
@@ -14,49 +12,91 @@ type Runtime struct {
 	Version  string   `json:"version"`
 	Aliases  []string `json:"aliases"`
 	Compiled bool     `json:"compiled"`
+
+	// The fields below are declared by the package of the runtime, and are
+	// only populated when the server provides them.
+
+	// Extension is the file extension of the code, without the dot.
+	Extension string `json:"extension,omitempty"`
+	// ShouldLimitMemory tells whether the memory limit is applied when the code runs.
+	// It is nil when the server does not provide it.
+	ShouldLimitMemory *bool `json:"shouldLimitMemory,omitempty"`
+	// MemoryLimit is the default memory limit in bytes.
+	MemoryLimit int64 `json:"memoryLimit,omitempty"`
+	// ProcessLimit is the maximum amount of processes the code can spawn.
+	ProcessLimit int64 `json:"processLimit,omitempty"`
+	// AllowedEntrypoints is the maximum amount of entrypoints of a request,
+	// or -1 for no limit.
+	AllowedEntrypoints int64 `json:"allowedEntrypoints,omitempty"`
 }
 
 type RuntimeResponse struct {
 	Runtime []Runtime `json:"runtime"`
 }
 
+// Find returns the runtime of the language and version, the same way the server
+// picks the runtime of an execute request. An empty version or VersionLatest
+// picks the newest version of the language.
+func (r RuntimeResponse) Find(language Language, version Version) (Runtime, bool) {
+	var found Runtime
+	var ok bool
+	for _, runtime := range r.Runtime {
+		if runtime.Language != string(language) {
+			continue
+		}
+
+		if version == "" || version == VersionLatest {
+			if !ok || newerVersion(runtime.Version, found.Version) {
+				found, ok = runtime, true
+			}
+			continue
+		}
+
+		if runtime.Version == string(version) {
+			return runtime, true
+		}
+	}
+
+	return found, ok
+}
+
 // ListRuntimes calls the list-runtimes endpoint. The Language and Version item from the response struct
 // can be used to create an execute code request.
 func (c *Client) ListRuntimes(ctx context.Context) (RuntimeResponse, error) {
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL.JoinPath("/api/list-runtimes").String(), nil)
+	result, err := do[RuntimeResponse](ctx, c, http.MethodGet, "/api/list-runtimes", nil)
 	if err != nil {
-		return RuntimeResponse{}, fmt.Errorf("creating request: %w", err)
+		return RuntimeResponse{}, err
 	}
 
-	response, err := c.sendRequest(ctx, request)
-	if err != nil {
-		return RuntimeResponse{}, fmt.Errorf("sending request: %w", err)
-	}
+	return result.body, nil
+}
 
-	if response.StatusCode != 200 {
-		var errResponse errorResponse
-		// HACK: the error is intentionally not handled, we wanted to leave the empty errorResponse struct
-		// if there is any non-json response being sent from the server
-		_ = json.NewDecoder(response.Body).Decode(&errResponse)
+// newerVersion reports whether version a is newer than version b, comparing the
+// major, minor and patch numbers like rce does. On a tie, a nightly version is older.
+func newerVersion(a, b string) bool {
+	partsA := strings.FieldsFunc(a, isVersionSeparator)
+	partsB := strings.FieldsFunc(b, isVersionSeparator)
 
-		err = response.Body.Close()
-		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrClosedPipe) && !errors.Is(err, http.ErrBodyReadAfterClose) {
-			return RuntimeResponse{}, fmt.Errorf("closing response body: %w", err)
+	for i := 0; i < 3; i++ {
+		numberA, numberB := versionNumber(partsA, i), versionNumber(partsB, i)
+		if numberA != numberB {
+			return numberA > numberB
 		}
-
-		return RuntimeResponse{}, c.handleErrorCode(response.StatusCode, errResponse)
 	}
 
-	var runtimes RuntimeResponse
-	err = json.NewDecoder(response.Body).Decode(&runtimes)
-	if err != nil {
-		return RuntimeResponse{}, fmt.Errorf("reading json body: %w", err)
-	}
+	return len(partsB) > 3 && partsB[3] == "nightly" && !(len(partsA) > 3 && partsA[3] == "nightly")
+}
+
+func isVersionSeparator(r rune) bool {
+	return r == '.' || r == '-'
+}
 
-	err = response.Body.Close()
-	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrClosedPipe) && !errors.Is(err, http.ErrBodyReadAfterClose) {
-		return RuntimeResponse{}, fmt.Errorf("closing response body: %w", err)
+func versionNumber(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
 	}
 
-	return runtimes, nil
+	// rce treats a part that is not a number as 0.
+	number, _ := strconv.Atoi(parts[i])
+	return number
 }
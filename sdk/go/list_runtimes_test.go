@@ -91,6 +91,45 @@ func TestClient_ListRuntimes(t *testing.T) {
 	})
 }
 
+func TestRuntimeResponse_Find(t *testing.T) {
+	runtimes := pesto.RuntimeResponse{
+		Runtime: []pesto.Runtime{
+			{Language: "Go", Version: "1.20.2"},
+			{Language: "Go", Version: "1.21.0-nightly"},
+			{Language: "Go", Version: "1.21.0"},
+			{Language: "Go", Version: "1.9.7"},
+			{Language: "Python", Version: "3.10.2"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		language pesto.Language
+		version  pesto.Version
+		expected string
+		found    bool
+	}{
+		{name: "Exact", language: "Go", version: "1.20.2", expected: "1.20.2", found: true},
+		{name: "Latest", language: "Go", version: pesto.VersionLatest, expected: "1.21.0", found: true},
+		{name: "EmptyVersion", language: "Python", version: "", expected: "3.10.2", found: true},
+		{name: "VersionNotFound", language: "Go", version: "1.18.2"},
+		{name: "LanguageNotFound", language: "Rust", version: pesto.VersionLatest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runtime, ok := runtimes.Find(test.language, test.version)
+			if ok != test.found {
+				t.Fatalf("expecting found to be %t, got %t", test.found, ok)
+			}
+
+			if runtime.Version != test.expected {
+				t.Errorf("expecting version %q, got %q", test.expected, runtime.Version)
+			}
+		})
+	}
+}
+
 func ExampleClient_ListRuntimes() {
 	client, err := pesto.NewClientWithConfig(pesto.Config{
 		Token:   token,
@@ -0,0 +1,590 @@
+// Package local provides a pesto.Executor that runs the code on the local machine
+// instead of calling Pesto's API, for offline development and CI.
+//
+// The runtimes are defined by the same config.toml files as the packages of
+// Pesto's rce service, and the code is compiled and run the same way: in a
+// temporary directory, with the compile and run timeouts, and with the resource
+// limits applied through prlimit. The compilers and interpreters must be installed
+// on the local machine, use Config.Environment to point PATH to them:
+//
+//	packages, err := manifest.Load("rce/packages")
+//	if err != nil {
+//		return err
+//	}
+//
+//	executor, err := local.NewExecutor(local.Config{
+//		Packages:    packages,
+//		Environment: map[string]string{"PATH": os.Getenv("PATH")},
+//	})
+package local
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/manifest"
+)
+
+const (
+	// defaultTimeout is the compile and run timeout of rce when the request has none.
+	defaultTimeout = 10 * time.Second
+	// defaultMaxOutputBytes is where rce cuts every output field.
+	defaultMaxOutputBytes = 5000
+	// maxOpenFiles, maxCompileFileSize and maxRunFileSize are the fixed limits of rce.
+	maxOpenFiles       = 2048
+	maxCompileFileSize = 10_000_000
+	maxRunFileSize     = 30_000_000
+)
+
+var (
+	// ErrNoPackages indicates the executor was created without any package.
+	ErrNoPackages = errors.New("no packages")
+	// ErrPrlimitNotFound indicates prlimit is not installed, so the resource
+	// limits of the packages can't be applied. Set Config.WithoutLimits to run
+	// the code without them.
+	ErrPrlimitNotFound = errors.New("prlimit not found")
+)
+
+// Config provides configuration for the Executor.
+type Config struct {
+	// Packages are the runtimes the Executor can run. Use manifest.Load to read
+	// them from the rce/packages directory.
+	Packages []manifest.Manifest
+	// WorkDir is where the temporary directory of every execution is created.
+	// Defaults to os.TempDir()
+	WorkDir string
+	// Environment overrides the environment variables of every package, such as
+	// PATH, since the packages point to the installation directories of rce.
+	// Defaults to nil
+	Environment map[string]string
+	// MaxOutputBytes cuts every output field, like rce does.
+	// Defaults to 5000
+	MaxOutputBytes int
+	// Prlimit is the path of the prlimit command of util-linux, which applies the
+	// resource limits of the packages.
+	// Defaults to "prlimit", looked up in PATH
+	Prlimit string
+	// WithoutLimits runs the code without the resource limits of the packages,
+	// and only the timeouts apply. This is required where prlimit is not
+	// available, such as on macOS.
+	// Defaults to false
+	WithoutLimits bool
+	// LimitProcesses applies the process_limit of the packages. The kernel counts
+	// every process of the user against the limit, rather than the processes of the
+	// execution, so only enable it when running as a dedicated user, like rce does.
+	// Defaults to false
+	LimitProcesses bool
+}
+
+// Executor runs the code on the local machine. It implements pesto.Executor.
+// It is safe for concurrent use by multiple goroutines.
+type Executor struct {
+	config  Config
+	prlimit string
+}
+
+var _ pesto.Executor = (*Executor)(nil)
+
+// NewExecutor creates an Executor with the given Config.
+// If no package is provided, it will return ErrNoPackages error.
+// If prlimit is not found and Config.WithoutLimits is not set,
+// it will return ErrPrlimitNotFound error.
+func NewExecutor(config Config) (*Executor, error) {
+	if len(config.Packages) == 0 {
+		return nil, ErrNoPackages
+	}
+
+	if config.WorkDir == "" {
+		config.WorkDir = os.TempDir()
+	}
+
+	if config.MaxOutputBytes <= 0 {
+		config.MaxOutputBytes = defaultMaxOutputBytes
+	}
+
+	if config.Prlimit == "" {
+		config.Prlimit = "prlimit"
+	}
+
+	executor := &Executor{config: config}
+	if config.WithoutLimits {
+		return executor, nil
+	}
+
+	prlimit, err := exec.LookPath(config.Prlimit)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPrlimitNotFound, err.Error())
+	}
+	executor.prlimit = prlimit
+
+	return executor, nil
+}
+
+// ListRuntimes returns the runtimes of the packages, like Client.ListRuntimes.
+func (e *Executor) ListRuntimes(ctx context.Context) (pesto.RuntimeResponse, error) {
+	runtimes := make([]pesto.Runtime, 0, len(e.config.Packages))
+	for _, pkg := range e.config.Packages {
+		runtimes = append(runtimes, pkg.Runtime())
+	}
+
+	return pesto.RuntimeResponse{Runtime: runtimes}, nil
+}
+
+// Execute compiles and runs the code, and returns the same response as Client.Execute.
+//
+// If language, both code and files, or the name of a file are empty, ErrMissingParameters
+// will be returned. If the files have more entrypoints than the package allows,
+// a *pesto.ValidationError will be returned. If the combination between language and version is not found on the packages,
+// ErrRuntimeNotFound will be returned.
+//
+// Like rce, a process that is killed for exceeding the timeout reports an exit
+// code of 0. Check Metadata.TimedOut to tell it apart.
+func (e *Executor) Execute(ctx context.Context, codeRequest pesto.CodeRequest) (pesto.CodeResponse, error) {
+	if codeRequest.Language == "" {
+		return pesto.CodeResponse{}, fmt.Errorf("%w: language is required", pesto.ErrMissingParameters)
+	}
+
+	if codeRequest.Code == "" && len(codeRequest.Files) == 0 {
+		return pesto.CodeResponse{}, fmt.Errorf("%w: Both code and files must not be empty", pesto.ErrMissingParameters)
+	}
+
+	pkg, ok := e.find(codeRequest.Language, codeRequest.Version)
+	if !ok {
+		return pesto.CodeResponse{}, pesto.ErrRuntimeNotFound
+	}
+
+	files, err := codeFiles(codeRequest, pkg.Extension)
+	if err != nil {
+		return pesto.CodeResponse{}, err
+	}
+
+	var entrypoints []string
+	for _, file := range files {
+		if file.Entrypoint {
+			entrypoints = append(entrypoints, file.Name)
+		}
+	}
+
+	if pkg.AllowedEntrypoints != -1 && len(files) > 1 && int64(len(entrypoints)) > pkg.AllowedEntrypoints {
+		return pesto.CodeResponse{}, &pesto.ValidationError{
+			Problems: []string{fmt.Sprintf("Maximum allowed entrypoint exceeded of %d entries", pkg.AllowedEntrypoints)},
+		}
+	}
+
+	dir, err := os.MkdirTemp(e.config.WorkDir, "pesto-")
+	if err != nil {
+		return pesto.CodeResponse{}, fmt.Errorf("creating work directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, file := range files {
+		err := writeFile(dir, file)
+		if err != nil {
+			return pesto.CodeResponse{}, err
+		}
+	}
+
+	memoryLimit := int64(codeRequest.MemoryLimit)
+	if memoryLimit <= 0 {
+		memoryLimit = pkg.MemoryLimit
+	}
+
+	response := pesto.CodeResponse{Language: pkg.Language, Version: pkg.Version}
+
+	if pkg.Compiled {
+		step, err := e.run(ctx, dir, pkg, command{
+			args:        replaceFile(pkg.BuildCommand, strings.Join(entrypoints, " ")),
+			timeout:     timeoutOrDefault(codeRequest.CompileTimeout),
+			fileSize:    maxCompileFileSize,
+			memoryLimit: memoryLimit,
+		})
+		if err != nil {
+			return pesto.CodeResponse{}, err
+		}
+
+		response.Compile = step.output
+		response.Metadata.CompileDuration = step.duration
+		response.Metadata.TimedOut = step.timedOut
+		if step.output.ExitCode != 0 {
+			e.truncate(&response, codeRequest.MaxOutputBytes)
+			return response, nil
+		}
+	}
+
+	runFiles := make([]string, 0, len(entrypoints))
+	for _, entrypoint := range entrypoints {
+		if pkg.Compiled {
+			runFiles = append(runFiles, filepath.Join(dir, "code"))
+		} else {
+			runFiles = append(runFiles, entrypoint)
+		}
+	}
+
+	run := command{
+		args:     replaceFile(pkg.RunCommand, strings.Join(runFiles, " ")),
+		timeout:  timeoutOrDefault(codeRequest.RunTimeout),
+		fileSize: maxRunFileSize,
+	}
+	if pkg.ShouldLimitMemory {
+		run.memoryLimit = memoryLimit
+	}
+
+	step, err := e.run(ctx, dir, pkg, run)
+	if err != nil {
+		return pesto.CodeResponse{}, err
+	}
+
+	response.Runtime = step.output
+	response.Metadata.RunDuration = step.duration
+	response.Metadata.TimedOut = response.Metadata.TimedOut || step.timedOut
+	e.truncate(&response, codeRequest.MaxOutputBytes)
+	return response, nil
+}
+
+// find returns the package of the language and version. An empty version,
+// or "latest", finds the latest version of the language.
+func (e *Executor) find(language pesto.Language, version pesto.Version) (manifest.Manifest, bool) {
+	var found manifest.Manifest
+	var ok bool
+	for _, pkg := range e.config.Packages {
+		if pkg.Language != string(language) {
+			continue
+		}
+
+		if version == "" || version == pesto.VersionLatest {
+			if !ok || newerVersion(pkg.Version, found.Version) {
+				found, ok = pkg, true
+			}
+			continue
+		}
+
+		if pkg.Version == string(version) {
+			return pkg, true
+		}
+	}
+
+	return found, ok
+}
+
+// truncate cuts the outputs to the MaxOutputBytes of the request, like Client.Execute does.
+func (e *Executor) truncate(response *pesto.CodeResponse, maxOutputBytes int) {
+	if maxOutputBytes <= 0 {
+		return
+	}
+
+	for _, output := range []*pesto.Output{&response.Compile, &response.Runtime} {
+		for _, field := range []*string{&output.Stdout, &output.Stderr, &output.Output} {
+			if len(*field) > maxOutputBytes {
+				*field = string(cutUTF8([]byte(*field), maxOutputBytes))
+				output.Truncated = true
+			}
+		}
+	}
+}
+
+// codeFiles returns the files of the request. The code of a request without
+// files is named like rce does, while the files must be named by the caller.
+func codeFiles(codeRequest pesto.CodeRequest, extension string) ([]pesto.File, error) {
+	if len(codeRequest.Files) == 0 {
+		return []pesto.File{{Name: "code0." + extension, Code: codeRequest.Code, Entrypoint: true}}, nil
+	}
+
+	for _, file := range codeRequest.Files {
+		if file.Name == "" {
+			return nil, fmt.Errorf("%w: File name cannot be empty", pesto.ErrMissingParameters)
+		}
+	}
+
+	return codeRequest.Files, nil
+}
+
+func writeFile(dir string, file pesto.File) error {
+	name := filepath.Clean(filepath.FromSlash(file.Name))
+	if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("invalid file name %q", file.Name)
+	}
+
+	path := filepath.Join(dir, name)
+	err := os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", file.Name, err)
+	}
+
+	err = os.WriteFile(path, []byte(file.Code), 0o700)
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", file.Name, err)
+	}
+
+	return nil
+}
+
+func timeoutOrDefault(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return defaultTimeout
+	}
+
+	return timeout
+}
+
+// replaceFile replaces the first "{file}" of every argument, like rce does.
+func replaceFile(args []string, file string) []string {
+	replaced := make([]string, len(args))
+	for i, arg := range args {
+		replaced[i] = strings.Replace(arg, "{file}", file, 1)
+	}
+
+	return replaced
+}
+
+// command is a single compile or run step.
+type command struct {
+	args        []string
+	timeout     time.Duration
+	fileSize    int64
+	memoryLimit int64
+}
+
+type stepResult struct {
+	output   pesto.Output
+	duration time.Duration
+	timedOut bool
+}
+
+func (e *Executor) run(ctx context.Context, dir string, pkg manifest.Manifest, c command) (stepResult, error) {
+	if len(c.args) == 0 {
+		return stepResult{}, fmt.Errorf("%s %s: empty command", pkg.Language, pkg.Version)
+	}
+
+	env := e.environment(pkg)
+
+	args := c.args
+	if e.prlimit != "" {
+		limits := []string{
+			e.prlimit,
+			"--nofile=" + strconv.Itoa(maxOpenFiles),
+			"--fsize=" + strconv.FormatInt(c.fileSize, 10),
+		}
+		if e.config.LimitProcesses && pkg.ProcessLimit > 0 {
+			limits = append(limits, "--nproc="+strconv.FormatInt(pkg.ProcessLimit, 10))
+		}
+		if c.memoryLimit > 0 {
+			limits = append(limits, "--as="+strconv.FormatInt(c.memoryLimit, 10))
+		}
+
+		// prlimit looks the command up on the PATH of the environment.
+		args = append(limits, args...)
+	} else {
+		path, err := lookPath(args[0], env["PATH"])
+		if err != nil {
+			return stepResult{}, err
+		}
+
+		args = append([]string{path}, args[1:]...)
+	}
+
+	output := &outputs{limit: e.config.MaxOutputBytes}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = environ(env)
+	cmd.Stdout = stream{outputs: output, own: &output.stdout}
+	cmd.Stderr = stream{outputs: output, own: &output.stderr}
+	setProcessGroup(cmd)
+
+	stepCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := cmd.Start()
+	if err != nil {
+		return stepResult{}, fmt.Errorf("starting %s: %w", c.args[0], err)
+	}
+
+	waitErr := make(chan error, 1)
+	go func() {
+		waitErr <- cmd.Wait()
+	}()
+
+	select {
+	case err = <-waitErr:
+	case <-stepCtx.Done():
+		killProcessGroup(cmd)
+		err = <-waitErr
+	}
+
+	result := stepResult{duration: time.Since(start)}
+
+	if ctx.Err() != nil {
+		return stepResult{}, ctx.Err()
+	}
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return stepResult{}, fmt.Errorf("running %s: %w", c.args[0], err)
+	}
+
+	result.timedOut = errors.Is(stepCtx.Err(), context.DeadlineExceeded)
+	result.output = output.result()
+
+	// A process that was killed by a signal has no exit code, and rce reports 0.
+	if exitCode := cmd.ProcessState.ExitCode(); exitCode > 0 {
+		result.output.ExitCode = exitCode
+	}
+
+	return result, nil
+}
+
+// environment returns the environment variables of the package, on top of
+// the PATH of the current process, like rce does.
+func (e *Executor) environment(pkg manifest.Manifest) map[string]string {
+	env := map[string]string{"PATH": os.Getenv("PATH")}
+	for key, value := range pkg.Environment {
+		env[key] = value
+	}
+
+	for key, value := range e.config.Environment {
+		env[key] = value
+	}
+
+	return env
+}
+
+func environ(env map[string]string) []string {
+	variables := make([]string, 0, len(env))
+	for key, value := range env {
+		variables = append(variables, key+"="+value)
+	}
+
+	sort.Strings(variables)
+	return variables
+}
+
+// lookPath is exec.LookPath with the PATH of the package instead of the PATH of the
+// current process. A name with a path separator is left to be resolved from the work directory.
+func lookPath(name string, path string) (string, error) {
+	if strings.ContainsRune(name, filepath.Separator) || strings.ContainsRune(name, '/') {
+		return name, nil
+	}
+
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+
+		candidate := filepath.Join(dir, name)
+		info, err := os.Stat(candidate)
+		if err == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s: %w", name, exec.ErrNotFound)
+}
+
+// outputs collects stdout, stderr and their interleaved output, up to limit bytes each.
+type outputs struct {
+	mu        sync.Mutex
+	limit     int
+	stdout    bytes.Buffer
+	stderr    bytes.Buffer
+	output    bytes.Buffer
+	truncated bool
+}
+
+func (o *outputs) write(buffer *bytes.Buffer, p []byte) {
+	room := o.limit - buffer.Len()
+	if room < len(p) {
+		o.truncated = true
+		if room <= 0 {
+			return
+		}
+		p = p[:room]
+	}
+
+	buffer.Write(p)
+}
+
+func (o *outputs) result() pesto.Output {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return pesto.Output{
+		Stdout:    string(cutUTF8(o.stdout.Bytes(), o.limit)),
+		Stderr:    string(cutUTF8(o.stderr.Bytes(), o.limit)),
+		Output:    string(cutUTF8(o.output.Bytes(), o.limit)),
+		Truncated: o.truncated,
+	}
+}
+
+// stream writes to its own buffer and to the interleaved output.
+type stream struct {
+	outputs *outputs
+	own     *bytes.Buffer
+}
+
+func (s stream) Write(p []byte) (int, error) {
+	s.outputs.mu.Lock()
+	defer s.outputs.mu.Unlock()
+
+	s.outputs.write(s.own, p)
+	s.outputs.write(&s.outputs.output, p)
+	return len(p), nil
+}
+
+// cutUTF8 cuts b to at most n bytes, without splitting a multi-byte character.
+func cutUTF8(b []byte, n int) []byte {
+	if len(b) > n {
+		b = b[:n]
+	}
+
+	for i := 0; i < utf8.UTFMax-1 && len(b) > 0; i++ {
+		r, size := utf8.DecodeLastRune(b)
+		if r != utf8.RuneError || size != 1 {
+			break
+		}
+		b = b[:len(b)-1]
+	}
+
+	return b
+}
+
+// newerVersion reports whether version a is newer than version b, comparing the
+// major, minor and patch numbers like rce does. On a tie, a nightly version is older.
+func newerVersion(a, b string) bool {
+	partsA := strings.FieldsFunc(a, isVersionSeparator)
+	partsB := strings.FieldsFunc(b, isVersionSeparator)
+
+	for i := 0; i < 3; i++ {
+		numberA, numberB := versionNumber(partsA, i), versionNumber(partsB, i)
+		if numberA != numberB {
+			return numberA > numberB
+		}
+	}
+
+	return len(partsB) > 3 && partsB[3] == "nightly" && !(len(partsA) > 3 && partsA[3] == "nightly")
+}
+
+func isVersionSeparator(r rune) bool {
+	return r == '.' || r == '-'
+}
+
+func versionNumber(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+
+	// rce treats a part that is not a number as 0.
+	number, _ := strconv.Atoi(parts[i])
+	return number
+}
@@ -0,0 +1,307 @@
+//go:build unix
+
+package local_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/local"
+	"github.com/teknologi-umum/pesto/sdk/go/manifest"
+	"github.com/teknologi-umum/pesto/sdk/go/pestotest"
+)
+
+var testPackages = []manifest.Manifest{
+	{
+		Language:           "Shell",
+		Version:            "1.0.0",
+		Extension:          "sh",
+		RunCommand:         []string{"sh", "{file}"},
+		Environment:        map[string]string{"GREETING": "hello"},
+		Aliases:            []string{"sh"},
+		ProcessLimit:       256,
+		AllowedEntrypoints: 1,
+	},
+	{
+		Language:           "Shell",
+		Version:            "1.2.0",
+		Extension:          "sh",
+		RunCommand:         []string{"sh", "{file}"},
+		Environment:        map[string]string{"GREETING": "hello from 1.2.0"},
+		Aliases:            []string{"sh"},
+		ProcessLimit:       256,
+		AllowedEntrypoints: 1,
+	},
+	{
+		Language:           "Compiled Shell",
+		Version:            "1.0.0",
+		Extension:          "sh",
+		Compiled:           true,
+		BuildCommand:       []string{"sh", "-c", `sh -n "$1" && cp "$1" code && chmod +x code`, "sh", "{file}"},
+		RunCommand:         []string{"./code"},
+		Environment:        map[string]string{},
+		Aliases:            []string{"csh"},
+		ShouldLimitMemory:  true,
+		MemoryLimit:        512 * 1024 * 1024,
+		ProcessLimit:       256,
+		AllowedEntrypoints: 1,
+	},
+}
+
+func TestExecutor_Execute(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   local.Config
+		request  pesto.CodeRequest
+		expected error
+		check    func(t *testing.T, response pesto.CodeResponse)
+	}{
+		{
+			name:    "Interpreted",
+			request: pesto.CodeRequest{Language: "Shell", Version: "1.0.0", Code: "echo out; echo err >&2; exit 3"},
+			check: func(t *testing.T, response pesto.CodeResponse) {
+				if response.Language != "Shell" || response.Version != "1.0.0" {
+					t.Errorf("unexpected runtime: %s %s", response.Language, response.Version)
+				}
+
+				if response.Runtime.Stdout != "out\n" || response.Runtime.Stderr != "err\n" {
+					t.Errorf("unexpected runtime output: %+v", response.Runtime)
+				}
+
+				// The streams are read concurrently, so their order on Output is not fixed.
+				if output := response.Runtime.Output; output != "out\nerr\n" && output != "err\nout\n" {
+					t.Errorf("expecting the output to contain both streams, got %q", response.Runtime.Output)
+				}
+
+				if response.Runtime.ExitCode != 3 {
+					t.Errorf("expecting exit code 3, got %d", response.Runtime.ExitCode)
+				}
+			},
+		},
+		{
+			name:    "LatestVersion",
+			request: pesto.CodeRequest{Language: "Shell", Version: pesto.VersionLatest, Code: "echo $GREETING"},
+			check: func(t *testing.T, response pesto.CodeResponse) {
+				if response.Version != "1.2.0" || response.Runtime.Stdout != "hello from 1.2.0\n" {
+					t.Errorf("expecting the latest version to run, got %s: %+v", response.Version, response.Runtime)
+				}
+			},
+		},
+		{
+			name:    "EnvironmentOverride",
+			config:  local.Config{Environment: map[string]string{"GREETING": "overridden"}},
+			request: pesto.CodeRequest{Language: "Shell", Version: "1.0.0", Code: "echo $GREETING"},
+			check: func(t *testing.T, response pesto.CodeResponse) {
+				if response.Runtime.Stdout != "overridden\n" {
+					t.Errorf("expecting the environment to be overridden, got %q", response.Runtime.Stdout)
+				}
+			},
+		},
+		{
+			name:    "Compiled",
+			request: pesto.CodeRequest{Language: "Compiled Shell", Version: "1.0.0", Code: "#!/bin/sh\necho compiled"},
+			check: func(t *testing.T, response pesto.CodeResponse) {
+				if response.Compile.ExitCode != 0 || response.Runtime.Stdout != "compiled\n" {
+					t.Errorf("unexpected response: %+v", response)
+				}
+			},
+		},
+		{
+			name:    "CompileError",
+			request: pesto.CodeRequest{Language: "Compiled Shell", Version: "1.0.0", Code: "if then"},
+			check: func(t *testing.T, response pesto.CodeResponse) {
+				if response.Compile.ExitCode == 0 || response.Compile.Stderr == "" {
+					t.Errorf("expecting a compile error, got %+v", response.Compile)
+				}
+
+				if response.Runtime != (pesto.Output{}) {
+					t.Errorf("expecting the code to not run, got %+v", response.Runtime)
+				}
+			},
+		},
+		{
+			name: "Files",
+			request: pesto.CodeRequest{
+				Language: "Shell",
+				Version:  "1.0.0",
+				Files: []pesto.File{
+					{Name: "main.sh", Code: ". ./lib/greet.sh\ngreet", Entrypoint: true},
+					{Name: "lib/greet.sh", Code: "greet() { echo hi; }"},
+				},
+			},
+			check: func(t *testing.T, response pesto.CodeResponse) {
+				if response.Runtime.Stdout != "hi\n" {
+					t.Errorf("expecting the files to be written, got %+v", response.Runtime)
+				}
+			},
+		},
+		{
+			name:    "RunTimeout",
+			request: pesto.CodeRequest{Language: "Shell", Version: "1.0.0", Code: "echo started; sleep 10", RunTimeout: 200 * time.Millisecond},
+			check: func(t *testing.T, response pesto.CodeResponse) {
+				if !response.Metadata.TimedOut || response.Runtime.ExitCode != 0 {
+					t.Errorf("expecting a timeout with exit code 0, got %+v", response)
+				}
+
+				if response.Runtime.Stdout != "started\n" {
+					t.Errorf("expecting the output before the timeout, got %q", response.Runtime.Stdout)
+				}
+
+				if response.Metadata.RunDuration > 5*time.Second {
+					t.Errorf("expecting the process group to be killed, took %s", response.Metadata.RunDuration)
+				}
+			},
+		},
+		{
+			name:    "OutputLimit",
+			config:  local.Config{MaxOutputBytes: 10},
+			request: pesto.CodeRequest{Language: "Shell", Version: "1.0.0", Code: "printf '%0100d' 0"},
+			check: func(t *testing.T, response pesto.CodeResponse) {
+				if len(response.Runtime.Stdout) != 10 || !response.Runtime.Truncated {
+					t.Errorf("expecting the output to be cut at 10 bytes, got %+v", response.Runtime)
+				}
+			},
+		},
+		{
+			name:    "WithoutLimits",
+			config:  local.Config{Prlimit: "pesto-prlimit-does-not-exist", WithoutLimits: true},
+			request: pesto.CodeRequest{Language: "Shell", Version: "1.0.0", Code: "echo ok"},
+			check: func(t *testing.T, response pesto.CodeResponse) {
+				if response.Runtime.Stdout != "ok\n" {
+					t.Errorf("unexpected runtime output: %+v", response.Runtime)
+				}
+			},
+		},
+		{
+			name: "TooManyEntrypoints",
+			request: pesto.CodeRequest{
+				Language: "Shell",
+				Version:  "1.0.0",
+				Files:    []pesto.File{{Name: "a.sh", Code: "echo a", Entrypoint: true}, {Name: "b.sh", Code: "echo b", Entrypoint: true}},
+			},
+			expected: errors.New("invalid request: Maximum allowed entrypoint exceeded of 1 entries"),
+		},
+		{
+			name: "EmptyFileName",
+			request: pesto.CodeRequest{
+				Language: "Shell",
+				Version:  "1.0.0",
+				Files:    []pesto.File{{Name: "main.sh", Code: ". ./lib.sh", Entrypoint: true}, {Code: "echo a"}},
+			},
+			expected: errors.New("missing parameters: File name cannot be empty"),
+		},
+		{
+			name: "InvalidFileName",
+			request: pesto.CodeRequest{
+				Language: "Shell",
+				Version:  "1.0.0",
+				Files:    []pesto.File{{Name: "../escape.sh", Code: "echo a", Entrypoint: true}},
+			},
+			expected: errors.New(`invalid file name "../escape.sh"`),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := test.config
+			config.Packages = testPackages
+
+			executor, err := local.NewExecutor(config)
+			if err != nil {
+				t.Fatalf("creating executor: %s", err.Error())
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			response, err := executor.Execute(ctx, test.request)
+			if test.expected != nil {
+				if err == nil || err.Error() != test.expected.Error() {
+					t.Fatalf("expecting an error of %v, instead got %v", test.expected, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			test.check(t, response)
+		})
+	}
+}
+
+// TestExecutor_SameErrors runs the same requests against the local executor
+// and the fake of Pesto's API, since the code that uses pesto.Executor
+// should not tell them apart.
+func TestExecutor_SameErrors(t *testing.T) {
+	server := pestotest.NewServer(
+		pestotest.WithToken("testing-token", 100),
+		pestotest.WithRuntimes(pesto.Runtime{Language: "Shell", Version: "1.0.0", Aliases: []string{"sh"}}),
+	)
+	defer server.Close()
+
+	client, err := pesto.NewClientWithConfig(pesto.Config{Token: "testing-token", BaseURL: server.BaseURL()})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	executor, err := local.NewExecutor(local.Config{Packages: testPackages})
+	if err != nil {
+		t.Fatalf("creating executor: %s", err.Error())
+	}
+
+	tests := []struct {
+		name     string
+		request  pesto.CodeRequest
+		expected error
+	}{
+		{name: "RuntimeNotFound", request: pesto.CodeRequest{Language: "Rust", Version: "1.64.0", Code: "fn main() {}"}, expected: pesto.ErrRuntimeNotFound},
+		{name: "VersionNotFound", request: pesto.CodeRequest{Language: "Shell", Version: "0.1.0", Code: "echo"}, expected: pesto.ErrRuntimeNotFound},
+		{name: "EmptyFileName", request: pesto.CodeRequest{Language: "Shell", Version: "1.0.0", Files: []pesto.File{{Code: "echo"}}}, expected: pesto.ErrMissingParameters},
+	}
+
+	for _, executor := range []pesto.Executor{client, executor} {
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				defer cancel()
+
+				_, err := executor.Execute(ctx, test.request)
+				if !errors.Is(err, test.expected) {
+					t.Errorf("expecting an error of %v from %T, instead got %v", test.expected, executor, err)
+				}
+			})
+		}
+	}
+}
+
+func TestExecutor_Canceled(t *testing.T) {
+	executor, err := local.NewExecutor(local.Config{Packages: testPackages})
+	if err != nil {
+		t.Fatalf("creating executor: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err = executor.Execute(ctx, pesto.CodeRequest{Language: "Shell", Version: "1.0.0", Code: "sleep 10"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expecting an error of context.DeadlineExceeded, instead got %v", err)
+	}
+}
+
+func TestNewExecutor(t *testing.T) {
+	_, err := local.NewExecutor(local.Config{})
+	if !errors.Is(err, local.ErrNoPackages) {
+		t.Errorf("expecting an error of ErrNoPackages, instead got %v", err)
+	}
+
+	_, err = local.NewExecutor(local.Config{Packages: testPackages, Prlimit: "pesto-prlimit-does-not-exist"})
+	if !errors.Is(err, local.ErrPrlimitNotFound) {
+		t.Errorf("expecting an error of ErrPrlimitNotFound, instead got %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package local
+
+import "os/exec"
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup only kills the command itself, the processes it spawns are left running.
+func killProcessGroup(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
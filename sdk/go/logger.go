@@ -0,0 +1,72 @@
+package pesto
+
+import "unicode/utf8"
+
+// maxLoggedBodyBytes caps the size of a body that is dumped with Config.LogBodies.
+const maxLoggedBodyBytes = 64 << 10
+
+// Logger is the structured logger that the client writes to. The arguments
+// are alternating keys and values. *slog.Logger satisfies this interface:
+//
+//	client, err := pesto.NewClientWithConfig(pesto.Config{
+//		Token:  token,
+//		Logger: slog.Default(),
+//	})
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, ...any) {}
+func (noopLogger) Info(string, ...any)  {}
+func (noopLogger) Warn(string, ...any)  {}
+func (noopLogger) Error(string, ...any) {}
+
+// isServerError reports whether err is the server's or the network's fault,
+// rather than the caller's.
+func isServerError(err error) bool {
+	switch ErrorClass(err) {
+	case "internal_server_error", "circuit_open", "transport", "other":
+		return true
+	}
+
+	return false
+}
+
+// redactToken keeps only the last 4 characters of the token, which is enough
+// to tell the tokens apart on the logs.
+func redactToken(token string) string {
+	if len(token) <= 8 {
+		return "****"
+	}
+
+	return "****" + token[len(token)-4:]
+}
+
+// loggedBody returns the body as a string to be logged, cut at maxLoggedBodyBytes.
+func loggedBody(body []byte) string {
+	if len(body) <= maxLoggedBodyBytes {
+		return string(body)
+	}
+
+	end := maxLoggedBodyBytes
+	for end > 0 && !utf8.RuneStart(body[end]) {
+		end--
+	}
+
+	return string(body[:end]) + "...(truncated)"
+}
+
+// logAttributer is implemented by request bodies that have attributes worth logging,
+// other than the code itself.
+type logAttributer interface {
+	logAttributes() []any
+}
+
+func (r codeRequestSimplified) logAttributes() []any {
+	return []any{"language", r.Language, "version", r.Version}
+}
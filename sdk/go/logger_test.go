@@ -0,0 +1,219 @@
+package pesto_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+type logEntry struct {
+	level string
+	msg   string
+	attrs map[string]string
+}
+
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (l *recordingLogger) record(level string, msg string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	attrs := make(map[string]string)
+	for i := 0; i+1 < len(args); i += 2 {
+		attrs[fmt.Sprint(args[i])] = fmt.Sprint(args[i+1])
+	}
+
+	l.entries = append(l.entries, logEntry{level: level, msg: msg, attrs: attrs})
+}
+
+func (l *recordingLogger) Debug(msg string, args ...any) { l.record("DEBUG", msg, args...) }
+func (l *recordingLogger) Info(msg string, args ...any)  { l.record("INFO", msg, args...) }
+func (l *recordingLogger) Warn(msg string, args ...any)  { l.record("WARN", msg, args...) }
+func (l *recordingLogger) Error(msg string, args ...any) { l.record("ERROR", msg, args...) }
+
+func (l *recordingLogger) find(msg string) (logEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, entry := range l.entries {
+		if entry.msg == msg {
+			return entry, true
+		}
+	}
+
+	return logEntry{}, false
+}
+
+func (l *recordingLogger) contains(s string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, entry := range l.entries {
+		if strings.Contains(entry.msg, s) {
+			return true
+		}
+
+		for _, value := range entry.attrs {
+			if strings.Contains(value, s) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func TestClient_Logger(t *testing.T) {
+	const code = "print('super secret code')"
+
+	t.Run("Success", func(t *testing.T) {
+		logger := &recordingLogger{}
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: happyMockServerURL,
+			Logger:  logger,
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err = client.Execute(ctx, pesto.CodeRequest{
+			Language: pesto.LanguagePython,
+			Version:  pesto.VersionPython,
+			Code:     code,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		started, ok := logger.find("pesto: request started")
+		if !ok || started.level != "DEBUG" || started.attrs["endpoint"] != "/api/execute" {
+			t.Errorf("unexpected request started log: %+v", started)
+		}
+
+		finished, ok := logger.find("pesto: request finished")
+		if !ok || finished.level != "INFO" {
+			t.Fatalf("unexpected request finished log: %+v", finished)
+		}
+
+		if finished.attrs["status"] != "200" || finished.attrs["language"] != "Python" || finished.attrs["latency"] == "" {
+			t.Errorf("unexpected request finished attributes: %+v", finished.attrs)
+		}
+
+		if logger.contains(token) {
+			t.Errorf("expecting the token to be redacted")
+		}
+
+		if logger.contains(code) {
+			t.Errorf("expecting the code to not be logged")
+		}
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		logger := &recordingLogger{}
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: happyMockServerURL,
+			Logger:  logger,
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, _ = client.Execute(ctx, pesto.CodeRequest{Language: "Rust", Version: "1.64.0", Code: code})
+
+		failed, ok := logger.find("pesto: request failed")
+		if !ok || failed.level != "WARN" {
+			t.Fatalf("unexpected request failed log: %+v", failed)
+		}
+
+		if failed.attrs["error_class"] != "runtime_not_found" || failed.attrs["status"] != "400" {
+			t.Errorf("unexpected request failed attributes: %+v", failed.attrs)
+		}
+	})
+
+	t.Run("LogBodies", func(t *testing.T) {
+		logger := &recordingLogger{}
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:     token,
+			BaseURL:   happyMockServerURL,
+			Logger:    logger,
+			LogBodies: true,
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err = client.Execute(ctx, pesto.CodeRequest{
+			Language: pesto.LanguagePython,
+			Version:  pesto.VersionPython,
+			Code:     code,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		request, ok := logger.find("pesto: request body")
+		if !ok || request.level != "DEBUG" || !strings.Contains(request.attrs["body"], "super secret code") {
+			t.Errorf("expecting the request body to be dumped, got %+v", request)
+		}
+
+		response, ok := logger.find("pesto: response body")
+		if !ok || !strings.Contains(response.attrs["body"], "Hello World") {
+			t.Errorf("expecting the response body to be dumped, got %+v", response)
+		}
+
+		if logger.contains(token) {
+			t.Errorf("expecting the token to be redacted")
+		}
+	})
+
+	t.Run("Failover", func(t *testing.T) {
+		failing, _ := InstanceMockServer(http.StatusInternalServerError)
+		defer failing.Close()
+		healthy, _ := InstanceMockServer(http.StatusOK)
+		defer healthy.Close()
+
+		logger := &recordingLogger{}
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:    token,
+			BaseURLs: []*url.URL{mustParseURL(t, failing.URL), mustParseURL(t, healthy.URL)},
+			Logger:   logger,
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err = client.Ping(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		ejected, ok := logger.find("pesto: instance responded with an internal server error, ejecting it")
+		if !ok || ejected.level != "WARN" || ejected.attrs["retrying"] != "true" {
+			t.Errorf("unexpected ejection log: %+v", ejected)
+		}
+	})
+}
@@ -0,0 +1,288 @@
+// Package manifest parses and validates the package definitions of Pesto's rce
+// service, the rce/packages/<language>/config.toml files, with the same rules
+// as the Runtime of the rce service.
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/internal/toml"
+)
+
+// ErrInvalidManifest indicates the manifest does not satisfy the rules of the rce service,
+// which would refuse to start with it.
+var ErrInvalidManifest = errors.New("invalid manifest")
+
+// Manifest is a runtime definition, read from the config.toml of an rce package.
+type Manifest struct {
+	Language  string
+	Version   string
+	Extension string
+	Compiled  bool
+	// BuildCommand and RunCommand are the arguments of the commands, where "{file}"
+	// is replaced with the entrypoints.
+	BuildCommand []string
+	RunCommand   []string
+	// Environment holds the variables that are set on top of PATH.
+	Environment map[string]string
+	// TestFile is the file of the package directory that is used to test the runtime.
+	TestFile          string
+	Aliases           []string
+	ShouldLimitMemory bool
+	// MemoryLimit is in bytes, while config.toml declares it in MiB.
+	MemoryLimit  int64
+	ProcessLimit int64
+	// AllowedEntrypoints is the maximum amount of entrypoints of a request,
+	// or -1 for no limit.
+	AllowedEntrypoints int64
+}
+
+// Load reads and validates the config.toml of every package directory under dir,
+// such as the rce/packages directory of the repository. The manifests are sorted
+// by the name of their directory.
+func Load(dir string) ([]Manifest, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "config.toml"))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(paths)
+
+	manifests := make([]Manifest, 0, len(paths))
+	for _, path := range paths {
+		manifest, err := ParseFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		manifests = append(manifests, manifest)
+	}
+
+	return manifests, nil
+}
+
+// ParseFile reads, parses and validates a config.toml.
+func ParseFile(path string) (Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	manifest, err := Parse(data)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return manifest, nil
+}
+
+// Parse parses and validates the content of a config.toml.
+func Parse(data []byte) (Manifest, error) {
+	document, err := toml.Parse(data)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	d := decoder{document: document}
+	manifest := Manifest{
+		Language:           d.string("language"),
+		Version:            d.string("version"),
+		Extension:          d.string("extension"),
+		Compiled:           d.bool("compiled"),
+		BuildCommand:       d.strings("build_command"),
+		RunCommand:         d.strings("run_command"),
+		Environment:        make(map[string]string),
+		TestFile:           d.optionalString("test_file"),
+		Aliases:            d.strings("aliases"),
+		ShouldLimitMemory:  d.bool("should_limit_memory"),
+		MemoryLimit:        d.int("memory_limit") * 1024 * 1024,
+		ProcessLimit:       d.int("process_limit"),
+		AllowedEntrypoints: d.int("allowed_entrypoints"),
+	}
+
+	for _, variable := range d.strings("environment") {
+		key, value, ok := strings.Cut(variable, "=")
+		if !ok && d.err == nil {
+			d.err = fmt.Errorf("%w: environment variable %q is not KEY=VALUE", ErrInvalidManifest, variable)
+		}
+		manifest.Environment[key] = value
+	}
+
+	if d.err != nil {
+		return Manifest{}, d.err
+	}
+
+	err = manifest.Validate()
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	return manifest, nil
+}
+
+// Validate checks the manifest with the same rules as the Runtime constructor
+// of the rce service. It returns an error wrapping ErrInvalidManifest.
+func (m Manifest) Validate() error {
+	var problems []string
+	if m.Language == "" {
+		problems = append(problems, "language is empty")
+	}
+
+	if m.Version == "" {
+		problems = append(problems, "version is empty")
+	}
+
+	if m.Extension == "" {
+		problems = append(problems, "extension is empty")
+	}
+
+	if len(m.RunCommand) == 0 {
+		problems = append(problems, "run_command is empty")
+	}
+
+	if len(m.Aliases) == 0 {
+		problems = append(problems, "aliases is empty")
+	}
+
+	if m.AllowedEntrypoints == 0 {
+		problems = append(problems, "allowed_entrypoints is 0")
+	}
+
+	if m.Compiled && len(m.BuildCommand) == 0 {
+		problems = append(problems, "build_command is empty yet compiled is true")
+	}
+
+	if m.ShouldLimitMemory && m.MemoryLimit <= 0 {
+		problems = append(problems, "memory_limit is 0 or less")
+	}
+
+	if m.ProcessLimit <= 0 {
+		problems = append(problems, "process_limit is 0 or less")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidManifest, strings.Join(problems, ", "))
+	}
+
+	return nil
+}
+
+// Runtime returns the runtime of the manifest, as listed by Client.ListRuntimes
+// when the server provides the extended fields.
+func (m Manifest) Runtime() pesto.Runtime {
+	shouldLimitMemory := m.ShouldLimitMemory
+	return pesto.Runtime{
+		Language:           m.Language,
+		Version:            m.Version,
+		Aliases:            m.Aliases,
+		Compiled:           m.Compiled,
+		Extension:          m.Extension,
+		ShouldLimitMemory:  &shouldLimitMemory,
+		MemoryLimit:        m.MemoryLimit,
+		ProcessLimit:       m.ProcessLimit,
+		AllowedEntrypoints: m.AllowedEntrypoints,
+	}
+}
+
+// decoder reads the typed values of a TOML document, keeping the first error.
+type decoder struct {
+	document map[string]any
+	err      error
+}
+
+func (d *decoder) value(key string) (any, bool) {
+	value, ok := d.document[key]
+	if !ok && d.err == nil {
+		d.err = fmt.Errorf("%w: missing %s", ErrInvalidManifest, key)
+	}
+
+	return value, ok
+}
+
+func (d *decoder) typeError(key string, expected string, value any) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w: expecting %s to be %s, got %T", ErrInvalidManifest, key, expected, value)
+	}
+}
+
+func (d *decoder) string(key string) string {
+	value, ok := d.value(key)
+	if !ok {
+		return ""
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		d.typeError(key, "a string", value)
+	}
+
+	return s
+}
+
+func (d *decoder) optionalString(key string) string {
+	if _, ok := d.document[key]; !ok {
+		return ""
+	}
+
+	return d.string(key)
+}
+
+func (d *decoder) bool(key string) bool {
+	value, ok := d.value(key)
+	if !ok {
+		return false
+	}
+
+	b, ok := value.(bool)
+	if !ok {
+		d.typeError(key, "a boolean", value)
+	}
+
+	return b
+}
+
+func (d *decoder) int(key string) int64 {
+	value, ok := d.value(key)
+	if !ok {
+		return 0
+	}
+
+	i, ok := value.(int64)
+	if !ok {
+		d.typeError(key, "an integer", value)
+	}
+
+	return i
+}
+
+func (d *decoder) strings(key string) []string {
+	value, ok := d.value(key)
+	if !ok {
+		return nil
+	}
+
+	items, ok := value.([]any)
+	if !ok {
+		d.typeError(key, "an array", value)
+		return nil
+	}
+
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			d.typeError(key, "an array of strings", item)
+			return nil
+		}
+
+		values = append(values, s)
+	}
+
+	return values
+}
@@ -0,0 +1,205 @@
+package manifest_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/teknologi-umum/pesto/sdk/go/manifest"
+)
+
+const pythonManifest = `language = "Python"
+version = "3.10.2"
+extension = "py"
+compiled = false
+build_command = []
+run_command = ["/opt/python/3.10.2/bin/python3", "{file}"]
+aliases = ["py", "python", "python3"]
+environment = ["PATH=/opt/python/3.10.2/bin:$PATH"]
+should_limit_memory = true
+memory_limit = 256
+process_limit = 256
+allowed_entrypoints = 1
+`
+
+func TestLoad(t *testing.T) {
+	manifests, err := manifest.Load("../../../rce/packages")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(manifests) == 0 {
+		t.Skip("rce packages are not available")
+	}
+
+	var python *manifest.Manifest
+	for i := range manifests {
+		if manifests[i].Language == "Python" {
+			python = &manifests[i]
+		}
+	}
+
+	if python == nil {
+		t.Fatal("expecting the Python manifest to be loaded")
+	}
+
+	if python.Extension != "py" || python.MemoryLimit != 256*1024*1024 || python.AllowedEntrypoints != 1 {
+		t.Errorf("unexpected Python manifest: %+v", python)
+	}
+
+	if !strings.HasPrefix(python.Environment["PATH"], "/opt/python") {
+		t.Errorf("expecting the environment to be parsed, got %v", python.Environment)
+	}
+
+	if len(python.RunCommand) != 2 || python.RunCommand[1] != "{file}" {
+		t.Errorf("unexpected run command: %v", python.RunCommand)
+	}
+}
+
+func TestParse(t *testing.T) {
+	got, err := manifest.Parse([]byte(pythonManifest))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if got.Language != "Python" || got.Version != "3.10.2" || got.Extension != "py" || got.Compiled {
+		t.Errorf("unexpected manifest: %+v", got)
+	}
+
+	if got.MemoryLimit != 256*1024*1024 || got.ProcessLimit != 256 || !got.ShouldLimitMemory {
+		t.Errorf("unexpected limits: %+v", got)
+	}
+
+	if got.Environment["PATH"] != "/opt/python/3.10.2/bin:$PATH" {
+		t.Errorf("expecting the environment to be parsed, got %v", got.Environment)
+	}
+
+	if got.TestFile != "" {
+		t.Errorf("expecting test_file to be optional, got %q", got.TestFile)
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Syntax", input: "language = ", expected: "toml: line 1"},
+		{name: "Missing", input: `language = "Go"`, expected: "missing version"},
+		{name: "Type", input: "language = 1", expected: "expecting language to be a string, got int64"},
+		{
+			name:     "Environment",
+			input:    strings.Replace(pythonManifest, `"PATH=/opt/python/3.10.2/bin:$PATH"`, `"PATH"`, 1),
+			expected: `environment variable "PATH" is not KEY=VALUE`,
+		},
+		{
+			name:     "Invalid",
+			input:    strings.Replace(pythonManifest, "process_limit = 256", "process_limit = 0", 1),
+			expected: "process_limit is 0 or less",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := manifest.Parse([]byte(test.input))
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("expecting an error containing %q, instead got %v", test.expected, err)
+			}
+
+			if test.name != "Syntax" && !errors.Is(err, manifest.ErrInvalidManifest) {
+				t.Errorf("expecting an error of ErrInvalidManifest, instead got %v", err)
+			}
+		})
+	}
+}
+
+func TestManifest_Validate(t *testing.T) {
+	valid := func() manifest.Manifest {
+		return manifest.Manifest{
+			Language:           "C",
+			Version:            "9.3.0",
+			Extension:          "c",
+			Compiled:           true,
+			BuildCommand:       []string{"gcc", "-o", "code", "{file}"},
+			RunCommand:         []string{"./code"},
+			Environment:        map[string]string{},
+			Aliases:            []string{"gcc"},
+			ShouldLimitMemory:  true,
+			MemoryLimit:        128 * 1024 * 1024,
+			ProcessLimit:       128,
+			AllowedEntrypoints: -1,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(m *manifest.Manifest)
+		expected string
+	}{
+		{name: "Language", modify: func(m *manifest.Manifest) { m.Language = "" }, expected: "language is empty"},
+		{name: "Version", modify: func(m *manifest.Manifest) { m.Version = "" }, expected: "version is empty"},
+		{name: "Extension", modify: func(m *manifest.Manifest) { m.Extension = "" }, expected: "extension is empty"},
+		{name: "RunCommand", modify: func(m *manifest.Manifest) { m.RunCommand = nil }, expected: "run_command is empty"},
+		{name: "Aliases", modify: func(m *manifest.Manifest) { m.Aliases = []string{} }, expected: "aliases is empty"},
+		{name: "AllowedEntrypoints", modify: func(m *manifest.Manifest) { m.AllowedEntrypoints = 0 }, expected: "allowed_entrypoints is 0"},
+		{name: "BuildCommand", modify: func(m *manifest.Manifest) { m.BuildCommand = nil }, expected: "build_command is empty yet compiled is true"},
+		{name: "MemoryLimit", modify: func(m *manifest.Manifest) { m.MemoryLimit = 0 }, expected: "memory_limit is 0 or less"},
+		{name: "ProcessLimit", modify: func(m *manifest.Manifest) { m.ProcessLimit = -1 }, expected: "process_limit is 0 or less"},
+		{
+			name:     "Multiple",
+			modify:   func(m *manifest.Manifest) { m.Language, m.ProcessLimit = "", 0 },
+			expected: "language is empty, process_limit is 0 or less",
+		},
+	}
+
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := valid()
+			test.modify(&m)
+
+			err := m.Validate()
+			if !errors.Is(err, manifest.ErrInvalidManifest) {
+				t.Fatalf("expecting an error of ErrInvalidManifest, instead got %v", err)
+			}
+
+			if !strings.HasSuffix(err.Error(), test.expected) {
+				t.Errorf("expecting an error ending with %q, instead got %v", test.expected, err)
+			}
+		})
+	}
+
+	t.Run("MemoryLimitNotApplied", func(t *testing.T) {
+		m := valid()
+		m.ShouldLimitMemory = false
+		m.MemoryLimit = 0
+
+		if err := m.Validate(); err != nil {
+			t.Errorf("expecting the memory limit to be ignored, instead got %v", err)
+		}
+	})
+}
+
+func TestManifest_Runtime(t *testing.T) {
+	m, err := manifest.Parse([]byte(pythonManifest))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	runtime := m.Runtime()
+	if runtime.Language != "Python" || runtime.Version != "3.10.2" || runtime.Extension != "py" || len(runtime.Aliases) != 3 {
+		t.Errorf("unexpected runtime: %+v", runtime)
+	}
+
+	if runtime.ShouldLimitMemory == nil || !*runtime.ShouldLimitMemory {
+		t.Errorf("expecting ShouldLimitMemory to be true, got %v", runtime.ShouldLimitMemory)
+	}
+
+	if runtime.MemoryLimit != 256*1024*1024 || runtime.ProcessLimit != 256 || runtime.AllowedEntrypoints != 1 {
+		t.Errorf("unexpected limits: %+v", runtime)
+	}
+}
@@ -0,0 +1,188 @@
+package pesto
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Headers that identify the server and request that handled the call.
+//
+// Pesto's API does not send these headers yet. See Metadata.
+const (
+	HeaderRequestID      = "X-Request-Id"
+	HeaderPestoRequestID = "X-Pesto-Request-Id"
+	HeaderServerID       = "X-Pesto-Server-Id"
+)
+
+// Metadata contains information about how the code was executed,
+// and how long the HTTP request took from the client's point of view.
+//
+// Every field that is reported by the server is left empty if the server
+// does not send it. Pesto's API does not send the "metadata" object of the
+// response body nor the identifying headers yet, so against it only Latency
+// and QueueWait are set. The other fields are filled when the requests go
+// through a gateway that adds them, or by the local Executor, which sets
+// CompileDuration, RunDuration and TimedOut.
+type Metadata struct {
+	// CompileDuration is how long the compile step took on the server.
+	CompileDuration time.Duration
+	// RunDuration is how long the run step took on the server.
+	RunDuration time.Duration
+	// PeakMemory is the peak memory usage of the process, in bytes.
+	PeakMemory int64
+	// TimedOut is true if the process was killed for exceeding the timeout.
+	TimedOut bool
+	// MemoryExceeded is true if the process was killed for exceeding the memory limit.
+	MemoryExceeded bool
+	// ServerID identifies the server instance that handled the request.
+	ServerID string
+	// RequestID identifies the request on the server, useful when reporting an issue.
+	RequestID string
+	// Latency is measured on the client.
+	Latency Latency
+	// QueueWait is the time the execution spent in the Config.Scheduler queue.
+	QueueWait time.Duration
+}
+
+// Latency breaks down the time spent on the HTTP request, so network time can be
+// told apart from execution time. DNS, Connect and TLSHandshake are zero when
+// an idle connection was reused.
+type Latency struct {
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	// TimeToFirstByte is measured from the moment the request was written
+	// until the first byte of the response arrived, which includes the execution time.
+	TimeToFirstByte time.Duration
+	// Total is measured from the start of the call until the response body was read.
+	Total time.Duration
+	// ConnectionReused is true if an idle connection was reused for the request.
+	ConnectionReused bool
+}
+
+// metadataBody is the optional "metadata" object on the execute response body.
+type metadataBody struct {
+	CompileTime    int64  `json:"compileTime"`
+	RunTime        int64  `json:"runTime"`
+	PeakMemory     int64  `json:"peakMemory"`
+	TimedOut       bool   `json:"timedOut"`
+	MemoryExceeded bool   `json:"memoryExceeded"`
+	ServerID       string `json:"serverId"`
+	RequestID      string `json:"requestId"`
+}
+
+// newMetadata merges the metadata from the response body and headers.
+// The response headers take precedence over the body for the identifiers.
+func newMetadata(body *metadataBody, header http.Header) Metadata {
+	var metadata Metadata
+	if body != nil {
+		metadata = Metadata{
+			CompileDuration: time.Duration(body.CompileTime) * time.Millisecond,
+			RunDuration:     time.Duration(body.RunTime) * time.Millisecond,
+			PeakMemory:      body.PeakMemory,
+			TimedOut:        body.TimedOut,
+			MemoryExceeded:  body.MemoryExceeded,
+			ServerID:        body.ServerID,
+			RequestID:       body.RequestID,
+		}
+	}
+
+	for _, key := range []string{HeaderPestoRequestID, HeaderRequestID} {
+		if value := header.Get(key); value != "" {
+			metadata.RequestID = value
+			break
+		}
+	}
+
+	if value := header.Get(HeaderServerID); value != "" {
+		metadata.ServerID = value
+	}
+
+	return metadata
+}
+
+// latencyTracer measures the latency of a request through httptrace.
+// If the request fails over to another instance, the latest attempt is measured.
+type latencyTracer struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	latency      Latency
+}
+
+func newLatencyTracer() *latencyTracer {
+	return &latencyTracer{start: time.Now()}
+}
+
+// withContext returns a copy of ctx that reports the request events to the tracer.
+func (l *latencyTracer) withContext(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			l.mu.Lock()
+			l.dnsStart = time.Now()
+			l.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			l.mu.Lock()
+			l.latency.DNS = time.Since(l.dnsStart)
+			l.mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			l.mu.Lock()
+			l.connectStart = time.Now()
+			l.mu.Unlock()
+		},
+		ConnectDone: func(string, string, error) {
+			l.mu.Lock()
+			l.latency.Connect = time.Since(l.connectStart)
+			l.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			l.mu.Lock()
+			l.tlsStart = time.Now()
+			l.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			l.mu.Lock()
+			l.latency.TLSHandshake = time.Since(l.tlsStart)
+			l.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			l.mu.Lock()
+			l.latency.ConnectionReused = info.Reused
+			if info.Reused {
+				l.latency.DNS = 0
+				l.latency.Connect = 0
+				l.latency.TLSHandshake = 0
+			}
+			l.mu.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			l.mu.Lock()
+			l.wroteRequest = time.Now()
+			l.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			l.mu.Lock()
+			l.latency.TimeToFirstByte = time.Since(l.wroteRequest)
+			l.mu.Unlock()
+		},
+	})
+}
+
+// finish returns the measured latency, with Total set to the time since the tracer was created.
+func (l *latencyTracer) finish() Latency {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	latency := l.latency
+	latency.Total = time.Since(l.start)
+	return latency
+}
@@ -0,0 +1,153 @@
+package pesto_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+func TestCodeResponse_Metadata(t *testing.T) {
+	t.Run("NoMetadata", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: happyMockServerURL,
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		response, err := client.Execute(ctx, pesto.CodeRequest{
+			Language: pesto.LanguagePython,
+			Version:  pesto.VersionPython,
+			Code:     "print('Hello World')",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if response.Metadata.RunDuration != 0 || response.Metadata.RequestID != "" || response.Metadata.ServerID != "" {
+			t.Errorf("expecting server metadata to be empty, got %+v", response.Metadata)
+		}
+
+		if response.Metadata.Latency.Total <= 0 {
+			t.Errorf("expecting total latency to be measured, got %s", response.Metadata.Latency.Total)
+		}
+
+		if response.Metadata.Latency.TimeToFirstByte <= 0 || response.Metadata.Latency.TimeToFirstByte > response.Metadata.Latency.Total {
+			t.Errorf("expecting time to first byte to be within the total latency, got %+v", response.Metadata.Latency)
+		}
+	})
+
+	t.Run("BodyAndHeaders", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(pesto.HeaderRequestID, "request-123")
+			w.Header().Set(pesto.HeaderServerID, "rce-2")
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{
+				"language": "Python",
+				"version": "3.10.2",
+				"compile": {"stdout": "", "stderr": "", "output": "", "exitCode": 0},
+				"runtime": {"stdout": "", "stderr": "Killed", "output": "Killed", "exitCode": 137},
+				"metadata": {
+					"compileTime": 120,
+					"runTime": 2500,
+					"peakMemory": 134217728,
+					"timedOut": false,
+					"memoryExceeded": true,
+					"serverId": "rce-1",
+					"requestId": "body-request"
+				}
+			}`))
+		}))
+		defer server.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: mustParseURL(t, server.URL),
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		response, err := client.Execute(ctx, pesto.CodeRequest{
+			Language: pesto.LanguagePython,
+			Version:  pesto.VersionPython,
+			Code:     "x = [0] * (1 << 40)",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if response.Runtime.ExitCode != 137 {
+			t.Errorf("expecting runtime exit code to be 137, got %d", response.Runtime.ExitCode)
+		}
+
+		metadata := response.Metadata
+		if metadata.CompileDuration != time.Millisecond*120 {
+			t.Errorf("expecting compile duration to be 120ms, got %s", metadata.CompileDuration)
+		}
+
+		if metadata.RunDuration != time.Millisecond*2500 {
+			t.Errorf("expecting run duration to be 2.5s, got %s", metadata.RunDuration)
+		}
+
+		if metadata.PeakMemory != 134217728 {
+			t.Errorf("expecting peak memory to be 134217728, got %d", metadata.PeakMemory)
+		}
+
+		if metadata.TimedOut || !metadata.MemoryExceeded {
+			t.Errorf("expecting the process to be killed for memory, got %+v", metadata)
+		}
+
+		if metadata.RequestID != "request-123" {
+			t.Errorf("expecting request id from the header, got %s", metadata.RequestID)
+		}
+
+		if metadata.ServerID != "rce-2" {
+			t.Errorf("expecting server id from the header, got %s", metadata.ServerID)
+		}
+	})
+
+	t.Run("ConnectionReused", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: happyMockServerURL,
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		var response pesto.CodeResponse
+		for i := 0; i < 2; i++ {
+			response, err = client.Execute(ctx, pesto.CodeRequest{
+				Language: pesto.LanguagePython,
+				Version:  pesto.VersionPython,
+				Code:     "print('Hello World')",
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+		}
+
+		if !response.Metadata.Latency.ConnectionReused {
+			t.Errorf("expecting the second request to reuse the connection")
+		}
+
+		if response.Metadata.Latency.Connect != 0 {
+			t.Errorf("expecting connect latency to be zero on a reused connection, got %s", response.Metadata.Latency.Connect)
+		}
+	})
+}
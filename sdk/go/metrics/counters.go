@@ -0,0 +1,338 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds of the latency histogram, in seconds.
+// Executions are dominated by the compile and run time, hence the long tail.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Counters is a Collector that keeps plain counters in memory. It serves them
+// in the Prometheus text exposition format through WritePrometheus and ServeHTTP.
+type Counters struct {
+	buckets []float64
+
+	mu              sync.Mutex
+	requests        map[Labels]int64
+	errors          map[Labels]int64
+	compileFailures map[Labels]int64
+	runtimeFailures map[Labels]int64
+	inFlight        map[Labels]int64
+	latency         map[Labels]*histogram
+	queueDepth      map[string]int64
+	queueWait       map[string]*histogram
+}
+
+type histogram struct {
+	counts []int64
+	sum    float64
+	count  int64
+}
+
+// NewCounters creates an empty Counters. The latency histogram uses the given
+// bucket upper bounds in seconds, or DefaultBuckets if none are given.
+func NewCounters(buckets ...float64) *Counters {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &Counters{
+		buckets:         buckets,
+		requests:        make(map[Labels]int64),
+		errors:          make(map[Labels]int64),
+		compileFailures: make(map[Labels]int64),
+		runtimeFailures: make(map[Labels]int64),
+		inFlight:        make(map[Labels]int64),
+		latency:         make(map[Labels]*histogram),
+		queueDepth:      make(map[string]int64),
+		queueWait:       make(map[string]*histogram),
+	}
+}
+
+// IncRequests implements Collector.
+func (c *Counters) IncRequests(labels Labels) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests[labels]++
+}
+
+// IncErrors implements Collector.
+func (c *Counters) IncErrors(labels Labels) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errors[labels]++
+}
+
+// ObserveLatency implements Collector.
+func (c *Counters) ObserveLatency(labels Labels, latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, ok := c.latency[labels]
+	if !ok {
+		h = &histogram{counts: make([]int64, len(c.buckets))}
+		c.latency[labels] = h
+	}
+
+	h.observe(c.buckets, latency)
+}
+
+func (h *histogram) observe(buckets []float64, d time.Duration) {
+	seconds := d.Seconds()
+	for i, bound := range buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// SetQueueDepth records the amount of queued executions of the key.
+// Its signature matches pesto.SchedulerConfig.OnQueueDepth:
+//
+//	Scheduler: &pesto.SchedulerConfig{
+//		OnQueueDepth: counters.SetQueueDepth,
+//		OnDequeue:    counters.ObserveQueueWait,
+//	}
+//
+// Note that every key becomes a label value, so the keys should be bounded,
+// such as tenants rather than users.
+func (c *Counters) SetQueueDepth(key string, depth int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queueDepth[key] = int64(depth)
+}
+
+// ObserveQueueWait records the time an execution of the key spent in the queue.
+// Its signature matches pesto.SchedulerConfig.OnDequeue, see SetQueueDepth.
+func (c *Counters) ObserveQueueWait(key string, wait time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, ok := c.queueWait[key]
+	if !ok {
+		h = &histogram{counts: make([]int64, len(c.buckets))}
+		c.queueWait[key] = h
+	}
+
+	h.observe(c.buckets, wait)
+}
+
+// IncCompileFailures implements Collector.
+func (c *Counters) IncCompileFailures(labels Labels) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.compileFailures[labels]++
+}
+
+// IncRuntimeFailures implements Collector.
+func (c *Counters) IncRuntimeFailures(labels Labels) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.runtimeFailures[labels]++
+}
+
+// AddInFlight implements Collector.
+func (c *Counters) AddInFlight(labels Labels, delta int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight[labels] += delta
+}
+
+// Requests returns the amount of requests with the given labels.
+func (c *Counters) Requests(labels Labels) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requests[labels]
+}
+
+// Errors returns the amount of errors with the given labels, including the Error label.
+func (c *Counters) Errors(labels Labels) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.errors[labels]
+}
+
+// CompileFailures returns the amount of compile failures with the given labels.
+func (c *Counters) CompileFailures(labels Labels) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.compileFailures[labels]
+}
+
+// RuntimeFailures returns the amount of runtime failures with the given labels.
+func (c *Counters) RuntimeFailures(labels Labels) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.runtimeFailures[labels]
+}
+
+// InFlight returns the amount of executions with the given labels that are in flight.
+func (c *Counters) InFlight(labels Labels) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.inFlight[labels]
+}
+
+// LatencyCount returns the amount of latencies observed with the given labels.
+func (c *Counters) LatencyCount(labels Labels) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, ok := c.latency[labels]
+	if !ok {
+		return 0
+	}
+
+	return h.count
+}
+
+// QueueDepth returns the amount of queued executions of the key.
+func (c *Counters) QueueDepth(key string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.queueDepth[key]
+}
+
+// QueueWaitCount returns the amount of queue waits observed for the key.
+func (c *Counters) QueueWaitCount(key string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, ok := c.queueWait[key]
+	if !ok {
+		return 0
+	}
+
+	return h.count
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (c *Counters) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = c.WritePrometheus(w)
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (c *Counters) WritePrometheus(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+
+	writeCounters(bw, "pesto_requests_total", "counter", "Total number of calls to Pesto's API.", c.requests, false)
+	writeCounters(bw, "pesto_errors_total", "counter", "Total number of calls to Pesto's API that returned an error.", c.errors, true)
+	writeCounters(bw, "pesto_compile_failures_total", "counter", "Total number of executions that failed to compile.", c.compileFailures, false)
+	writeCounters(bw, "pesto_runtime_failures_total", "counter", "Total number of executions that exited with a non-zero exit code, or were killed for exceeding a limit.", c.runtimeFailures, false)
+	writeCounters(bw, "pesto_executions_in_flight", "gauge", "Number of executions that are in flight.", c.inFlight, false)
+
+	const name = "pesto_request_duration_seconds"
+	fmt.Fprintf(bw, "# HELP %s Latency of the calls to Pesto's API.\n", name)
+	fmt.Fprintf(bw, "# TYPE %s histogram\n", name)
+	for _, labels := range sortedLabels(c.latency) {
+		c.writeHistogram(bw, name, formatLabels(labels, false), c.latency[labels])
+	}
+
+	if len(c.queueDepth) > 0 || len(c.queueWait) > 0 {
+		fmt.Fprintf(bw, "# HELP pesto_queue_depth Number of executions waiting in the scheduler queue.\n")
+		fmt.Fprintf(bw, "# TYPE pesto_queue_depth gauge\n")
+		for _, key := range sortedKeys(c.queueDepth) {
+			fmt.Fprintf(bw, "pesto_queue_depth{key=\"%s\"} %d\n", escapeLabelValue(key), c.queueDepth[key])
+		}
+
+		const name = "pesto_queue_wait_seconds"
+		fmt.Fprintf(bw, "# HELP %s Time the executions spent in the scheduler queue.\n", name)
+		fmt.Fprintf(bw, "# TYPE %s histogram\n", name)
+		for _, key := range sortedKeys(c.queueWait) {
+			c.writeHistogram(bw, name, `key="`+escapeLabelValue(key)+`"`, c.queueWait[key])
+		}
+	}
+
+	return bw.Flush()
+}
+
+func (c *Counters) writeHistogram(w io.Writer, name string, labels string, h *histogram) {
+	for i, bound := range c.buckets {
+		fmt.Fprintf(w, "%s_bucket{%s,le=%q} %d\n", name, labels, formatFloat(bound), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.count)
+}
+
+func writeCounters(w io.Writer, name string, kind string, help string, values map[Labels]int64, withError bool) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+	for _, labels := range sortedLabels(values) {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, formatLabels(labels, withError), values[labels])
+	}
+}
+
+// sortedLabels returns the keys of m in a stable order, so the output is deterministic.
+func sortedLabels[V any](m map[Labels]V) []Labels {
+	labels := make([]Labels, 0, len(m))
+	for l := range m {
+		labels = append(labels, l)
+	}
+
+	sort.Slice(labels, func(i, j int) bool {
+		a, b := labels[i], labels[j]
+		if a.Endpoint != b.Endpoint {
+			return a.Endpoint < b.Endpoint
+		}
+		if a.Language != b.Language {
+			return a.Language < b.Language
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Error < b.Error
+	})
+
+	return labels
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func formatLabels(labels Labels, withError bool) string {
+	var sb strings.Builder
+	sb.WriteString(`endpoint="` + escapeLabelValue(labels.Endpoint) + `"`)
+	sb.WriteString(`,language="` + escapeLabelValue(labels.Language) + `"`)
+	sb.WriteString(`,version="` + escapeLabelValue(labels.Version) + `"`)
+	if withError {
+		sb.WriteString(`,error="` + escapeLabelValue(labels.Error) + `"`)
+	}
+
+	return sb.String()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,136 @@
+// Package metrics wraps the Pesto client to record the requests, errors, latency,
+// compile and runtime failures, and in-flight executions on a Collector.
+//
+// Counters is the bundled Collector, which keeps plain counters in memory
+// and serves them in the Prometheus text exposition format:
+//
+//	counters := metrics.NewCounters()
+//	client := metrics.NewClient(pestoClient, counters)
+//	http.Handle("/metrics", counters)
+//
+// Counters also records the queue depth and the queue wait of pesto.SchedulerConfig,
+// through SetQueueDepth and ObserveQueueWait.
+package metrics
+
+import (
+	"context"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+// Endpoint label values.
+const (
+	EndpointExecute      = "execute"
+	EndpointPing         = "ping"
+	EndpointListRuntimes = "list-runtimes"
+)
+
+// Labels describes a single call. Language and Version are empty for the
+// endpoints that don't execute any code. Error is empty if the call succeeded.
+type Labels struct {
+	Endpoint string
+	Language string
+	Version  string
+	Error    string
+}
+
+// Collector records the metrics of the calls made through Client.
+// Implementations must be safe for concurrent use.
+type Collector interface {
+	// IncRequests is called once for every call, after it has finished.
+	IncRequests(labels Labels)
+	// IncErrors is called once for every call that returned an error.
+	IncErrors(labels Labels)
+	// ObserveLatency is called once for every call, with the time it took.
+	ObserveLatency(labels Labels, latency time.Duration)
+	// IncCompileFailures is called when the code failed to compile,
+	// that is, the compile step exited with a non-zero exit code.
+	IncCompileFailures(labels Labels)
+	// IncRuntimeFailures is called when the code exited with a non-zero exit code,
+	// or was killed for exceeding the timeout or the memory limit, which Pesto
+	// reports with an exit code of 0. See pesto.Metadata.
+	IncRuntimeFailures(labels Labels)
+	// AddInFlight is called with 1 when an execution starts, and -1 when it ends.
+	AddInFlight(labels Labels, delta int64)
+}
+
+// ErrorLabel returns the Error label of err, which is its pesto.ErrorClass.
+// It returns an empty string if err is nil.
+func ErrorLabel(err error) string {
+	return pesto.ErrorClass(err)
+}
+
+// Client wraps a Pesto client and records the metrics of every call on the Collector.
+type Client struct {
+	client    *pesto.Client
+	collector Collector
+}
+
+var _ pesto.Executor = (*Client)(nil)
+
+// NewClient wraps the given client.
+func NewClient(client *pesto.Client, collector Collector) *Client {
+	return &Client{client: client, collector: collector}
+}
+
+// Unwrap returns the wrapped client.
+func (c *Client) Unwrap() *pesto.Client {
+	return c.client
+}
+
+// Execute calls pesto.Client.Execute and records the metrics of the call.
+// The Version label is the requested version, so "latest" is not resolved.
+func (c *Client) Execute(ctx context.Context, codeRequest pesto.CodeRequest) (pesto.CodeResponse, error) {
+	labels := Labels{
+		Endpoint: EndpointExecute,
+		Language: string(codeRequest.Language),
+		Version:  string(codeRequest.Version),
+	}
+
+	c.collector.AddInFlight(labels, 1)
+	defer c.collector.AddInFlight(labels, -1)
+
+	start := time.Now()
+	response, err := c.client.Execute(ctx, codeRequest)
+	c.record(labels, start, err)
+	if err != nil {
+		return response, err
+	}
+
+	if response.Compile.ExitCode != 0 {
+		c.collector.IncCompileFailures(labels)
+	}
+
+	if response.Runtime.ExitCode != 0 || response.Metadata.TimedOut || response.Metadata.MemoryExceeded {
+		c.collector.IncRuntimeFailures(labels)
+	}
+
+	return response, nil
+}
+
+// Ping calls pesto.Client.Ping and records the metrics of the call.
+func (c *Client) Ping(ctx context.Context) (pesto.PingResponse, error) {
+	start := time.Now()
+	response, err := c.client.Ping(ctx)
+	c.record(Labels{Endpoint: EndpointPing}, start, err)
+	return response, err
+}
+
+// ListRuntimes calls pesto.Client.ListRuntimes and records the metrics of the call.
+func (c *Client) ListRuntimes(ctx context.Context) (pesto.RuntimeResponse, error) {
+	start := time.Now()
+	response, err := c.client.ListRuntimes(ctx)
+	c.record(Labels{Endpoint: EndpointListRuntimes}, start, err)
+	return response, err
+}
+
+func (c *Client) record(labels Labels, start time.Time, err error) {
+	c.collector.ObserveLatency(labels, time.Since(start))
+	c.collector.IncRequests(labels)
+
+	if err != nil {
+		labels.Error = ErrorLabel(err)
+		c.collector.IncErrors(labels)
+	}
+}
@@ -0,0 +1,304 @@
+package metrics_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/metrics"
+	"github.com/teknologi-umum/pesto/sdk/go/pestotest"
+)
+
+func TestClient(t *testing.T) {
+	counters := metrics.NewCounters()
+	executeLabels := metrics.Labels{
+		Endpoint: metrics.EndpointExecute,
+		Language: string(pesto.LanguagePython),
+		Version:  string(pesto.VersionPython),
+	}
+
+	var inFlight atomic.Int64
+	server := pestotest.NewServer(
+		pestotest.WithToken("testing-token", 100),
+		pestotest.WithExecuteFunc(func(runtime pesto.Runtime, request pestotest.ExecuteRequest) pesto.CodeResponse {
+			inFlight.Store(counters.InFlight(executeLabels))
+
+			switch *request.Code {
+			case "compile error":
+				return pesto.CodeResponse{Compile: pesto.Output{Stderr: "SyntaxError", ExitCode: 1}}
+			case "runtime error":
+				return pesto.CodeResponse{Runtime: pesto.Output{Stderr: "ZeroDivisionError", ExitCode: 1}}
+			}
+
+			return pesto.CodeResponse{Runtime: pesto.Output{Stdout: "Hello World"}}
+		}),
+	)
+	defer server.Close()
+
+	pestoClient, err := pesto.NewClientWithConfig(pesto.Config{Token: "testing-token", BaseURL: server.BaseURL()})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	client := metrics.NewClient(pestoClient, counters)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for _, code := range []string{"print('Hello World')", "compile error", "runtime error", "runtime error"} {
+		_, err := client.Execute(ctx, pesto.CodeRequest{
+			Language: pesto.LanguagePython,
+			Version:  pesto.VersionPython,
+			Code:     code,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+
+	_, err = client.Execute(ctx, pesto.CodeRequest{Language: "Rust", Version: "1.64.0", Code: "fn main() {}"})
+	if !errors.Is(err, pesto.ErrRuntimeNotFound) {
+		t.Errorf("expecting an error of ErrRuntimeNotFound, instead got %v", err)
+	}
+
+	_, err = client.Ping(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	t.Run("Counters", func(t *testing.T) {
+		if inFlight.Load() != 1 {
+			t.Errorf("expecting 1 execution in flight during the execution, got %d", inFlight.Load())
+		}
+
+		if counters.InFlight(executeLabels) != 0 {
+			t.Errorf("expecting no executions in flight, got %d", counters.InFlight(executeLabels))
+		}
+
+		if counters.Requests(executeLabels) != 4 {
+			t.Errorf("expecting 4 requests, got %d", counters.Requests(executeLabels))
+		}
+
+		if counters.LatencyCount(executeLabels) != 4 {
+			t.Errorf("expecting 4 latency observations, got %d", counters.LatencyCount(executeLabels))
+		}
+
+		if counters.CompileFailures(executeLabels) != 1 {
+			t.Errorf("expecting 1 compile failure, got %d", counters.CompileFailures(executeLabels))
+		}
+
+		if counters.RuntimeFailures(executeLabels) != 2 {
+			t.Errorf("expecting 2 runtime failures, got %d", counters.RuntimeFailures(executeLabels))
+		}
+
+		rustErrors := counters.Errors(metrics.Labels{
+			Endpoint: metrics.EndpointExecute,
+			Language: "Rust",
+			Version:  "1.64.0",
+			Error:    "runtime_not_found",
+		})
+		if rustErrors != 1 {
+			t.Errorf("expecting 1 runtime_not_found error, got %d", rustErrors)
+		}
+
+		if counters.Requests(metrics.Labels{Endpoint: metrics.EndpointPing}) != 1 {
+			t.Errorf("expecting 1 ping request, got %d", counters.Requests(metrics.Labels{Endpoint: metrics.EndpointPing}))
+		}
+	})
+
+	t.Run("Prometheus", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		counters.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+		if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain") {
+			t.Errorf("unexpected Content-Type: %s", recorder.Header().Get("Content-Type"))
+		}
+
+		body := recorder.Body.String()
+		expected := []string{
+			"# TYPE pesto_requests_total counter",
+			`pesto_requests_total{endpoint="execute",language="Python",version="3.10.10"} 4`,
+			`pesto_errors_total{endpoint="execute",language="Rust",version="1.64.0",error="runtime_not_found"} 1`,
+			`pesto_compile_failures_total{endpoint="execute",language="Python",version="3.10.10"} 1`,
+			`pesto_runtime_failures_total{endpoint="execute",language="Python",version="3.10.10"} 2`,
+			`pesto_executions_in_flight{endpoint="execute",language="Python",version="3.10.10"} 0`,
+			"# TYPE pesto_request_duration_seconds histogram",
+			`pesto_request_duration_seconds_bucket{endpoint="execute",language="Python",version="3.10.10",le="+Inf"} 4`,
+			`pesto_request_duration_seconds_count{endpoint="ping",language="",version=""} 1`,
+		}
+		for _, line := range expected {
+			if !strings.Contains(body, line+"\n") {
+				t.Errorf("expecting the output to contain %q, got:\n%s", line, body)
+			}
+		}
+	})
+}
+
+func TestClient_Killed(t *testing.T) {
+	// Pesto's API does not report the metadata yet, but a gateway might.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Code string `json:"code"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"language": "Python",
+			"version": "3.10.2",
+			"compile": {"stdout": "", "stderr": "", "output": "", "exitCode": 0},
+			"runtime": {"stdout": "", "stderr": "", "output": "", "exitCode": 0},
+			"metadata": {"timedOut": ` + strconv.FormatBool(body.Code == "timeout") + `, "memoryExceeded": ` + strconv.FormatBool(body.Code == "memory") + `}
+		}`))
+	}))
+	defer server.Close()
+
+	baseURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("parsing server url: %s", err.Error())
+	}
+
+	pestoClient, err := pesto.NewClientWithConfig(pesto.Config{Token: "testing-token", BaseURL: baseURL})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	counters := metrics.NewCounters()
+	client := metrics.NewClient(pestoClient, counters)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for _, code := range []string{"print('Hello World')", "timeout", "memory"} {
+		_, err := client.Execute(ctx, pesto.CodeRequest{
+			Language: pesto.LanguagePython,
+			Version:  pesto.VersionPython,
+			Code:     code,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+
+	labels := metrics.Labels{
+		Endpoint: metrics.EndpointExecute,
+		Language: string(pesto.LanguagePython),
+		Version:  string(pesto.VersionPython),
+	}
+	if counters.RuntimeFailures(labels) != 2 {
+		t.Errorf("expecting 2 runtime failures, got %d", counters.RuntimeFailures(labels))
+	}
+}
+
+func TestCounters_Scheduler(t *testing.T) {
+	counters := metrics.NewCounters()
+
+	release := make(chan struct{})
+	server := pestotest.NewServer(
+		pestotest.WithToken("testing-token", 100),
+		pestotest.WithExecuteFunc(func(runtime pesto.Runtime, request pestotest.ExecuteRequest) pesto.CodeResponse {
+			<-release
+			return pesto.CodeResponse{Runtime: pesto.Output{Stdout: "Hello World"}}
+		}),
+	)
+	defer server.Close()
+
+	client, err := pesto.NewClientWithConfig(pesto.Config{
+		Token:   "testing-token",
+		BaseURL: server.BaseURL(),
+		Scheduler: &pesto.SchedulerConfig{
+			MaxConcurrency: 1,
+			OnQueueDepth:   counters.SetQueueDepth,
+			OnDequeue:      counters.ObserveQueueWait,
+		},
+	})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = client.Execute(ctx, pesto.CodeRequest{
+				Language: pesto.LanguagePython,
+				Version:  pesto.VersionPython,
+				Code:     "print('Hello World')",
+				Key:      "bulk",
+			})
+		}()
+	}
+
+	for client.SchedulerStats().Queued != 2 {
+		if ctx.Err() != nil {
+			t.Fatal("expecting 2 executions to be queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if counters.QueueDepth("bulk") != 2 {
+		t.Errorf("expecting a queue depth of 2, got %d", counters.QueueDepth("bulk"))
+	}
+
+	close(release)
+	wg.Wait()
+
+	if counters.QueueDepth("bulk") != 0 {
+		t.Errorf("expecting an empty queue, got %d", counters.QueueDepth("bulk"))
+	}
+
+	if counters.QueueWaitCount("bulk") != 3 {
+		t.Errorf("expecting 3 queue wait observations, got %d", counters.QueueWaitCount("bulk"))
+	}
+
+	var sb strings.Builder
+	err = counters.WritePrometheus(&sb)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	for _, line := range []string{
+		"# TYPE pesto_queue_depth gauge",
+		`pesto_queue_depth{key="bulk"} 0`,
+		"# TYPE pesto_queue_wait_seconds histogram",
+		`pesto_queue_wait_seconds_count{key="bulk"} 3`,
+	} {
+		if !strings.Contains(sb.String(), line+"\n") {
+			t.Errorf("expecting the output to contain %q, got:\n%s", line, sb.String())
+		}
+	}
+}
+
+func TestErrorLabel(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{err: nil, expected: ""},
+		{err: pesto.ErrMonthlyLimitExceeded, expected: "monthly_limit_exceeded"},
+		{err: context.DeadlineExceeded, expected: "deadline_exceeded"},
+		{err: pesto.ErrQueueTimeout, expected: "queue_timeout"},
+		{err: &pesto.ValidationError{Problems: []string{"language is empty"}}, expected: "invalid_request"},
+		{err: errors.New("something else"), expected: "other"},
+	}
+
+	for _, test := range tests {
+		if label := metrics.ErrorLabel(test.err); label != test.expected {
+			t.Errorf("expecting label of %v to be %q, got %q", test.err, test.expected, label)
+		}
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 func HappyMockServer() *httptest.Server {
@@ -16,9 +19,7 @@ func HappyMockServer() *httptest.Server {
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"message":"OK"}`))
+		writeMockResponse(w, r, http.StatusOK, `{"message":"OK"}`, url.Values{"message": {"OK"}})
 	})
 
 	handler.HandleFunc("/api/list-runtimes", func(w http.ResponseWriter, r *http.Request) {
@@ -27,9 +28,13 @@ func HappyMockServer() *httptest.Server {
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"runtime":[{"language":"Go","version":"1.18.2","aliases":["go","golang"],"compiled":true}]}`))
+		writeMockResponse(
+			w,
+			r,
+			http.StatusOK,
+			`{"runtime":[{"language":"Go","version":"1.18.2","aliases":["go","golang"],"compiled":true}]}`,
+			url.Values{"runtimes": {"language=Go&version=1.18.2&aliases=go&aliases=golang&compiled=true"}},
+		)
 	})
 
 	handler.HandleFunc("/api/execute", func(w http.ResponseWriter, r *http.Request) {
@@ -55,40 +60,99 @@ func HappyMockServer() *httptest.Server {
 		}
 
 		type requestBody struct {
-			Language       string `json:"language"`
-			Version        string `json:"version"`
-			Code           string `json:"code"`
-			CompileTimeout int32  `json:"compileTimeout,omitempty"`
-			RunTimeout     int32  `json:"runTimeout,omitempty"`
-			MemoryLimit    int32  `json:"memoryLimit,omitempty"`
+			Language string  `json:"language"`
+			Version  *string `json:"version"`
+			Code     *string `json:"code"`
+			Files    []struct {
+				Name       string `json:"name"`
+				Code       string `json:"code"`
+				Entrypoint bool   `json:"entrypoint"`
+			} `json:"files"`
+			CompileTimeout *float64 `json:"compileTimeout"`
+			RunTimeout     *float64 `json:"runTimeout"`
+			MemoryLimit    *float64 `json:"memoryLimit"`
 		}
 
+		// The rce server validates the body with a schema, every problem is listed
+		// with the wording of the schema library.
 		var body requestBody
-		err := json.NewDecoder(r.Body).Decode(&body)
+		var problems []string
+		var err error
+		if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+			err = r.ParseForm()
+			body.Language = r.PostForm.Get("language")
+			for key, target := range map[string]**string{"version": &body.Version, "code": &body.Code} {
+				if r.PostForm.Has(key) {
+					value := r.PostForm.Get(key)
+					*target = &value
+				}
+			}
+
+			// Every value of a form body is a string.
+			for _, key := range []string{"compileTimeout", "runTimeout", "memoryLimit"} {
+				if r.PostForm.Has(key) {
+					problems = append(problems, "Expected number, received string")
+				}
+			}
+		} else {
+			err = json.NewDecoder(r.Body).Decode(&body)
+		}
 		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(`{"message":"Invalid body parameter: ` + err.Error() + `"}`))
+			writeMockResponse(
+				w,
+				r,
+				http.StatusBadRequest,
+				`{"msg":"Invalid body content with the Content-Type header specification"}`,
+				url.Values{"msg": {"Invalid body content with the Content-Type header specification"}},
+			)
 			return
 		}
 
-		if body.Language == "" || body.Code == "" || body.Version == "" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(`{"message":"Missing parameters"}`))
+		if body.Language == "" {
+			problems = append([]string{"String must contain at least 1 character(s)"}, problems...)
+		}
+
+		for _, file := range body.Files {
+			if file.Name == "" || file.Code == "" {
+				problems = append(problems, "String must contain at least 1 character(s)")
+			}
+		}
+
+		for _, timeout := range []*float64{body.CompileTimeout, body.RunTimeout} {
+			if timeout != nil && *timeout > 30_000 {
+				problems = append(problems, "Number must be less than or equal to 30000")
+			}
+		}
+
+		if body.MemoryLimit != nil && *body.MemoryLimit > 1024*1024*1024 {
+			problems = append(problems, "Number must be less than or equal to 1073741824")
+		}
+
+		if len(problems) > 0 {
+			message := "Missing parameters: " + strings.Join(problems, ", ")
+			messageBody, _ := json.Marshal(map[string]string{"message": message})
+			writeMockResponse(w, r, http.StatusBadRequest, string(messageBody), url.Values{"message": {message}})
+			return
+		}
+
+		if (body.Code == nil && body.Files == nil) || (body.Code != nil && *body.Code == "" && body.Files != nil && len(body.Files) == 0) {
+			writeMockResponse(
+				w,
+				r,
+				http.StatusBadRequest,
+				`{"message":"Both code and files must not be empty"}`,
+				url.Values{"message": {"Both code and files must not be empty"}},
+			)
 			return
 		}
 
 		if body.Language != "Python" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(`{"message":"Runtime not found"}`))
+			writeMockResponse(w, r, http.StatusBadRequest, `{"message":"Runtime not found"}`, url.Values{"message": {"Runtime not found"}})
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{
+		// The rce server appends the runtime output under the "compile" key.
+		writeMockResponse(w, r, http.StatusOK, `{
 			"language": "Python",
 			"version": "3.10.2",
 			"compile": {
@@ -103,12 +167,34 @@ func HappyMockServer() *httptest.Server {
 			  "output": "Hello World",
 			  "exitCode": 0
 			}
-		  }`))
+		  }`, url.Values{
+			"language": {"Python"},
+			"version":  {"3.10.2"},
+			"compile": {
+				"stdout=&stderr=&output=&exitCode=0",
+				"stdout=Hello+World&stderr=&output=Hello+World&exitCode=0",
+			},
+		})
 	})
 
 	return httptest.NewServer(handler)
 }
 
+// writeMockResponse mimics the rce server, which responds with a form body
+// if the request accepts application/x-www-form-urlencoded, and JSON otherwise.
+func writeMockResponse(w http.ResponseWriter, r *http.Request, statusCode int, jsonBody string, formBody url.Values) {
+	if r.Header.Get("Accept") == "application/x-www-form-urlencoded" {
+		w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+		w.WriteHeader(statusCode)
+		w.Write([]byte(formBody.Encode()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write([]byte(jsonBody))
+}
+
 func RateLimitedMockServer() *httptest.Server {
 	rateLimiter := sync.Map{}
 
@@ -171,3 +257,52 @@ func RateLimitedMockServer() *httptest.Server {
 
 	return httptest.NewServer(handler)
 }
+
+// InstanceMockServer mimics a single self-hosted Pesto instance that always
+// responds with the given status code. The number of requests received by
+// each path is recorded on the returned map.
+func InstanceMockServer(statusCode int) (*httptest.Server, *sync.Map) {
+	hits := &sync.Map{}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, _ := hits.LoadOrStore(r.URL.Path, new(int64))
+		atomic.AddInt64(v.(*int64), 1)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		switch {
+		case statusCode != http.StatusOK:
+			w.Write([]byte(`{"message":"Something's wrong on our end"}`))
+		case r.URL.Path == "/api/list-runtimes":
+			w.Write([]byte(`{"runtime":[{"language":"Go","version":"1.18.2","aliases":["go","golang"],"compiled":true}]}`))
+		default:
+			w.Write([]byte(`{"message":"OK"}`))
+		}
+	})
+
+	return httptest.NewServer(handler), hits
+}
+
+func hitCount(hits *sync.Map, path string) int64 {
+	v, ok := hits.Load(path)
+	if !ok {
+		return 0
+	}
+
+	return atomic.LoadInt64(v.(*int64))
+}
+
+// ToggleMockServer responds with an internal server error while failing is true.
+func ToggleMockServer(failing *atomic.Bool, hits *atomic.Int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Something's wrong on our end"}`))
+			return
+		}
+
+		w.Write([]byte(`{"message":"OK"}`))
+	}))
+}
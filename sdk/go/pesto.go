@@ -16,17 +16,39 @@
 package pesto
 
 import (
+	"fmt"
 	"net/http"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Client stores data related to the HTTP request creation.
+//
+// A Client is safe for concurrent use by multiple goroutines, and should be
+// reused instead of created for every request. The token, the base URLs and
+// the default timeout can be changed at runtime through SetToken, SetBaseURLs
+// and SetDefaultTimeout. A change applies to the requests that start after it,
+// while requests that are already in flight keep the previous value.
 type Client struct {
-	baseURL        *url.URL
-	defaultTimeout time.Duration
-	token          string
-	httpClient     *http.Client
+	balancer         *balancer
+	healthChecker    *healthChecker
+	circuitBreaker   *circuitBreaker
+	scheduler        *scheduler
+	quotas           sync.Map // token -> Quota
+	lifecycle        lifecycle
+	codec            Codec
+	maxResponseBytes int64
+	logger           Logger
+	logBodies        bool
+	defaultTimeout   atomic.Int64
+	jobs             jobRegistry
+	callbacks        *callbackPool
+	// token is shared with the health checker, which must not
+	// hold a reference to the Client.
+	token      *atomic.Pointer[string]
+	httpClient *http.Client
 }
 
 // Config provides configuration for Pesto client.
@@ -34,9 +56,68 @@ type Config struct {
 	// BaseURL states the base URL of the Pesto's API.
 	// Defaults to "https://pesto.teknologiumum.com"
 	BaseURL *url.URL
-	// DefaultTimeout is used to set the timeout for HTTP request.
+	// BaseURLs states multiple base URLs of self-hosted Pesto instances.
+	// Requests are spread across them according to LoadBalancingStrategy,
+	// and fail over to the other instances when one of them is unreachable
+	// or returns ErrInternalServerError.
+	// If BaseURLs is provided, BaseURL is ignored.
+	BaseURLs []*url.URL
+	// BaseURLWeights states the weight of each item in BaseURLs, in the same order.
+	// It is only used by the Weighted strategy. Missing or non-positive weights
+	// default to 1.
+	BaseURLWeights []int
+	// LoadBalancingStrategy decides which of BaseURLs will receive the next request.
+	// Defaults to RoundRobin
+	LoadBalancingStrategy LoadBalancingStrategy
+	// EjectionDuration is how long an instance is taken out of the rotation
+	// after it fails a request or a health check.
+	// Defaults to 30 seconds
+	EjectionDuration time.Duration
+	// HealthCheckInterval enables periodic Ping calls to every instance
+	// when it is greater than zero. Instances that fail the health check are ejected,
+	// and instances that pass it are put back into the rotation.
+	// Note that each health check counts against the token's quota.
+	// Defaults to 0 (disabled)
+	HealthCheckInterval time.Duration
+	// CircuitBreaker enables the circuit breaker, which fails the requests
+	// fast with ErrCircuitOpen while Pesto's API is down.
+	// Defaults to nil (disabled)
+	CircuitBreaker *CircuitBreakerConfig
+	// Scheduler enables the scheduler, which caps the amount of executions in flight
+	// and queues the rest by priority, fairly across the keys of the requests.
+	// Defaults to nil (disabled)
+	Scheduler *SchedulerConfig
+	// DefaultTimeout is used to set the timeout for HTTP request, including
+	// reading the response body. A deadline on the request's context that is
+	// sooner than the timeout takes precedence.
 	// Defaults to 5 minutes
 	DefaultTimeout time.Duration
+	// Codec encodes the request bodies and decodes the response bodies.
+	// Defaults to JSONCodec
+	Codec Codec
+	// MaxResponseBytes caps the size of a response body that is read into memory,
+	// protecting against code that prints in an infinite loop.
+	// Defaults to 32 MiB
+	MaxResponseBytes int64
+	// Logger receives the structured logs of every request: the start and the end,
+	// the endpoint, the status, the latency, the failovers and the class of the error.
+	// The token is redacted, and the code is never logged unless LogBodies is enabled.
+	// *slog.Logger satisfies the Logger interface.
+	// Defaults to nil (no logging)
+	Logger Logger
+	// LogBodies dumps the request and response bodies to Logger on the debug level.
+	// Note that the request body contains the code.
+	// Defaults to false
+	LogBodies bool
+	// CallbackWorkers is the most goroutines that run the callbacks of the jobs
+	// created by Client.Submit at once. The goroutines exit once there are no
+	// callbacks to run.
+	// Defaults to 4
+	CallbackWorkers int
+	// JobHistory is the amount of finished jobs that are kept for Client.Jobs.
+	// A negative value keeps no history.
+	// Defaults to 100
+	JobHistory int
 	// Token contains the Pesto token.
 	// To acquire a token, go to https://pesto.teknologiumum.com/#request
 	Token string
@@ -54,48 +135,169 @@ func NewClient(token string) (*Client, error) {
 		return &Client{}, ErrEmptyToken
 	}
 
-	client := &Client{
-		baseURL: &url.URL{
-			Scheme: "https",
-			Host:   "pesto.teknologiumum.com",
-		},
-		defaultTimeout: time.Minute * 5,
-		token:          token,
-		httpClient:     &http.Client{Timeout: time.Minute * 5},
-	}
-
-	return client, nil
+	return newClient(Config{Token: token}), nil
 }
 
 // NewClientWithConfig creates a Client struct with the given Config struct.
 // If token is not provided, it will return ErrEmptyToken error.
 // If anything else is not provided, it will set a default value.
+// If a value cannot be used, it will return ErrInvalidConfig error.
 func NewClientWithConfig(config Config) (*Client, error) {
 	if config.Token == "" {
 		return &Client{}, ErrEmptyToken
 	}
 
+	if config.CircuitBreaker != nil && config.CircuitBreaker.Window != 0 && config.CircuitBreaker.Window < circuitBreakerBuckets {
+		return &Client{}, fmt.Errorf("%w: circuit breaker window of %s is shorter than %s", ErrInvalidConfig, config.CircuitBreaker.Window, time.Duration(circuitBreakerBuckets))
+	}
+
+	return newClient(config), nil
+}
+
+// newClient creates a Client struct from the given Config struct
+// without validating the token, since the token might be provided
+// per request through WithToken.
+func newClient(config Config) *Client {
 	client := &Client{
-		token:          config.Token,
-		baseURL:        config.BaseURL,
-		defaultTimeout: config.DefaultTimeout,
-		httpClient:     config.HttpClient,
+		codec:            config.Codec,
+		maxResponseBytes: config.MaxResponseBytes,
+		logger:           config.Logger,
+		logBodies:        config.LogBodies,
+		token:            &atomic.Pointer[string]{},
+		httpClient:       config.HttpClient,
 	}
+	client.token.Store(&config.Token)
 
-	if config.BaseURL == nil {
-		client.baseURL = &url.URL{
-			Scheme: "https",
-			Host:   "pesto.teknologiumum.com",
+	baseURLs := config.BaseURLs
+	if len(baseURLs) == 0 {
+		if config.BaseURL == nil {
+			config.BaseURL = &url.URL{
+				Scheme: "https",
+				Host:   "pesto.teknologiumum.com",
+			}
 		}
+
+		baseURLs = []*url.URL{config.BaseURL}
+	}
+
+	if config.EjectionDuration == 0 {
+		config.EjectionDuration = time.Second * 30
 	}
 
+	client.balancer = newBalancer(baseURLs, config.BaseURLWeights, config.LoadBalancingStrategy, config.EjectionDuration)
+
 	if config.DefaultTimeout == 0 {
-		client.defaultTimeout = time.Minute * 5
+		config.DefaultTimeout = time.Minute * 5
+	}
+	client.defaultTimeout.Store(int64(config.DefaultTimeout))
+
+	if config.Codec == nil {
+		client.codec = JSONCodec{}
+	}
+
+	if config.MaxResponseBytes <= 0 {
+		client.maxResponseBytes = defaultMaxResponseBytes
+	}
+
+	if config.Logger == nil {
+		client.logger = noopLogger{}
+	}
+
+	if config.CallbackWorkers <= 0 {
+		config.CallbackWorkers = 4
 	}
+	client.callbacks = newCallbackPool(config.CallbackWorkers, client.logger)
 
+	if config.JobHistory == 0 {
+		config.JobHistory = 100
+	}
+	client.jobs.historyCap = config.JobHistory
+
+	// The default timeout is applied through the request's context,
+	// so it can be changed at runtime.
 	if config.HttpClient == nil {
-		client.httpClient = &http.Client{Timeout: config.DefaultTimeout}
+		client.httpClient = &http.Client{}
+	}
+
+	if config.CircuitBreaker != nil {
+		breakerConfig := *config.CircuitBreaker
+		// The logger is captured instead of the client, to avoid keeping the client alive.
+		logger := client.logger
+		onStateChange := breakerConfig.OnStateChange
+		breakerConfig.OnStateChange = func(from, to CircuitState) {
+			logger.Warn("pesto: circuit breaker state changed", "from", from.String(), "to", to.String())
+			if onStateChange != nil {
+				onStateChange(from, to)
+			}
+		}
+
+		client.circuitBreaker = newCircuitBreaker(breakerConfig)
 	}
 
-	return client, nil
+	if config.Scheduler != nil {
+		client.scheduler = newScheduler(*config.Scheduler)
+	}
+
+	if config.HealthCheckInterval > 0 {
+		startHealthChecker(client, config.HealthCheckInterval)
+	}
+
+	return client
+}
+
+// CircuitState returns the current state of the circuit breaker.
+// If the circuit breaker is not enabled, it will always return CircuitClosed.
+func (c *Client) CircuitState() CircuitState {
+	if c.circuitBreaker == nil {
+		return CircuitClosed
+	}
+
+	return c.circuitBreaker.State()
+}
+
+// SetToken replaces the token that is sent with every request, for example
+// after the token was rotated. Requests that are already in flight keep
+// using the previous token. If token is empty, it will return ErrEmptyToken error.
+func (c *Client) SetToken(token string) error {
+	if token == "" {
+		return ErrEmptyToken
+	}
+
+	previous := c.token.Swap(&token)
+	if *previous != token {
+		c.forgetQuota(*previous)
+	}
+
+	return nil
+}
+
+// SetBaseURL replaces the base URL of Pesto's API. See SetBaseURLs.
+func (c *Client) SetBaseURL(baseURL *url.URL) error {
+	return c.SetBaseURLs([]*url.URL{baseURL}, nil)
+}
+
+// SetBaseURLs replaces the base URLs of the instances, along with their weights
+// for the Weighted strategy. The load balancing strategy and the ejection
+// duration are kept. Requests that are already in flight are not affected.
+// If no base URL is provided, it will return ErrEmptyBaseURL error.
+func (c *Client) SetBaseURLs(baseURLs []*url.URL, weights []int) error {
+	if len(baseURLs) == 0 {
+		return ErrEmptyBaseURL
+	}
+
+	for _, baseURL := range baseURLs {
+		if baseURL == nil {
+			return ErrEmptyBaseURL
+		}
+	}
+
+	c.balancer.setNodes(baseURLs, weights)
+	return nil
+}
+
+// SetDefaultTimeout replaces the timeout of every request that starts after the call.
+// A timeout of 0 or less disables the default timeout, leaving the deadline
+// to the request's context.
+func (c *Client) SetDefaultTimeout(timeout time.Duration) {
+	c.defaultTimeout.Store(int64(timeout))
 }
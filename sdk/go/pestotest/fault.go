@@ -0,0 +1,341 @@
+package pestotest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Fault is a failure that a FaultTransport injects.
+type Fault int
+
+const (
+	// FaultConnectionReset fails the request with a connection reset by peer.
+	FaultConnectionReset Fault = iota
+	// FaultTimeout hangs until the request is canceled, or until
+	// FaultConfig.Timeout elapses, then fails it with an i/o timeout.
+	FaultTimeout
+	// FaultSlowBody sends the request, and slows down reading the response body.
+	FaultSlowBody
+	// FaultTruncatedJSON sends the request, and cuts the response body in half.
+	FaultTruncatedJSON
+	// FaultInternalServerError responds with a 500 status and an HTML body,
+	// like a proxy in front of the API does.
+	FaultInternalServerError
+	// FaultNotFound responds with a 404 status.
+	FaultNotFound
+	// FaultMissingToken responds like the API does when the token is not sent.
+	FaultMissingToken
+	// FaultTokenNotRegistered responds like the API does for an unknown token.
+	FaultTokenNotRegistered
+	// FaultTokenRevoked responds like the API does for a revoked token.
+	FaultTokenRevoked
+	// FaultMonthlyLimitExceeded responds like the API does when the monthly quota is used up.
+	FaultMonthlyLimitExceeded
+	// FaultServerRateLimited responds like the API does for a burst of requests.
+	FaultServerRateLimited
+	// FaultRuntimeNotFound responds like the API does for an unknown runtime.
+	FaultRuntimeNotFound
+	// FaultMissingParameters responds like the API does for an invalid request body.
+	FaultMissingParameters
+	// FaultEmptyCodeAndFiles responds like the API does for a request without code and files.
+	FaultEmptyCodeAndFiles
+	// FaultEmptyFileName responds like the API does for a file without a name.
+	FaultEmptyFileName
+	// FaultEntrypointsExceeded responds like the API does for a request with
+	// more entrypoints than the runtime allows.
+	FaultEntrypointsExceeded
+	// FaultInvalidBody responds like the API does for a body that can't be
+	// parsed, which carries the message under "msg" instead of "message".
+	FaultInvalidBody
+)
+
+// faultCount is the amount of faults, which are numbered from 0.
+const faultCount = int(FaultInvalidBody) + 1
+
+var faultNames = [faultCount]string{
+	"connection reset",
+	"timeout",
+	"slow body",
+	"truncated JSON",
+	"internal server error",
+	"not found",
+	"missing token",
+	"token not registered",
+	"token revoked",
+	"monthly limit exceeded",
+	"server rate limited",
+	"runtime not found",
+	"missing parameters",
+	"empty code and files",
+	"empty file name",
+	"entrypoints exceeded",
+	"invalid body",
+}
+
+func (f Fault) String() string {
+	if f < 0 || int(f) >= faultCount {
+		return "unknown fault " + strconv.Itoa(int(f))
+	}
+
+	return faultNames[f]
+}
+
+// errorFaults are the faults that respond with an error message of the API,
+// the same ones that are mapped to the errors of the SDK.
+var errorFaults = map[Fault]struct {
+	statusCode int
+	message    string
+	// key is the key of the message on the response body, "message" if empty.
+	key string
+}{
+	FaultNotFound:             {http.StatusNotFound, "Not found", ""},
+	FaultMissingToken:         {http.StatusUnauthorized, "Token must be supplied", ""},
+	FaultTokenNotRegistered:   {http.StatusUnauthorized, "Token not registered", ""},
+	FaultTokenRevoked:         {http.StatusUnauthorized, "Token has been revoked", ""},
+	FaultMonthlyLimitExceeded: {http.StatusTooManyRequests, "Monthly limit exceeded", ""},
+	FaultServerRateLimited:    {http.StatusTooManyRequests, "Too many requests", ""},
+	FaultRuntimeNotFound:      {http.StatusBadRequest, "Runtime not found", ""},
+	FaultMissingParameters:    {http.StatusBadRequest, "Missing parameters: String must contain at least 1 character(s)", ""},
+	FaultEmptyCodeAndFiles:    {http.StatusBadRequest, "Both code and files must not be empty", ""},
+	FaultEmptyFileName:        {http.StatusBadRequest, "File name cannot be empty", ""},
+	FaultEntrypointsExceeded:  {http.StatusBadRequest, "Maximum allowed entrypoint exceeded of 1 entries", ""},
+	FaultInvalidBody:          {http.StatusBadRequest, "Invalid body content with the Content-Type header specification", "msg"},
+}
+
+// internalServerErrorBody is not JSON, which the SDK must survive.
+const internalServerErrorBody = "<html><body><h1>500 Internal Server Error</h1></body></html>\n"
+
+// FaultConfig configures a FaultTransport.
+type FaultConfig struct {
+	// Probabilities is the probability of each fault, between 0 and 1. A request gets
+	// at most one fault, drawn in the order of the Fault constants, so the sum of the
+	// probabilities should not exceed 1.
+	// Defaults to nil (no faults)
+	Probabilities map[Fault]float64
+	// Seed seeds the pseudo-random generator that draws the faults, so the faults
+	// of a failing test can be reproduced with the same seed.
+	// Defaults to 0
+	Seed int64
+	// Timeout is how long FaultTimeout hangs before it fails the request.
+	// Defaults to 30 seconds
+	Timeout time.Duration
+	// SlowBodyDelay is the pause before each chunk of a body slowed by FaultSlowBody.
+	// Defaults to 10 milliseconds
+	SlowBodyDelay time.Duration
+	// Transport sends the requests that get no fault, or a fault of the response body.
+	// Defaults to http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// FaultTransport is an http.RoundTripper that makes requests fail on purpose,
+// to test how the code that uses the SDK copes with the failures of the network
+// and of Pesto's API:
+//
+//	transport := pestotest.NewFaultTransport(pestotest.FaultConfig{
+//		Probabilities: map[pestotest.Fault]float64{
+//			pestotest.FaultConnectionReset:     0.1,
+//			pestotest.FaultInternalServerError: 0.1,
+//		},
+//	})
+//	client, err := pesto.NewClientWithConfig(pesto.Config{
+//		Token:      "token",
+//		BaseURL:    server.BaseURL(),
+//		HttpClient: &http.Client{Transport: transport},
+//	})
+type FaultTransport struct {
+	probabilities [faultCount]float64
+	timeout       time.Duration
+	slowBodyDelay time.Duration
+	transport     http.RoundTripper
+
+	mu       sync.Mutex
+	random   *rand.Rand
+	injected [faultCount]int
+}
+
+// NewFaultTransport creates a FaultTransport.
+func NewFaultTransport(config FaultConfig) *FaultTransport {
+	t := &FaultTransport{
+		timeout:       config.Timeout,
+		slowBodyDelay: config.SlowBodyDelay,
+		transport:     config.Transport,
+		random:        rand.New(rand.NewSource(config.Seed)),
+	}
+
+	for fault, probability := range config.Probabilities {
+		if fault >= 0 && int(fault) < faultCount {
+			t.probabilities[fault] = probability
+		}
+	}
+
+	if t.timeout <= 0 {
+		t.timeout = time.Second * 30
+	}
+
+	if t.slowBodyDelay <= 0 {
+		t.slowBodyDelay = time.Millisecond * 10
+	}
+
+	if t.transport == nil {
+		t.transport = http.DefaultTransport
+	}
+
+	return t
+}
+
+// Injected returns how many times each fault was injected.
+func (t *FaultTransport) Injected() map[Fault]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	injected := make(map[Fault]int)
+	for fault, count := range t.injected {
+		if count > 0 {
+			injected[Fault(fault)] = count
+		}
+	}
+
+	return injected
+}
+
+// draw picks the fault of the next request, if any.
+func (t *FaultTransport) draw() (Fault, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := t.random.Float64()
+	cumulative := 0.0
+	for fault, probability := range t.probabilities {
+		cumulative += probability
+		if n < cumulative {
+			t.injected[fault]++
+			return Fault(fault), true
+		}
+	}
+
+	return 0, false
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *FaultTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	fault, ok := t.draw()
+	if !ok {
+		return t.transport.RoundTrip(request)
+	}
+
+	switch fault {
+	case FaultSlowBody, FaultTruncatedJSON:
+		return t.faultyResponse(request, fault)
+	}
+
+	if request.Body != nil {
+		_ = request.Body.Close()
+	}
+
+	switch fault {
+	case FaultConnectionReset:
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+	case FaultTimeout:
+		select {
+		case <-request.Context().Done():
+			return nil, request.Context().Err()
+		case <-time.After(t.timeout):
+			return nil, &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}
+		}
+	case FaultInternalServerError:
+		return newResponse(request, http.StatusInternalServerError, "text/html; charset=utf-8", internalServerErrorBody), nil
+	default:
+		errorFault := errorFaults[fault]
+		key := errorFault.key
+		if key == "" {
+			key = "message"
+		}
+
+		body, _ := json.Marshal(map[string]string{key: errorFault.message})
+		return newResponse(request, errorFault.statusCode, "application/json", string(body)), nil
+	}
+}
+
+// faultyResponse sends the request, and injects the fault into the response body.
+func (t *FaultTransport) faultyResponse(request *http.Request, fault Fault) (*http.Response, error) {
+	response, err := t.transport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if fault == FaultSlowBody {
+		response.Body = &slowBody{ReadCloser: response.Body, ctx: request.Context(), delay: t.slowBodyDelay}
+		return response, nil
+	}
+
+	body, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	body = body[:len(body)/2]
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	response.ContentLength = int64(len(body))
+	response.Header.Del("Content-Length")
+	return response, nil
+}
+
+func newResponse(request *http.Request, statusCode int, contentType string, body string) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(statusCode) + " " + http.StatusText(statusCode),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{contentType}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}
+}
+
+// timeoutError is the net.Error of FaultTimeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// slowBody reads the body in small chunks, pausing before each of them.
+type slowBody struct {
+	io.ReadCloser
+	ctx   context.Context
+	delay time.Duration
+}
+
+// slowBodyChunk is the most that is read from a slowBody at once.
+const slowBodyChunk = 16
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	timer := time.NewTimer(b.delay)
+	defer timer.Stop()
+
+	select {
+	case <-b.ctx.Done():
+		return 0, b.ctx.Err()
+	case <-timer.C:
+	}
+
+	if len(p) > slowBodyChunk {
+		p = p[:slowBodyChunk]
+	}
+
+	return b.ReadCloser.Read(p)
+}
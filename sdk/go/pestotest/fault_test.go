@@ -0,0 +1,207 @@
+package pestotest_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/pestotest"
+)
+
+func newFaultClient(t *testing.T, server *pestotest.Server, transport *pestotest.FaultTransport) *pesto.Client {
+	t.Helper()
+
+	client, err := pesto.NewClientWithConfig(pesto.Config{
+		Token:      "testing-token",
+		BaseURL:    server.BaseURL(),
+		HttpClient: &http.Client{Transport: transport},
+	})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	return client
+}
+
+func TestFaultTransport(t *testing.T) {
+	server := pestotest.NewServer(
+		pestotest.WithToken("testing-token", 1000),
+		pestotest.WithExecuteFunc(func(runtime pesto.Runtime, request pestotest.ExecuteRequest) pesto.CodeResponse {
+			return pesto.CodeResponse{Runtime: pesto.Output{Stdout: "Hello World\n", Output: "Hello World\n"}}
+		}),
+	)
+	defer server.Close()
+
+	isTimeout := func(err error) bool {
+		var netErr net.Error
+		return errors.As(err, &netErr) && netErr.Timeout()
+	}
+
+	tests := []struct {
+		fault pestotest.Fault
+		check func(err error) bool
+	}{
+		{fault: pestotest.FaultConnectionReset, check: func(err error) bool { return errors.Is(err, syscall.ECONNRESET) }},
+		{fault: pestotest.FaultTimeout, check: isTimeout},
+		{fault: pestotest.FaultTruncatedJSON, check: func(err error) bool { return err != nil && strings.Contains(err.Error(), "decoding response body") }},
+		{fault: pestotest.FaultInternalServerError, check: func(err error) bool { return errors.Is(err, pesto.ErrInternalServerError) }},
+		{fault: pestotest.FaultNotFound, check: func(err error) bool { return err != nil && err.Error() == "api path not found" }},
+		{fault: pestotest.FaultMissingToken, check: func(err error) bool { return errors.Is(err, pesto.ErrMissingToken) }},
+		{fault: pestotest.FaultTokenNotRegistered, check: func(err error) bool { return errors.Is(err, pesto.ErrTokenNotRegistered) }},
+		{fault: pestotest.FaultTokenRevoked, check: func(err error) bool { return errors.Is(err, pesto.ErrTokenRevoked) }},
+		{fault: pestotest.FaultMonthlyLimitExceeded, check: func(err error) bool { return errors.Is(err, pesto.ErrMonthlyLimitExceeded) }},
+		{fault: pestotest.FaultServerRateLimited, check: func(err error) bool { return errors.Is(err, pesto.ErrServerRateLimited) }},
+		{fault: pestotest.FaultRuntimeNotFound, check: func(err error) bool { return errors.Is(err, pesto.ErrRuntimeNotFound) }},
+		{fault: pestotest.FaultMissingParameters, check: func(err error) bool { return errors.Is(err, pesto.ErrMissingParameters) }},
+		{fault: pestotest.FaultEmptyCodeAndFiles, check: func(err error) bool { return errors.Is(err, pesto.ErrMissingParameters) }},
+		{fault: pestotest.FaultEmptyFileName, check: func(err error) bool { return errors.Is(err, pesto.ErrMissingParameters) }},
+		{fault: pestotest.FaultEntrypointsExceeded, check: func(err error) bool { return errors.Is(err, pesto.ErrInvalidRequest) }},
+		{fault: pestotest.FaultInvalidBody, check: func(err error) bool {
+			return err != nil && strings.Contains(err.Error(), "Invalid body content with the Content-Type header specification")
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fault.String(), func(t *testing.T) {
+			transport := pestotest.NewFaultTransport(pestotest.FaultConfig{
+				Probabilities: map[pestotest.Fault]float64{test.fault: 1},
+				Timeout:       10 * time.Millisecond,
+			})
+			client := newFaultClient(t, server, transport)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			_, err := client.Execute(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionLatest, Code: "print('Hello World')"})
+			if !test.check(err) {
+				t.Errorf("expecting the error of %s, instead got %v", test.fault, err)
+			}
+
+			if injected := transport.Injected(); injected[test.fault] != 1 {
+				t.Errorf("expecting the fault to be injected once, got %v", injected)
+			}
+		})
+	}
+
+	t.Run("SlowBody", func(t *testing.T) {
+		delay := 5 * time.Millisecond
+		transport := pestotest.NewFaultTransport(pestotest.FaultConfig{
+			Probabilities: map[pestotest.Fault]float64{pestotest.FaultSlowBody: 1},
+			SlowBodyDelay: delay,
+		})
+		client := newFaultClient(t, server, transport)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		start := time.Now()
+		response, err := client.Execute(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionLatest, Code: "print('Hello World')"})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if response.Runtime.Stdout != "Hello World\n" {
+			t.Errorf("expecting the whole body to be read, got %+v", response)
+		}
+
+		if elapsed := time.Since(start); elapsed < 5*delay {
+			t.Errorf("expecting the body to be read slowly, took %s", elapsed)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), delay)
+		defer cancel()
+
+		_, err = client.Execute(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionLatest, Code: "print('Hello World')"})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expecting an error of context.DeadlineExceeded, instead got %v", err)
+		}
+	})
+
+	t.Run("TimeoutCanceled", func(t *testing.T) {
+		transport := pestotest.NewFaultTransport(pestotest.FaultConfig{
+			Probabilities: map[pestotest.Fault]float64{pestotest.FaultTimeout: 1},
+		})
+		client := newFaultClient(t, server, transport)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := client.Ping(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expecting an error of context.DeadlineExceeded, instead got %v", err)
+		}
+	})
+}
+
+func TestFaultTransport_Probabilities(t *testing.T) {
+	server := pestotest.NewServer(pestotest.WithToken("testing-token", 1000))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	outcomes := func(seed int64) ([]string, map[pestotest.Fault]int) {
+		transport := pestotest.NewFaultTransport(pestotest.FaultConfig{
+			Probabilities: map[pestotest.Fault]float64{
+				pestotest.FaultServerRateLimited: 0.3,
+				pestotest.FaultRuntimeNotFound:   0.2,
+			},
+			Seed: seed,
+		})
+		client := newFaultClient(t, server, transport)
+
+		var outcomes []string
+		for i := 0; i < 200; i++ {
+			_, err := client.Execute(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionLatest, Code: "print(1)"})
+			switch {
+			case err == nil:
+				outcomes = append(outcomes, "ok")
+			case errors.Is(err, pesto.ErrServerRateLimited):
+				outcomes = append(outcomes, "rate limited")
+			case errors.Is(err, pesto.ErrRuntimeNotFound):
+				outcomes = append(outcomes, "runtime not found")
+			default:
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+		}
+
+		return outcomes, transport.Injected()
+	}
+
+	first, injected := outcomes(42)
+
+	counts := make(map[string]int)
+	for _, outcome := range first {
+		counts[outcome]++
+	}
+
+	if counts["rate limited"] != injected[pestotest.FaultServerRateLimited] || counts["runtime not found"] != injected[pestotest.FaultRuntimeNotFound] {
+		t.Errorf("expecting the outcomes %v to match the injected faults %v", counts, injected)
+	}
+
+	if counts["ok"] < 60 || counts["ok"] > 140 {
+		t.Errorf("expecting about half of the requests to succeed, got %d of 200", counts["ok"])
+	}
+
+	second, _ := outcomes(42)
+	if !reflect.DeepEqual(first, second) {
+		t.Error("expecting the same seed to inject the same faults")
+	}
+}
+
+func TestFault_String(t *testing.T) {
+	if s := pestotest.FaultTruncatedJSON.String(); s != "truncated JSON" {
+		t.Errorf("expecting 'truncated JSON', got %s", s)
+	}
+
+	if s := pestotest.Fault(100).String(); s != "unknown fault 100" {
+		t.Errorf("expecting 'unknown fault 100', got %s", s)
+	}
+}
@@ -0,0 +1,400 @@
+package pestotest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrUnmatchedRequest indicates a Recorder replaying a cassette got a request
+// that matches none of its interactions.
+var ErrUnmatchedRequest = errors.New("unmatched request")
+
+// redacted replaces the secret headers in the cassette.
+const redacted = "REDACTED"
+
+// secretHeaders are never written to a cassette.
+var secretHeaders = []string{"X-Pesto-Token", "Authorization", "Cookie", "Set-Cookie"}
+
+// cassetteVersion is the version of the cassette format.
+const cassetteVersion = 1
+
+// Mode tells whether a Recorder replays the cassette or records a new one.
+type Mode int
+
+const (
+	// ModeReplay answers the requests from the cassette, without a network.
+	// A request that matches no interaction fails with ErrUnmatchedRequest.
+	ModeReplay Mode = iota
+	// ModeRecord sends every request to the transport, and records
+	// the interactions, replacing the ones of the cassette on Save.
+	ModeRecord
+	// ModeReplayOrRecord answers the requests from the cassette, and
+	// records the requests that match none of its interactions.
+	ModeReplayOrRecord
+	// ModeReplayOrLive answers the requests from the cassette, and sends the
+	// requests that match none of its interactions to the transport, without
+	// recording them.
+	ModeReplayOrLive
+)
+
+// Match is a set of the parts of a request that are compared with the
+// recorded requests.
+type Match int
+
+const (
+	// MatchEndpoint compares the method, the path and the query of the URL,
+	// ignoring the host, so a cassette works with any BaseURL.
+	MatchEndpoint Match = 1 << iota
+	// MatchBody compares the SHA-256 hash of the request body.
+	MatchBody
+)
+
+// RecorderConfig configures a Recorder.
+type RecorderConfig struct {
+	// Cassette is the path of the JSON file the interactions are read from and saved to.
+	// A missing file is an empty cassette, unless Mode is ModeReplay.
+	Cassette string
+	// Mode tells whether the cassette is replayed or recorded.
+	// Defaults to ModeReplay
+	Mode Mode
+	// Match is what a request is matched on.
+	// Defaults to MatchEndpoint | MatchBody
+	Match Match
+	// Strict plays every interaction only once, so a request that matches only
+	// interactions that were played is unmatched. Otherwise the last matching
+	// interaction is played again once the others were played.
+	// Defaults to false
+	Strict bool
+	// Transport sends the requests that are recorded, or the unmatched ones in ModeReplayOrLive.
+	// Defaults to http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// Interaction is a recorded request along with its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request of the cassette. The secret headers, like the token, are redacted.
+type RecordedRequest struct {
+	Method   string              `json:"method"`
+	URL      string              `json:"url"`
+	Header   map[string][]string `json:"header,omitempty"`
+	Body     string              `json:"body,omitempty"`
+	BodyHash string              `json:"bodyHash"`
+}
+
+// RecordedResponse is a response of the cassette.
+type RecordedResponse struct {
+	StatusCode int                 `json:"statusCode"`
+	Header     map[string][]string `json:"header,omitempty"`
+	Body       string              `json:"body"`
+}
+
+type cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper that records the interactions with Pesto's API
+// to a cassette, and replays them, so the SDK can be tested against real payloads
+// without a network:
+//
+//	recorder, err := pestotest.NewRecorder(pestotest.RecorderConfig{Cassette: "testdata/execute.json"})
+//	// ...
+//	client, err := pesto.NewClientWithConfig(pesto.Config{
+//		Token:      "token",
+//		HttpClient: &http.Client{Transport: recorder},
+//	})
+//
+// Run the tests with ModeRecord and a real token to record the cassette, then call
+// Save. The token is redacted from the cassette.
+type Recorder struct {
+	mode      Mode
+	match     Match
+	strict    bool
+	path      string
+	transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	played       []bool
+	recorded     []Interaction
+}
+
+// NewRecorder creates a Recorder, reading the interactions of the cassette.
+func NewRecorder(config RecorderConfig) (*Recorder, error) {
+	r := &Recorder{
+		mode:      config.Mode,
+		match:     config.Match,
+		strict:    config.Strict,
+		path:      config.Cassette,
+		transport: config.Transport,
+	}
+
+	if r.match == 0 {
+		r.match = MatchEndpoint | MatchBody
+	}
+
+	if r.transport == nil {
+		r.transport = http.DefaultTransport
+	}
+
+	if r.mode == ModeRecord {
+		return r, nil
+	}
+
+	content, err := os.ReadFile(config.Cassette)
+	if errors.Is(err, os.ErrNotExist) && r.mode == ModeReplayOrRecord {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading cassette: %w", err)
+	}
+
+	var c cassette
+	err = json.Unmarshal(content, &c)
+	if err != nil {
+		return nil, fmt.Errorf("parsing cassette %s: %w", config.Cassette, err)
+	}
+
+	if c.Version != cassetteVersion {
+		return nil, fmt.Errorf("parsing cassette %s: unsupported version %d", config.Cassette, c.Version)
+	}
+
+	r.interactions = c.Interactions
+	r.played = make([]bool, len(c.Interactions))
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(request)
+	if err != nil {
+		return nil, err
+	}
+	recordedRequest := newRecordedRequest(request, body)
+
+	if r.mode != ModeRecord {
+		if interaction, ok := r.play(recordedRequest); ok {
+			return interaction.Response.toResponse(request), nil
+		}
+
+		if r.mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrUnmatchedRequest, request.Method, request.URL.RequestURI())
+		}
+	}
+
+	outgoing := request.Clone(request.Context())
+	if body != nil {
+		outgoing.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	response, err := r.transport.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode == ModeReplayOrLive {
+		return response, nil
+	}
+
+	responseBody, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("recording response: %w", err)
+	}
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	r.mu.Lock()
+	r.recorded = append(r.recorded, Interaction{
+		Request: recordedRequest,
+		Response: RecordedResponse{
+			StatusCode: response.StatusCode,
+			Header:     redactHeader(response.Header),
+			Body:       string(responseBody),
+		},
+	})
+	r.mu.Unlock()
+
+	return response, nil
+}
+
+// play finds the first interaction that matches the request and was not played yet.
+// Unless the Recorder is strict, the last matching interaction is played again
+// once every matching interaction was played.
+func (r *Recorder) play(request RecordedRequest) (Interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last := -1
+	for i, interaction := range r.interactions {
+		if !r.matches(interaction.Request, request) {
+			continue
+		}
+
+		if !r.played[i] {
+			r.played[i] = true
+			return interaction, true
+		}
+		last = i
+	}
+
+	if last >= 0 && !r.strict {
+		return r.interactions[last], true
+	}
+
+	return Interaction{}, false
+}
+
+func (r *Recorder) matches(recorded RecordedRequest, request RecordedRequest) bool {
+	if r.match&MatchEndpoint != 0 {
+		if recorded.Method != request.Method || endpoint(recorded.URL) != endpoint(request.URL) {
+			return false
+		}
+	}
+
+	if r.match&MatchBody != 0 && recorded.BodyHash != request.BodyHash {
+		return false
+	}
+
+	return true
+}
+
+// Unplayed returns the interactions of the cassette that were never played,
+// to check that a test made every request it was recorded with.
+func (r *Recorder) Unplayed() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unplayed []Interaction
+	for i, interaction := range r.interactions {
+		if !r.played[i] {
+			unplayed = append(unplayed, interaction)
+		}
+	}
+
+	return unplayed
+}
+
+// Save writes the cassette. In ModeRecord, it holds the recorded interactions,
+// while in ModeReplayOrRecord the recorded interactions are added to the ones
+// that were read. It does nothing in ModeReplay and ModeReplayOrLive.
+func (r *Recorder) Save() error {
+	if r.mode == ModeReplay || r.mode == ModeReplayOrLive {
+		return nil
+	}
+
+	r.mu.Lock()
+	c := cassette{Version: cassetteVersion, Interactions: r.recorded}
+	if r.mode == ModeReplayOrRecord {
+		c.Interactions = append(append([]Interaction{}, r.interactions...), r.recorded...)
+	}
+	r.mu.Unlock()
+
+	if c.Interactions == nil {
+		c.Interactions = []Interaction{}
+	}
+
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding cassette: %w", err)
+	}
+
+	err = os.WriteFile(r.path, append(content, '\n'), 0o644)
+	if err != nil {
+		return fmt.Errorf("writing cassette: %w", err)
+	}
+
+	return nil
+}
+
+// readRequestBody reads and closes the body of the request.
+func readRequestBody(request *http.Request) ([]byte, error) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(request.Body)
+	_ = request.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading request body: %w", err)
+	}
+
+	return body, nil
+}
+
+func newRecordedRequest(request *http.Request, body []byte) RecordedRequest {
+	hash := sha256.Sum256(body)
+	return RecordedRequest{
+		Method:   request.Method,
+		URL:      request.URL.String(),
+		Header:   redactHeader(request.Header),
+		Body:     string(body),
+		BodyHash: hex.EncodeToString(hash[:]),
+	}
+}
+
+// redactHeader copies the header, replacing the values of the secret headers.
+func redactHeader(header http.Header) map[string][]string {
+	if len(header) == 0 {
+		return nil
+	}
+
+	redactedHeader := make(map[string][]string, len(header))
+	for key, values := range header {
+		redactedHeader[key] = append([]string{}, values...)
+	}
+
+	for _, key := range secretHeaders {
+		if _, ok := redactedHeader[key]; ok {
+			redactedHeader[key] = []string{redacted}
+		}
+	}
+
+	return redactedHeader
+}
+
+// endpoint returns the path and the query of a URL, with the query parameters sorted.
+func endpoint(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	if parsed.RawQuery == "" {
+		return parsed.EscapedPath()
+	}
+
+	return parsed.EscapedPath() + "?" + parsed.Query().Encode()
+}
+
+func (r RecordedResponse) toResponse(request *http.Request) *http.Response {
+	header := make(http.Header, len(r.Header))
+	for key, values := range r.Header {
+		header[key] = append([]string{}, values...)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       request,
+	}
+}
@@ -0,0 +1,251 @@
+package pestotest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/pestotest"
+)
+
+// errNetwork is returned by offlineTransport, to prove the Recorder answers without a network.
+var errNetwork = errors.New("network is unreachable")
+
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errNetwork
+}
+
+func TestRecorder(t *testing.T) {
+	server := pestotest.NewServer(
+		pestotest.WithToken("testing-token", 100),
+		pestotest.WithExecuteFunc(func(runtime pesto.Runtime, request pestotest.ExecuteRequest) pesto.CodeResponse {
+			return pesto.CodeResponse{Runtime: pesto.Output{Stdout: *request.Code}}
+		}),
+	)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+	recorder, err := pestotest.NewRecorder(pestotest.RecorderConfig{Cassette: cassette, Mode: pestotest.ModeRecord})
+	if err != nil {
+		t.Fatalf("creating recorder: %s", err.Error())
+	}
+
+	client, err := pesto.NewClientWithConfig(pesto.Config{
+		Token:      "testing-token",
+		BaseURL:    server.BaseURL(),
+		HttpClient: &http.Client{Transport: recorder},
+	})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	for _, code := range []string{"first", "second"} {
+		_, err = client.Execute(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionLatest, Code: code})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+
+	_, err = client.Ping(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	err = recorder.Save()
+	if err != nil {
+		t.Fatalf("saving cassette: %s", err.Error())
+	}
+
+	content, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatalf("reading cassette: %s", err.Error())
+	}
+
+	if strings.Contains(string(content), "testing-token") || !strings.Contains(string(content), "REDACTED") {
+		t.Errorf("expecting the token to be redacted, got %s", content)
+	}
+
+	// The host of the server is not part of the match, so the cassette is replayed offline.
+	execute := func(t *testing.T, config pestotest.RecorderConfig, code string) (*pestotest.Recorder, pesto.CodeResponse, error) {
+		t.Helper()
+
+		config.Cassette = cassette
+		config.Transport = offlineTransport{}
+		recorder, err := pestotest.NewRecorder(config)
+		if err != nil {
+			t.Fatalf("creating recorder: %s", err.Error())
+		}
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{Token: "another-token", HttpClient: &http.Client{Transport: recorder}})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		response, err := client.Execute(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionLatest, Code: code})
+		return recorder, response, err
+	}
+
+	t.Run("MatchBody", func(t *testing.T) {
+		recorder, response, err := execute(t, pestotest.RecorderConfig{Strict: true}, "second")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if response.Runtime.Stdout != "second" {
+			t.Errorf("expecting the interaction of the same body to be played, got %q", response.Runtime.Stdout)
+		}
+
+		if unplayed := recorder.Unplayed(); len(unplayed) != 2 {
+			t.Errorf("expecting 2 unplayed interactions, got %d", len(unplayed))
+		}
+	})
+
+	t.Run("MatchEndpoint", func(t *testing.T) {
+		_, response, err := execute(t, pestotest.RecorderConfig{Match: pestotest.MatchEndpoint}, "third")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if response.Runtime.Stdout != "first" {
+			t.Errorf("expecting the first interaction of the endpoint to be played, got %q", response.Runtime.Stdout)
+		}
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		_, _, err := execute(t, pestotest.RecorderConfig{Strict: true}, "third")
+		if !errors.Is(err, pestotest.ErrUnmatchedRequest) {
+			t.Errorf("expecting an error of ErrUnmatchedRequest, instead got %v", err)
+		}
+	})
+
+	t.Run("Unmatched", func(t *testing.T) {
+		_, _, err := execute(t, pestotest.RecorderConfig{}, "third")
+		if !errors.Is(err, pestotest.ErrUnmatchedRequest) {
+			t.Errorf("expecting an error of ErrUnmatchedRequest, instead got %v", err)
+		}
+	})
+
+	t.Run("ReplayOrLive", func(t *testing.T) {
+		_, _, err := execute(t, pestotest.RecorderConfig{Mode: pestotest.ModeReplayOrLive}, "third")
+		if !errors.Is(err, errNetwork) {
+			t.Errorf("expecting the unmatched request to be sent to the transport, instead got %v", err)
+		}
+	})
+
+	t.Run("PlayedOnce", func(t *testing.T) {
+		for _, strict := range []bool{true, false} {
+			recorder, err := pestotest.NewRecorder(pestotest.RecorderConfig{
+				Cassette:  cassette,
+				Match:     pestotest.MatchEndpoint,
+				Strict:    strict,
+				Transport: offlineTransport{},
+			})
+			if err != nil {
+				t.Fatalf("creating recorder: %s", err.Error())
+			}
+
+			client, err := pesto.NewClientWithConfig(pesto.Config{Token: "another-token", HttpClient: &http.Client{Transport: recorder}})
+			if err != nil {
+				t.Fatalf("creating client: %s", err.Error())
+			}
+
+			var outputs []string
+			for i := 0; i < 3; i++ {
+				response, err := client.Execute(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionLatest, Code: "any"})
+				if err != nil {
+					outputs = append(outputs, "error")
+					continue
+				}
+				outputs = append(outputs, response.Runtime.Stdout)
+			}
+
+			expected := "first second second"
+			if strict {
+				expected = "first second error"
+			}
+
+			if strings.Join(outputs, " ") != expected {
+				t.Errorf("expecting %q with strict %t, got %q", expected, strict, strings.Join(outputs, " "))
+			}
+		}
+	})
+
+	t.Run("ReplayOrRecord", func(t *testing.T) {
+		recorder, err := pestotest.NewRecorder(pestotest.RecorderConfig{
+			Cassette:  cassette,
+			Mode:      pestotest.ModeReplayOrRecord,
+			Transport: http.DefaultTransport,
+		})
+		if err != nil {
+			t.Fatalf("creating recorder: %s", err.Error())
+		}
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:      "testing-token",
+			BaseURL:    server.BaseURL(),
+			HttpClient: &http.Client{Transport: recorder},
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		for _, code := range []string{"first", "third"} {
+			_, err = client.Execute(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionLatest, Code: code})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+		}
+
+		err = recorder.Save()
+		if err != nil {
+			t.Fatalf("saving cassette: %s", err.Error())
+		}
+
+		_, response, err := execute(t, pestotest.RecorderConfig{Strict: true}, "third")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if response.Runtime.Stdout != "third" {
+			t.Errorf("expecting the new interaction to be recorded, got %q", response.Runtime.Stdout)
+		}
+	})
+}
+
+func TestNewRecorder_Error(t *testing.T) {
+	dir := t.TempDir()
+	malformed := filepath.Join(dir, "malformed.json")
+	err := os.WriteFile(malformed, []byte("{"), 0o600)
+	if err != nil {
+		t.Fatalf("writing cassette: %s", err.Error())
+	}
+
+	unsupported := filepath.Join(dir, "unsupported.json")
+	err = os.WriteFile(unsupported, []byte(`{"version": 2}`), 0o600)
+	if err != nil {
+		t.Fatalf("writing cassette: %s", err.Error())
+	}
+
+	for _, cassette := range []string{filepath.Join(dir, "missing.json"), malformed, unsupported} {
+		_, err := pestotest.NewRecorder(pestotest.RecorderConfig{Cassette: cassette})
+		if err == nil {
+			t.Errorf("expecting an error for %s, got nil", filepath.Base(cassette))
+		}
+	}
+
+	_, err = pestotest.NewRecorder(pestotest.RecorderConfig{Cassette: filepath.Join(dir, "missing.json"), Mode: pestotest.ModeReplayOrRecord})
+	if err != nil {
+		t.Errorf("expecting a missing cassette to be empty, got %v", err)
+	}
+}
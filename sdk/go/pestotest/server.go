@@ -0,0 +1,522 @@
+// Package pestotest provides an in-memory fake of Pesto's API, including the
+// registration service, so code that uses the Go SDK can be tested end to end
+// without a network connection.
+//
+// The fake follows the same authentication rules, status codes and error messages
+// as the real services, but it does not execute any code. The execution result
+// is provided by the ExecuteFunc.
+//
+// Recorder records the interactions with the real API to a cassette instead,
+// and replays them, for tests that need the payloads of the real API. FaultTransport
+// makes the requests fail on purpose, to test the retries and the fallbacks.
+package pestotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+// ExecuteFile is a single file of an ExecuteRequest.
+type ExecuteFile struct {
+	Name       string `json:"name"`
+	Code       string `json:"code"`
+	Entrypoint bool   `json:"entrypoint"`
+}
+
+// ExecuteRequest is the body of the execute request, as received by the fake.
+type ExecuteRequest struct {
+	Language       string        `json:"language"`
+	Version        string        `json:"version"`
+	Code           *string       `json:"code,omitempty"`
+	Files          []ExecuteFile `json:"files,omitempty"`
+	CompileTimeout *float64      `json:"compileTimeout,omitempty"`
+	RunTimeout     *float64      `json:"runTimeout,omitempty"`
+	MemoryLimit    *float64      `json:"memoryLimit,omitempty"`
+}
+
+// ExecuteFunc produces the result of an execute request. The Language and Version
+// of the returned response are overwritten with the resolved runtime.
+type ExecuteFunc func(runtime pesto.Runtime, request ExecuteRequest) pesto.CodeResponse
+
+// Registrant is a user on the registration waiting list.
+type Registrant struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Building string `json:"building,omitempty"`
+	Calls    int64  `json:"calls"`
+}
+
+// TokenState is the state of a token known to the fake.
+type TokenState struct {
+	Email        string
+	MonthlyLimit int64
+	Used         int64
+	Revoked      bool
+	// ExpiresAt is only set for trial tokens.
+	ExpiresAt time.Time
+}
+
+// Option configures the Server.
+type Option func(s *Server)
+
+// WithRuntimes replaces the default runtimes of the Server.
+// The first runtime of each language is treated as its latest version.
+func WithRuntimes(runtimes ...pesto.Runtime) Option {
+	return func(s *Server) {
+		s.runtimes = runtimes
+	}
+}
+
+// WithExecuteFunc replaces the default ExecuteFunc, which returns an empty output.
+func WithExecuteFunc(fn ExecuteFunc) Option {
+	return func(s *Server) {
+		s.executeFunc = fn
+	}
+}
+
+// WithToken registers a token with the given monthly limit on the Server.
+func WithToken(token string, monthlyLimit int64) Option {
+	return func(s *Server) {
+		s.tokens[token] = &TokenState{MonthlyLimit: monthlyLimit}
+	}
+}
+
+// WithQuotaHeaders makes the Server send the monthly quota headers
+// (see pesto.HeaderMonthlyLimit) on every authenticated response.
+// Without it, the Server sends no quota headers, like Pesto's API.
+func WithQuotaHeaders() Option {
+	return func(s *Server) {
+		s.quotaHeaders = true
+	}
+}
+
+// DefaultRuntimes are the runtimes served by the Server unless WithRuntimes is used.
+var DefaultRuntimes = []pesto.Runtime{
+	{Language: string(pesto.LanguagePython), Version: string(pesto.VersionPython), Aliases: []string{"python", "py"}, Compiled: false},
+	{Language: string(pesto.LanguageGo), Version: string(pesto.VersionGo), Aliases: []string{"go", "golang"}, Compiled: true},
+	{Language: string(pesto.LanguageC), Version: string(pesto.VersionC), Aliases: []string{"c"}, Compiled: true},
+}
+
+// trialMonthlyLimit and trialDuration mirror the trial tokens of the registration service.
+const (
+	trialMonthlyLimit = 10
+	trialDuration     = time.Hour * 24
+)
+
+// Server is a fake of Pesto's API running on a local httptest.Server.
+type Server struct {
+	*httptest.Server
+
+	runtimes     []pesto.Runtime
+	executeFunc  ExecuteFunc
+	quotaHeaders bool
+
+	mu          sync.Mutex
+	tokens      map[string]*TokenState
+	waitingList []Registrant
+	trials      int
+}
+
+// NewServer starts and returns a new Server. The caller should call Close when finished.
+func NewServer(options ...Option) *Server {
+	s := &Server{
+		runtimes: DefaultRuntimes,
+		executeFunc: func(runtime pesto.Runtime, request ExecuteRequest) pesto.CodeResponse {
+			return pesto.CodeResponse{}
+		},
+		tokens: make(map[string]*TokenState),
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/ping", s.authenticate(http.MethodGet, s.handlePing))
+	mux.HandleFunc("/api/list-runtimes", s.authenticate(http.MethodGet, s.handleListRuntimes))
+	mux.HandleFunc("/api/execute", s.authenticate(http.MethodPost, s.handleExecute))
+	mux.HandleFunc("/api/register", s.method(http.MethodPost, s.handleRegister))
+	mux.HandleFunc("/api/pending", s.method(http.MethodGet, s.handlePending))
+	mux.HandleFunc("/api/approve", s.method(http.MethodPut, s.handleApprove))
+	mux.HandleFunc("/api/revoke", s.method(http.MethodPut, s.handleRevoke))
+	mux.HandleFunc("/api/trial", s.method(http.MethodPost, s.handleTrial))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not found"})
+	})
+
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// BaseURL returns the URL of the Server, to be used as pesto.Config.BaseURL.
+func (s *Server) BaseURL() *url.URL {
+	baseURL, _ := url.Parse(s.URL)
+	return baseURL
+}
+
+// AddToken registers a token with the given monthly limit on the Server.
+func (s *Server) AddToken(token string, monthlyLimit int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[token] = &TokenState{MonthlyLimit: monthlyLimit}
+}
+
+// Token returns the state of the token, and false if the token is not registered.
+func (s *Server) Token(token string) (TokenState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.tokens[token]
+	if !ok {
+		return TokenState{}, false
+	}
+
+	return *state, true
+}
+
+// WaitingList returns the users that are waiting for their registration to be approved.
+func (s *Server) WaitingList() []Registrant {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Registrant{}, s.waitingList...)
+}
+
+func (s *Server) method(method string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// authenticate mirrors the auth service, which guards every rce endpoint
+// and counts each request against the token's monthly limit. Like the auth
+// service, it only refuses a request once the token was used more times than
+// its limit, so a token gets one request beyond its MonthlyLimit.
+func (s *Server) authenticate(method string, next http.HandlerFunc) http.HandlerFunc {
+	return s.method(method, func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Pesto-Token")
+		if token == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Token must be supplied"})
+			return
+		}
+
+		s.mu.Lock()
+		state, ok := s.tokens[token]
+		if ok && !state.ExpiresAt.IsZero() && time.Now().After(state.ExpiresAt) {
+			delete(s.tokens, token)
+			ok = false
+		}
+
+		switch {
+		case !ok:
+			s.mu.Unlock()
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Token not registered"})
+			return
+		case state.Revoked:
+			s.mu.Unlock()
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Token has been revoked"})
+			return
+		case state.Used > state.MonthlyLimit:
+			s.writeQuotaHeaders(w, *state)
+			s.mu.Unlock()
+			writeJSON(w, http.StatusTooManyRequests, map[string]string{"message": "Monthly limit exceeded"})
+			return
+		}
+
+		state.Used++
+		s.writeQuotaHeaders(w, *state)
+		s.mu.Unlock()
+
+		next(w, r)
+	})
+}
+
+func (s *Server) writeQuotaHeaders(w http.ResponseWriter, state TokenState) {
+	if !s.quotaHeaders {
+		return
+	}
+
+	now := time.Now().UTC()
+	reset := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The token gets one request beyond its limit, which is not advertised.
+	remaining := state.MonthlyLimit - state.Used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	w.Header().Set(pesto.HeaderMonthlyLimit, strconv.FormatInt(state.MonthlyLimit, 10))
+	w.Header().Set(pesto.HeaderMonthlyUsed, strconv.FormatInt(state.Used, 10))
+	w.Header().Set(pesto.HeaderMonthlyRemaining, strconv.FormatInt(remaining, 10))
+	w.Header().Set(pesto.HeaderMonthlyReset, strconv.FormatInt(reset.Unix(), 10))
+}
+
+func (s *Server) handlePing(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, pesto.PingResponse{Message: "OK"})
+}
+
+func (s *Server) handleListRuntimes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, pesto.RuntimeResponse{Runtime: s.runtimes})
+}
+
+func (s *Server) handleExecute(w http.ResponseWriter, r *http.Request) {
+	var request ExecuteRequest
+	var formProblems []string
+	var err error
+	if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		formProblems, err = decodeExecuteForm(r, &request)
+	} else {
+		err = json.NewDecoder(r.Body).Decode(&request)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"msg": "Invalid body content with the Content-Type header specification"})
+		return
+	}
+
+	if problems := append(validateExecuteRequest(&request), formProblems...); len(problems) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "Missing parameters: " + strings.Join(problems, ", ")})
+		return
+	}
+
+	if request.Code == nil && request.Files == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "Both code and files must not be empty"})
+		return
+	}
+
+	if request.Code != nil && *request.Code == "" && request.Files != nil && len(request.Files) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "Both code and files must not be empty"})
+		return
+	}
+
+	runtime, ok := s.findRuntime(request.Language, request.Version)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "Runtime not found"})
+		return
+	}
+
+	response := s.executeFunc(runtime, request)
+	response.Language = runtime.Language
+	response.Version = runtime.Version
+	writeJSON(w, http.StatusOK, response)
+}
+
+// decodeExecuteForm decodes a form body the way the real API does. Every value
+// of a form body is a string, so the numbers are reported as problems, and there
+// is no way to send files.
+func decodeExecuteForm(r *http.Request, request *ExecuteRequest) ([]string, error) {
+	err := r.ParseForm()
+	if err != nil {
+		return nil, err
+	}
+
+	request.Language = r.PostForm.Get("language")
+	request.Version = r.PostForm.Get("version")
+	if r.PostForm.Has("code") {
+		code := r.PostForm.Get("code")
+		request.Code = &code
+	}
+
+	var problems []string
+	for _, key := range []string{"compileTimeout", "runTimeout", "memoryLimit"} {
+		if r.PostForm.Has(key) {
+			problems = append(problems, "Expected number, received string")
+		}
+	}
+
+	return problems, nil
+}
+
+// validateExecuteRequest mirrors the validation schema of the execute endpoint,
+// returning the problems with the same wording as the schema library.
+func validateExecuteRequest(request *ExecuteRequest) []string {
+	var problems []string
+	if request.Language == "" {
+		problems = append(problems, "String must contain at least 1 character(s)")
+	}
+
+	if request.Version == "" {
+		request.Version = "latest"
+	}
+
+	for _, file := range request.Files {
+		if file.Name == "" || file.Code == "" {
+			problems = append(problems, "String must contain at least 1 character(s)")
+		}
+	}
+
+	for _, timeout := range []*float64{request.CompileTimeout, request.RunTimeout} {
+		if timeout != nil && *timeout > 30_000 {
+			problems = append(problems, "Number must be less than or equal to 30000")
+		}
+	}
+
+	if request.MemoryLimit != nil && *request.MemoryLimit > 1024*1024*1024 {
+		problems = append(problems, "Number must be less than or equal to 1073741824")
+	}
+
+	return problems
+}
+
+func (s *Server) findRuntime(language string, version string) (pesto.Runtime, bool) {
+	for _, runtime := range s.runtimes {
+		if runtime.Language != language {
+			continue
+		}
+
+		if version == "latest" || runtime.Version == version {
+			return runtime, true
+		}
+	}
+
+	return pesto.Runtime{}, false
+}
+
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var registrant struct {
+		Name     *string `json:"name"`
+		Email    *string `json:"email"`
+		Building *string `json:"building"`
+		Calls    *int64  `json:"calls"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&registrant)
+	if err != nil || registrant.Name == nil || registrant.Email == nil || registrant.Calls == nil {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte("Failed to deserialize the JSON body into the target type"))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.waitingList {
+		if user.Email == *registrant.Email {
+			writeJSON(w, http.StatusAccepted, map[string]string{"message": "Accepted"})
+			return
+		}
+	}
+
+	user := Registrant{Name: *registrant.Name, Email: *registrant.Email, Calls: *registrant.Calls}
+	if registrant.Building != nil {
+		user.Building = *registrant.Building
+	}
+	s.waitingList = append(s.waitingList, user)
+
+	writeJSON(w, http.StatusCreated, map[string]string{"message": "Created"})
+}
+
+func (s *Server) handlePending(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.WaitingList())
+}
+
+func (s *Server) handleApprove(w http.ResponseWriter, r *http.Request) {
+	var approval struct {
+		Token        string `json:"token"`
+		Email        string `json:"email"`
+		MonthlyLimit int64  `json:"limit"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&approval)
+	if err != nil {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte("Failed to deserialize the JSON body into the target type"))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tokens[approval.Token]; ok && approval.Token != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "Token already exists"})
+		return
+	}
+
+	index := -1
+	for i, user := range s.waitingList {
+		if user.Email == approval.Email {
+			index = i
+			break
+		}
+	}
+
+	if index < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "Email does not exists"})
+		return
+	}
+
+	if approval.Token == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "User seemed to be approved already"})
+		return
+	}
+
+	s.tokens[approval.Token] = &TokenState{Email: approval.Email, MonthlyLimit: approval.MonthlyLimit}
+	s.waitingList = append(s.waitingList[:index], s.waitingList[index+1:]...)
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "OK"})
+}
+
+func (s *Server) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token string `json:"token"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte("Failed to deserialize the JSON body into the target type"))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.tokens[body.Token]
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "User does not exists"})
+		return
+	}
+
+	if state.Revoked {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "User seemed to be revoked already"})
+		return
+	}
+
+	state.Revoked = true
+	writeJSON(w, http.StatusOK, map[string]string{"message": "OK"})
+}
+
+func (s *Server) handleTrial(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.trials++
+	token := fmt.Sprintf("TRIAL-%058d", s.trials)
+	s.tokens[token] = &TokenState{
+		Email:        fmt.Sprintf("trial-%d@pesto.teknologiumum.com", s.trials),
+		MonthlyLimit: trialMonthlyLimit,
+		ExpiresAt:    time.Now().Add(trialDuration),
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,97 @@
+package pestotest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/pestotest"
+)
+
+func TestServer(t *testing.T) {
+	server := pestotest.NewServer(
+		pestotest.WithToken("testing-token", 5),
+		pestotest.WithExecuteFunc(func(runtime pesto.Runtime, request pestotest.ExecuteRequest) pesto.CodeResponse {
+			return pesto.CodeResponse{Runtime: pesto.Output{Stdout: "Hello World", Output: "Hello World"}}
+		}),
+	)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	t.Run("Execute", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{Token: "testing-token", BaseURL: server.BaseURL()})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		response, err := client.Execute(ctx, pesto.CodeRequest{
+			Language: pesto.LanguagePython,
+			Version:  pesto.VersionLatest,
+			Code:     "print('Hello World')",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if response.Version != string(pesto.VersionPython) {
+			t.Errorf("expecting latest version to resolve to %s, got %s", pesto.VersionPython, response.Version)
+		}
+
+		if response.Runtime.Stdout != "Hello World" {
+			t.Errorf("expecting response.Runtime.Stdout to be 'Hello World', got %s", response.Runtime.Stdout)
+		}
+
+		_, err = client.Execute(ctx, pesto.CodeRequest{Language: "Rust", Version: "1.64.0", Code: "fn main() {}"})
+		if !errors.Is(err, pesto.ErrRuntimeNotFound) {
+			t.Errorf("expecting an error of ErrRuntimeNotFound, got %v", err)
+		}
+
+		_, err = client.Execute(ctx, pesto.CodeRequest{Version: "1.64.0", Code: "fn main() {}"})
+		if !errors.Is(err, pesto.ErrMissingParameters) {
+			t.Errorf("expecting an error of ErrMissingParameters, got %v", err)
+		}
+	})
+
+	t.Run("Authentication", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{Token: "unknown-token", BaseURL: server.BaseURL()})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		_, err = client.Ping(ctx)
+		if !errors.Is(err, pesto.ErrTokenNotRegistered) {
+			t.Errorf("expecting an error of ErrTokenNotRegistered, got %v", err)
+		}
+	})
+
+	t.Run("MonthlyLimit", func(t *testing.T) {
+		server.AddToken("limited-token", 2)
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{Token: "limited-token", BaseURL: server.BaseURL()})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		// The auth service lets one request beyond the limit through.
+		for i := 0; i < 3; i++ {
+			_, err := client.ListRuntimes(ctx)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+		}
+
+		_, err = client.ListRuntimes(ctx)
+		if !errors.Is(err, pesto.ErrMonthlyLimitExceeded) {
+			t.Errorf("expecting an error of ErrMonthlyLimitExceeded, got %v", err)
+		}
+
+		state, _ := server.Token("limited-token")
+		if state.Used != 3 {
+			t.Errorf("expecting the token to be used 3 times, got %d", state.Used)
+		}
+	})
+}
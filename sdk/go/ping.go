@@ -2,10 +2,6 @@ package pesto
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"io"
 	"net/http"
 )
 
@@ -17,40 +13,10 @@ type PingResponse struct {
 // To make the function work properly, put a context with deadline (or timeout), or provide
 // DefaultTimeout a value when creating the client.
 func (c *Client) Ping(ctx context.Context) (PingResponse, error) {
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL.JoinPath("/api/ping").String(), nil)
+	result, err := do[PingResponse](ctx, c, http.MethodGet, "/api/ping", nil)
 	if err != nil {
-		return PingResponse{}, fmt.Errorf("creating request: %w", err)
+		return PingResponse{}, err
 	}
 
-	response, err := c.sendRequest(ctx, request)
-	if err != nil {
-		return PingResponse{}, fmt.Errorf("sending request: %w", err)
-	}
-
-	if response.StatusCode != 200 {
-		var errResponse errorResponse
-		// HACK: the error is intentionally not handled, we wanted to leave the empty errorResponse struct
-		// if there is any non-json response being sent from the server
-		_ = json.NewDecoder(response.Body).Decode(&errResponse)
-
-		err = response.Body.Close()
-		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrClosedPipe) && !errors.Is(err, http.ErrBodyReadAfterClose) {
-			return PingResponse{}, fmt.Errorf("closing response body: %w", err)
-		}
-
-		return PingResponse{}, c.handleErrorCode(response.StatusCode, errResponse)
-	}
-
-	var pingResponse PingResponse
-	err = json.NewDecoder(response.Body).Decode(&pingResponse)
-	if err != nil {
-		return PingResponse{}, fmt.Errorf("reading json body: %w", err)
-	}
-
-	err = response.Body.Close()
-	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrClosedPipe) && !errors.Is(err, http.ErrBodyReadAfterClose) {
-		return PingResponse{}, fmt.Errorf("closing response body: %w", err)
-	}
-
-	return pingResponse, nil
+	return result.body, nil
 }
@@ -0,0 +1,260 @@
+package pesto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// tokenKey is the context key for the per-request token override.
+type tokenKey struct{}
+
+// WithToken returns a copy of ctx that makes the Client send the given token
+// instead of the one it was created with. This allows a single Client, and
+// its connection pool, to be shared across multiple Pesto tokens.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// TenantUsage contains the usage statistics of a single tenant on the ClientPool.
+type TenantUsage struct {
+	// Requests is the amount of requests sent on behalf of the tenant.
+	Requests int64
+	// Errors is the amount of those requests that returned an error.
+	Errors int64
+	// LastUsed is the time of the latest request.
+	LastUsed time.Time
+	// Disabled is true when the tenant's token was found to be revoked or not registered.
+	Disabled bool
+	// DisabledReason is the error that disabled the tenant,
+	// either ErrTokenRevoked or ErrTokenNotRegistered.
+	DisabledReason error
+}
+
+type tenant struct {
+	token string
+	usage TenantUsage
+}
+
+// ClientPool shares a single Client, along with its HTTP transport and
+// connection pool, across multiple tenants that each have their own token.
+//
+// Tenants whose token turns out to be revoked or not registered are disabled,
+// and every following request on their behalf fails with ErrTenantDisabled
+// without reaching Pesto's API, until their token is rotated.
+//
+// ClientPool is safe for concurrent use.
+type ClientPool struct {
+	client *Client
+
+	mu      sync.RWMutex
+	tenants map[string]*tenant
+}
+
+// NewClientPool creates a ClientPool with the given Config struct.
+// Config.Token is optional, since each tenant provides its own token.
+func NewClientPool(config Config) *ClientPool {
+	return &ClientPool{
+		client:  newClient(config),
+		tenants: make(map[string]*tenant),
+	}
+}
+
+// Client returns the underlying Client that is shared by every tenant.
+func (p *ClientPool) Client() *Client {
+	return p.client
+}
+
+// Close closes the underlying Client. See Client.Close.
+func (p *ClientPool) Close(ctx context.Context) error {
+	return p.client.Close(ctx)
+}
+
+// Register adds a tenant with the given token to the pool. If the tenant
+// is already registered, its token is replaced and its usage is kept.
+// If token is not provided, it will return ErrEmptyToken error.
+func (p *ClientPool) Register(name string, token string) error {
+	if token == "" {
+		return ErrEmptyToken
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if t, ok := p.tenants[name]; ok {
+		if t.token != token {
+			p.client.forgetQuota(t.token)
+		}
+		t.token = token
+		t.usage.Disabled = false
+		t.usage.DisabledReason = nil
+		return nil
+	}
+
+	p.tenants[name] = &tenant{token: token}
+	return nil
+}
+
+// RotateToken replaces the token of an existing tenant, and enables the tenant
+// again if it was disabled. Requests that are already in flight keep using the old token.
+func (p *ClientPool) RotateToken(name string, token string) error {
+	if token == "" {
+		return ErrEmptyToken
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t, ok := p.tenants[name]
+	if !ok {
+		return ErrTenantNotFound
+	}
+
+	if t.token != token {
+		p.client.forgetQuota(t.token)
+	}
+	t.token = token
+	t.usage.Disabled = false
+	t.usage.DisabledReason = nil
+	return nil
+}
+
+// Remove removes the tenant from the pool.
+func (p *ClientPool) Remove(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if t, ok := p.tenants[name]; ok {
+		p.client.forgetQuota(t.token)
+		delete(p.tenants, name)
+	}
+}
+
+// Usage returns the usage statistics of the tenant.
+func (p *ClientPool) Usage(name string) (TenantUsage, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	t, ok := p.tenants[name]
+	if !ok {
+		return TenantUsage{}, ErrTenantNotFound
+	}
+
+	return t.usage, nil
+}
+
+// Usages returns the usage statistics of every tenant, keyed by the tenant name.
+func (p *ClientPool) Usages() map[string]TenantUsage {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	usages := make(map[string]TenantUsage, len(p.tenants))
+	for name, t := range p.tenants {
+		usages[name] = t.usage
+	}
+
+	return usages
+}
+
+// Quota returns the quota of the tenant's token from the latest response that
+// carried the quota headers. See Client.Quota.
+func (p *ClientPool) Quota(name string) (Quota, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	t, ok := p.tenants[name]
+	if !ok {
+		return Quota{}, ErrTenantNotFound
+	}
+
+	return p.client.quotaOf(t.token), nil
+}
+
+// Execute calls Client.Execute on behalf of the tenant.
+func (p *ClientPool) Execute(ctx context.Context, name string, codeRequest CodeRequest) (CodeResponse, error) {
+	token, err := p.acquire(name)
+	if err != nil {
+		return CodeResponse{}, err
+	}
+
+	response, err := p.client.Execute(WithToken(ctx, token), codeRequest)
+	p.release(name, token, err)
+	return response, err
+}
+
+// Ping calls Client.Ping on behalf of the tenant.
+func (p *ClientPool) Ping(ctx context.Context, name string) (PingResponse, error) {
+	token, err := p.acquire(name)
+	if err != nil {
+		return PingResponse{}, err
+	}
+
+	response, err := p.client.Ping(WithToken(ctx, token))
+	p.release(name, token, err)
+	return response, err
+}
+
+// ListRuntimes calls Client.ListRuntimes on behalf of the tenant.
+func (p *ClientPool) ListRuntimes(ctx context.Context, name string) (RuntimeResponse, error) {
+	token, err := p.acquire(name)
+	if err != nil {
+		return RuntimeResponse{}, err
+	}
+
+	response, err := p.client.ListRuntimes(WithToken(ctx, token))
+	p.release(name, token, err)
+	return response, err
+}
+
+// acquire returns the current token of the tenant, and counts the request.
+func (p *ClientPool) acquire(name string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t, ok := p.tenants[name]
+	if !ok {
+		return "", ErrTenantNotFound
+	}
+
+	if t.usage.Disabled {
+		return "", fmt.Errorf("%w: %s", ErrTenantDisabled, t.usage.DisabledReason.Error())
+	}
+
+	t.usage.Requests++
+	t.usage.LastUsed = time.Now()
+	return t.token, nil
+}
+
+// release records the outcome of the request, and disables the tenant if the
+// token that was used is revoked or not registered. If the token was rotated
+// while the request was in flight, the tenant is left untouched.
+func (p *ClientPool) release(name string, token string, err error) {
+	if err == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t, ok := p.tenants[name]
+	if !ok {
+		return
+	}
+
+	t.usage.Errors++
+
+	if t.token != token {
+		return
+	}
+
+	switch {
+	case errors.Is(err, ErrTokenRevoked):
+		t.usage.Disabled = true
+		t.usage.DisabledReason = ErrTokenRevoked
+	case errors.Is(err, ErrTokenNotRegistered):
+		t.usage.Disabled = true
+		t.usage.DisabledReason = ErrTokenNotRegistered
+	}
+}
@@ -0,0 +1,156 @@
+package pesto_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+func TestClientPool(t *testing.T) {
+	codeRequest := pesto.CodeRequest{
+		Language: pesto.LanguagePython,
+		Version:  pesto.VersionPython,
+		Code:     "print('Hello World')",
+	}
+
+	t.Run("Happy", func(t *testing.T) {
+		pool := pesto.NewClientPool(pesto.Config{BaseURL: happyMockServerURL})
+
+		err := pool.Register("team-a", token)
+		if err != nil {
+			t.Fatalf("registering tenant: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		response, err := pool.Execute(ctx, "team-a", codeRequest)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+
+		if response.Runtime.Stdout != "Hello World" {
+			t.Errorf("exepcted response.Runtime.Stdout to be 'Hello World', instead got %s", response.Runtime.Stdout)
+		}
+
+		_, err = pool.Ping(ctx, "team-a")
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+
+		_, err = pool.ListRuntimes(ctx, "team-a")
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+
+		usage, err := pool.Usage("team-a")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if usage.Requests != 3 || usage.Errors != 0 {
+			t.Errorf("expecting 3 requests and 0 errors, got %d requests and %d errors", usage.Requests, usage.Errors)
+		}
+
+		if usage.LastUsed.IsZero() {
+			t.Errorf("expecting usage.LastUsed to be set")
+		}
+	})
+
+	t.Run("EmptyToken", func(t *testing.T) {
+		pool := pesto.NewClientPool(pesto.Config{BaseURL: happyMockServerURL})
+
+		err := pool.Register("team-a", "")
+		if !errors.Is(err, pesto.ErrEmptyToken) {
+			t.Errorf("expecting error of ErrEmptyToken, got %v", err)
+		}
+	})
+
+	t.Run("TenantNotFound", func(t *testing.T) {
+		pool := pesto.NewClientPool(pesto.Config{BaseURL: happyMockServerURL})
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err := pool.Execute(ctx, "nobody", codeRequest)
+		if !errors.Is(err, pesto.ErrTenantNotFound) {
+			t.Errorf("expecting error of ErrTenantNotFound, got %v", err)
+		}
+
+		err = pool.RotateToken("nobody", token)
+		if !errors.Is(err, pesto.ErrTenantNotFound) {
+			t.Errorf("expecting error of ErrTenantNotFound, got %v", err)
+		}
+	})
+
+	t.Run("DisabledAndRotated", func(t *testing.T) {
+		pool := pesto.NewClientPool(pesto.Config{BaseURL: happyMockServerURL})
+
+		_ = pool.Register("team-a", token)
+		_ = pool.Register("team-b", "invalid-token")
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err := pool.Execute(ctx, "team-b", codeRequest)
+		if !errors.Is(err, pesto.ErrTokenNotRegistered) {
+			t.Errorf("expecting error of ErrTokenNotRegistered, got %v", err)
+		}
+
+		_, err = pool.Execute(ctx, "team-b", codeRequest)
+		if !errors.Is(err, pesto.ErrTenantDisabled) {
+			t.Errorf("expecting error of ErrTenantDisabled, got %v", err)
+		}
+
+		usage, _ := pool.Usage("team-b")
+		if !usage.Disabled || !errors.Is(usage.DisabledReason, pesto.ErrTokenNotRegistered) {
+			t.Errorf("expecting team-b to be disabled because of ErrTokenNotRegistered, got %+v", usage)
+		}
+
+		if usage.Requests != 1 || usage.Errors != 1 {
+			t.Errorf("expecting 1 request and 1 error, got %d requests and %d errors", usage.Requests, usage.Errors)
+		}
+
+		// Other tenants are not affected.
+		_, err = pool.Execute(ctx, "team-a", codeRequest)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+
+		err = pool.RotateToken("team-b", token)
+		if err != nil {
+			t.Fatalf("rotating token: %s", err.Error())
+		}
+
+		_, err = pool.Execute(ctx, "team-b", codeRequest)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+
+		usages := pool.Usages()
+		if len(usages) != 2 || usages["team-b"].Disabled {
+			t.Errorf("expecting team-b to be enabled again, got %+v", usages)
+		}
+	})
+
+	t.Run("WithToken", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   "invalid-token",
+			BaseURL: happyMockServerURL,
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err = client.Execute(pesto.WithToken(ctx, token), codeRequest)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+	})
+}
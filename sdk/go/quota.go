@@ -0,0 +1,156 @@
+package pesto
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Quota headers that are parsed from every response.
+//
+// Pesto's API does not send these headers yet, neither the auth service nor
+// the rce service sets them, so the quota is only known when the requests go
+// through a gateway that adds them, or to the pestotest server with
+// pestotest.WithQuotaHeaders. Against Pesto's API, every Quota is empty.
+const (
+	HeaderMonthlyLimit     = "X-Pesto-Monthly-Limit"
+	HeaderMonthlyUsed      = "X-Pesto-Monthly-Used"
+	HeaderMonthlyRemaining = "X-Pesto-Monthly-Remaining"
+	HeaderMonthlyReset     = "X-Pesto-Monthly-Reset"
+	HeaderRateLimit        = "X-RateLimit-Limit"
+	HeaderRateRemaining    = "X-RateLimit-Remaining"
+	HeaderRateReset        = "X-RateLimit-Reset"
+	HeaderRetryAfter       = "Retry-After"
+)
+
+// Quota describes the request allowance of the token, as reported by the quota
+// headers of the response. Each field is left empty if the server does not send
+// the corresponding header.
+type Quota struct {
+	// MonthlyLimit is the amount of requests the token may send each month.
+	MonthlyLimit int64
+	// MonthlyUsed is the amount of requests the token has sent this month.
+	MonthlyUsed int64
+	// MonthlyRemaining is the amount of requests the token may still send this month.
+	MonthlyRemaining int64
+	// MonthlyReset is the time the monthly counter is reset.
+	MonthlyReset time.Time
+	// RateLimit is the amount of requests allowed within the current rate-limit window.
+	RateLimit int64
+	// RateRemaining is the amount of requests left within the current rate-limit window.
+	RateRemaining int64
+	// RateReset is the time the current rate-limit window ends. It is also set
+	// from the Retry-After header when the request was rate limited.
+	RateReset time.Time
+	// ObservedAt is the time the headers were received.
+	ObservedAt time.Time
+}
+
+// Known reports whether the server sent any of the quota headers.
+func (q Quota) Known() bool {
+	return !q.ObservedAt.IsZero()
+}
+
+// parseQuota reads the quota headers from the response header.
+func parseQuota(header http.Header, now time.Time) Quota {
+	var quota Quota
+	known := false
+
+	parseInt := func(key string, target *int64) {
+		value, err := strconv.ParseInt(header.Get(key), 10, 64)
+		if err != nil {
+			return
+		}
+
+		*target = value
+		known = true
+	}
+
+	parseTime := func(key string, target *time.Time) {
+		value := header.Get(key)
+		if value == "" {
+			return
+		}
+
+		if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+			// Values that are too small to be a unix timestamp are the
+			// amount of seconds until the reset, like the Retry-After header.
+			if seconds < 1_000_000_000 {
+				*target = now.Add(time.Duration(seconds) * time.Second)
+			} else {
+				*target = time.Unix(seconds, 0)
+			}
+			known = true
+			return
+		}
+
+		if t, err := http.ParseTime(value); err == nil {
+			*target = t
+			known = true
+		}
+	}
+
+	parseInt(HeaderMonthlyLimit, &quota.MonthlyLimit)
+	parseInt(HeaderMonthlyUsed, &quota.MonthlyUsed)
+	parseInt(HeaderMonthlyRemaining, &quota.MonthlyRemaining)
+	parseTime(HeaderMonthlyReset, &quota.MonthlyReset)
+	parseInt(HeaderRateLimit, &quota.RateLimit)
+	parseInt(HeaderRateRemaining, &quota.RateRemaining)
+	parseTime(HeaderRateReset, &quota.RateReset)
+	parseTime(HeaderRetryAfter, &quota.RateReset)
+
+	if !known {
+		return Quota{}
+	}
+
+	if header.Get(HeaderMonthlyRemaining) == "" && quota.MonthlyLimit > 0 {
+		quota.MonthlyRemaining = quota.MonthlyLimit - quota.MonthlyUsed
+	}
+
+	quota.ObservedAt = now
+	return quota
+}
+
+// observeQuota stores the quota of the response for the token of the request if the
+// response carried the quota headers, and returns the quota of the response.
+func (c *Client) observeQuota(ctx context.Context, response *http.Response) Quota {
+	quota := parseQuota(response.Header, time.Now())
+	if quota.Known() {
+		c.quotas.Store(c.tokenFor(ctx), quota)
+	}
+
+	return quota
+}
+
+// forgetQuota drops the quota of a token that is replaced or removed,
+// so the quotas of the old tokens don't pile up.
+func (c *Client) forgetQuota(token string) {
+	c.quotas.Delete(token)
+}
+
+// quotaOf returns the quota from the latest response for the token that carried the quota headers.
+func (c *Client) quotaOf(token string) Quota {
+	quota, ok := c.quotas.Load(token)
+	if !ok {
+		return Quota{}
+	}
+
+	return quota.(Quota)
+}
+
+// Quota returns the quota of the client's token from the latest response that
+// carried the quota headers. Requests sent with another token through WithToken
+// don't change it. Use Quota.Known to check whether any quota headers have been received.
+func (c *Client) Quota() Quota {
+	return c.quotaOf(*c.token.Load())
+}
+
+// Usage calls the ping endpoint and returns the quota reported on its response,
+// which is also returned along with ErrServerRateLimited or ErrMonthlyLimitExceeded.
+// The token of the request is the one that was set through WithToken, or the client's token.
+// Note that the ping itself counts as a request against the monthly quota.
+func (c *Client) Usage(ctx context.Context) (Quota, error) {
+	result, err := do[PingResponse](ctx, c, http.MethodGet, "/api/ping", nil)
+	return result.quota, err
+}
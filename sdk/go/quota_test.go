@@ -0,0 +1,269 @@
+package pesto_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/pestotest"
+)
+
+func TestClient_Quota(t *testing.T) {
+	t.Run("NoHeaders", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: happyMockServerURL,
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		quota, err := client.Usage(ctx)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+
+		if quota.Known() {
+			t.Errorf("expecting quota to be unknown, got %+v", quota)
+		}
+	})
+
+	t.Run("MonthlyHeaders", func(t *testing.T) {
+		server := pestotest.NewServer(pestotest.WithToken(token, 3), pestotest.WithQuotaHeaders())
+		defer server.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: server.BaseURL(),
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		response, err := client.Execute(ctx, pesto.CodeRequest{
+			Language: pesto.LanguagePython,
+			Version:  pesto.VersionPython,
+			Code:     "print('Hello World')",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if !response.Quota.Known() {
+			t.Fatalf("expecting response.Quota to be known")
+		}
+
+		if response.Quota.MonthlyLimit != 3 || response.Quota.MonthlyUsed != 1 || response.Quota.MonthlyRemaining != 2 {
+			t.Errorf("unexpected quota: %+v", response.Quota)
+		}
+
+		if !response.Quota.MonthlyReset.After(time.Now()) {
+			t.Errorf("expecting monthly reset to be in the future, got %s", response.Quota.MonthlyReset)
+		}
+
+		quota, err := client.Usage(ctx)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+
+		if quota.MonthlyRemaining != 1 {
+			t.Errorf("expecting 1 remaining request, got %d", quota.MonthlyRemaining)
+		}
+
+		// Like the auth service, the fake lets one request beyond the limit through.
+		_, _ = client.Ping(ctx)
+		_, _ = client.Ping(ctx)
+		_, err = client.Ping(ctx)
+		if !errors.Is(err, pesto.ErrMonthlyLimitExceeded) {
+			t.Errorf("expecting an error of ErrMonthlyLimitExceeded, got %v", err)
+		}
+
+		if client.Quota().MonthlyRemaining != 0 {
+			t.Errorf("expecting 0 remaining requests, got %d", client.Quota().MonthlyRemaining)
+		}
+	})
+
+	t.Run("RateLimitHeaders", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(pesto.HeaderRateLimit, "100")
+			w.Header().Set(pesto.HeaderRateRemaining, "0")
+			w.Header().Set(pesto.HeaderRetryAfter, "30")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"message":"Too many requests"}`))
+		}))
+		defer server.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: mustParseURL(t, server.URL),
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		quota, err := client.Usage(ctx)
+		if !errors.Is(err, pesto.ErrServerRateLimited) {
+			t.Errorf("expecting an error of ErrServerRateLimited, got %v", err)
+		}
+
+		if quota.RateLimit != 100 || quota.RateRemaining != 0 {
+			t.Errorf("unexpected quota: %+v", quota)
+		}
+
+		untilReset := time.Until(quota.RateReset)
+		if untilReset <= 0 || untilReset > time.Second*30 {
+			t.Errorf("expecting rate reset within 30 seconds, got %s", untilReset)
+		}
+	})
+
+	t.Run("UnixTimestamp", func(t *testing.T) {
+		reset := time.Now().Add(time.Hour).Truncate(time.Second)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(pesto.HeaderRateReset, strconv.FormatInt(reset.Unix(), 10))
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"message":"OK"}`))
+		}))
+		defer server.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: mustParseURL(t, server.URL),
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		quota, err := client.Usage(ctx)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+
+		if !quota.RateReset.Equal(reset) {
+			t.Errorf("expecting rate reset to be %s, got %s", reset, quota.RateReset)
+		}
+	})
+
+	t.Run("PerToken", func(t *testing.T) {
+		server := pestotest.NewServer(
+			pestotest.WithToken("alice-token", 5),
+			pestotest.WithToken("bob-token", 10),
+			pestotest.WithQuotaHeaders(),
+		)
+		defer server.Close()
+
+		pool := pesto.NewClientPool(pesto.Config{BaseURL: server.BaseURL()})
+		_ = pool.Register("alice", "alice-token")
+		_ = pool.Register("bob", "bob-token")
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err := pool.Ping(ctx, "alice")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		quota, err := pool.Client().Usage(pesto.WithToken(ctx, "bob-token"))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if quota.MonthlyLimit != 10 || quota.MonthlyRemaining != 9 {
+			t.Errorf("expecting the quota of bob, got %+v", quota)
+		}
+
+		quota, err = pool.Quota("alice")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if quota.MonthlyLimit != 5 || quota.MonthlyRemaining != 4 {
+			t.Errorf("expecting the quota of alice, got %+v", quota)
+		}
+
+		_, err = pool.Quota("carol")
+		if !errors.Is(err, pesto.ErrTenantNotFound) {
+			t.Errorf("expecting an error of ErrTenantNotFound, got %v", err)
+		}
+
+		_ = pool.RotateToken("alice", "new-alice-token")
+		quota, err = pool.Quota("alice")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if quota.Known() {
+			t.Errorf("expecting the quota of the old token to be forgotten, got %+v", quota)
+		}
+	})
+
+	t.Run("SetToken", func(t *testing.T) {
+		server := pestotest.NewServer(pestotest.WithToken(token, 3), pestotest.WithQuotaHeaders())
+		defer server.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: server.BaseURL(),
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err = client.Ping(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		_ = client.SetToken("another-token")
+		_ = client.SetToken(token)
+		if client.Quota().Known() {
+			t.Errorf("expecting the quota of the replaced token to be forgotten, got %+v", client.Quota())
+		}
+	})
+
+	t.Run("FakeWithoutQuotaHeaders", func(t *testing.T) {
+		server := pestotest.NewServer(pestotest.WithToken(token, 3))
+		defer server.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: server.BaseURL(),
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err = client.Ping(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if client.Quota().Known() {
+			t.Errorf("expecting the fake to send no quota headers by default, got %+v", client.Quota())
+		}
+	})
+}
@@ -0,0 +1,285 @@
+// Package registration provides client SDK for Pesto's registration service,
+// which issues and manages the tokens that are sent as the X-Pesto-Token header.
+//
+// Only Register and Trial are publicly reachable on https://pesto.teknologiumum.com,
+// the administrative methods (Pending, Approve and Revoke) are meant for
+// self-hosted deployments where the registration service is reachable directly.
+//
+// None of Pesto's services report the status, the monthly usage or the monthly
+// limit of a token. The limit is the one given on Approve, and StatusFromError
+// tells the status of a token from the calls that are made with it.
+package registration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+var (
+	// ErrTokenAlreadyExists indicates the token on the approval is already registered.
+	ErrTokenAlreadyExists = errors.New("token already exists")
+	// ErrEmailNotFound indicates the email on the approval is not on the waiting list.
+	ErrEmailNotFound = errors.New("email not found on the waiting list")
+	// ErrAlreadyApproved indicates the user could not be approved,
+	// most likely because it was approved already.
+	ErrAlreadyApproved = errors.New("user already approved")
+	// ErrAlreadyRemoved indicates the user was approved, but it was removed
+	// from the waiting list by another request already.
+	ErrAlreadyRemoved = errors.New("user already removed from the waiting list")
+	// ErrUserNotFound indicates the token that is being revoked is not registered.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrAlreadyRevoked indicates the token could not be revoked,
+	// most likely because it was revoked already.
+	ErrAlreadyRevoked = errors.New("token already revoked")
+	// ErrInvalidBody indicates the request body was rejected by the registration service.
+	ErrInvalidBody = errors.New("invalid request body")
+)
+
+// Client stores data related to the HTTP request creation.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+}
+
+// Config provides configuration for the registration client.
+type Config struct {
+	// BaseURL states the base URL of the registration service.
+	// Defaults to "https://pesto.teknologiumum.com"
+	BaseURL *url.URL
+	// HttpClient states custom HTTP client to use throughout the SDK.
+	// Defaults to &http.Client{Timeout: time.Minute}
+	HttpClient *http.Client
+}
+
+// NewClient creates a Client with the default configuration.
+func NewClient() *Client {
+	return NewClientWithConfig(Config{})
+}
+
+// NewClientWithConfig creates a Client with the given Config struct.
+// If anything is not provided, it will set a default value.
+func NewClientWithConfig(config Config) *Client {
+	client := &Client{
+		baseURL:    config.BaseURL,
+		httpClient: config.HttpClient,
+	}
+
+	if config.BaseURL == nil {
+		client.baseURL = &url.URL{
+			Scheme: "https",
+			Host:   "pesto.teknologiumum.com",
+		}
+	}
+
+	if config.HttpClient == nil {
+		client.httpClient = &http.Client{Timeout: time.Minute}
+	}
+
+	return client
+}
+
+// Registrant is a user that asks for a Pesto token.
+type Registrant struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	// Building states what the user is building with Pesto.
+	Building string `json:"building,omitempty"`
+	// Calls states the expected amount of monthly calls.
+	Calls int64 `json:"calls"`
+}
+
+// RegisterStatus is the outcome of a registration.
+type RegisterStatus int
+
+const (
+	// StatusCreated means the user is put on the waiting list.
+	StatusCreated RegisterStatus = iota
+	// StatusAlreadyRegistered means the email is already on the waiting list.
+	StatusAlreadyRegistered
+)
+
+// Approval grants a token to a user on the waiting list.
+type Approval struct {
+	Token        string `json:"token"`
+	Email        string `json:"email"`
+	MonthlyLimit int64  `json:"limit"`
+}
+
+// TokenStatus describes whether a token can be used on Pesto's API.
+type TokenStatus struct {
+	// Registered is true if the token is known to Pesto, even when it is revoked.
+	Registered bool
+	// Revoked is true if the token has been revoked.
+	Revoked bool
+	// MonthlyLimitExceeded is true if the token has used up its monthly quota.
+	MonthlyLimitExceeded bool
+}
+
+// Active reports whether the token can be used to execute code right now.
+func (s TokenStatus) Active() bool {
+	return s.Registered && !s.Revoked && !s.MonthlyLimitExceeded
+}
+
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+type tokenResponse struct {
+	Token string `json:"token"`
+}
+
+// Register puts the user on the waiting list for a token. The token will be sent
+// to the user's email once the registration is approved.
+func (c *Client) Register(ctx context.Context, registrant Registrant) (RegisterStatus, error) {
+	statusCode, err := c.do(ctx, http.MethodPost, "/api/register", registrant, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if statusCode == http.StatusAccepted {
+		return StatusAlreadyRegistered, nil
+	}
+
+	return StatusCreated, nil
+}
+
+// Trial requests a trial token, which is valid for a day and limited to 10 requests.
+func (c *Client) Trial(ctx context.Context) (string, error) {
+	var response tokenResponse
+	_, err := c.do(ctx, http.MethodPost, "/api/trial", nil, &response)
+	if err != nil {
+		return "", err
+	}
+
+	return response.Token, nil
+}
+
+// Pending returns the users on the waiting list.
+func (c *Client) Pending(ctx context.Context) ([]Registrant, error) {
+	var registrants []Registrant
+	_, err := c.do(ctx, http.MethodGet, "/api/pending", nil, &registrants)
+	if err != nil {
+		return nil, err
+	}
+
+	return registrants, nil
+}
+
+// Approve grants the token to a user on the waiting list, and removes the user
+// from the waiting list.
+func (c *Client) Approve(ctx context.Context, approval Approval) error {
+	_, err := c.do(ctx, http.MethodPut, "/api/approve", approval, nil)
+	return err
+}
+
+// Revoke revokes the token. Every following request with the token
+// will fail with pesto.ErrTokenRevoked.
+func (c *Client) Revoke(ctx context.Context, token string) error {
+	_, err := c.do(ctx, http.MethodPut, "/api/revoke", tokenResponse{Token: token}, nil)
+	return err
+}
+
+// StatusFromError tells the status of a token from the error of a call to Pesto's API
+// that was made with it, so the status is known without spending a request of the
+// monthly quota on checking it. It reports false if the error says nothing about
+// the token, like a network error.
+func StatusFromError(err error) (TokenStatus, bool) {
+	switch {
+	case err == nil:
+		return TokenStatus{Registered: true}, true
+	case errors.Is(err, pesto.ErrTokenRevoked):
+		return TokenStatus{Registered: true, Revoked: true}, true
+	case errors.Is(err, pesto.ErrMonthlyLimitExceeded):
+		return TokenStatus{Registered: true, MonthlyLimitExceeded: true}, true
+	case errors.Is(err, pesto.ErrTokenNotRegistered):
+		return TokenStatus{}, true
+	}
+
+	return TokenStatus{}, false
+}
+
+// do sends the request with an optional JSON body, and decodes the JSON response into out
+// if it is not nil. Non-2xx responses are mapped into the errors defined on this package.
+func (c *Client) do(ctx context.Context, method string, path string, body any, out any) (int, error) {
+	var requestBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("marshalling json body: %w", err)
+		}
+
+		requestBody = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL.JoinPath(path).String(), requestBody)
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("sending request: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, response.Body)
+		_ = response.Body.Close()
+	}()
+
+	if response.StatusCode >= 300 {
+		var errResponse messageResponse
+		_ = json.NewDecoder(response.Body).Decode(&errResponse)
+
+		return response.StatusCode, handleErrorCode(response.StatusCode, errResponse)
+	}
+
+	if out != nil {
+		err = json.NewDecoder(response.Body).Decode(out)
+		if err != nil {
+			return response.StatusCode, fmt.Errorf("reading json body: %w", err)
+		}
+	}
+
+	return response.StatusCode, nil
+}
+
+// handleErrorCode maps the HTTP status code and message from the registration service
+// into the errors that are defined on this package.
+func handleErrorCode(code int, response messageResponse) error {
+	switch code {
+	case http.StatusInternalServerError:
+		return fmt.Errorf("%w: %s", pesto.ErrInternalServerError, response.Message)
+	case http.StatusUnprocessableEntity, http.StatusUnsupportedMediaType:
+		return ErrInvalidBody
+	case http.StatusBadRequest:
+		switch response.Message {
+		case "Token already exists":
+			return ErrTokenAlreadyExists
+		case "Email does not exists":
+			return ErrEmailNotFound
+		case "User seemed to be approved already":
+			return ErrAlreadyApproved
+		case "User seemed to be removed from waiting list already":
+			return ErrAlreadyRemoved
+		case "User does not exists":
+			return ErrUserNotFound
+		case "User seemed to be revoked already":
+			return ErrAlreadyRevoked
+		}
+	}
+
+	return fmt.Errorf("received code %d: %s (this is probably a problem with the SDK, please submit an issue on our Github repository)", code, response.Message)
+}
@@ -0,0 +1,190 @@
+package registration_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+	"github.com/teknologi-umum/pesto/sdk/go/pestotest"
+	"github.com/teknologi-umum/pesto/sdk/go/registration"
+)
+
+func TestClient_Onboarding(t *testing.T) {
+	server := pestotest.NewServer()
+	defer server.Close()
+
+	client := registration.NewClientWithConfig(registration.Config{BaseURL: server.BaseURL()})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	status, err := client.Register(ctx, registration.Registrant{
+		Name:     "Jane",
+		Email:    "jane@example.com",
+		Building: "a grading bot",
+		Calls:    1000,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if status != registration.StatusCreated {
+		t.Errorf("expecting status to be StatusCreated, got %d", status)
+	}
+
+	status, err = client.Register(ctx, registration.Registrant{Name: "Jane", Email: "jane@example.com", Calls: 1000})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if status != registration.StatusAlreadyRegistered {
+		t.Errorf("expecting status to be StatusAlreadyRegistered, got %d", status)
+	}
+
+	pending, err := client.Pending(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(pending) != 1 || pending[0].Email != "jane@example.com" || pending[0].Building != "a grading bot" {
+		t.Errorf("unexpected waiting list: %+v", pending)
+	}
+
+	pestoClient, err := pesto.NewClientWithConfig(pesto.Config{Token: "jane-token", BaseURL: server.BaseURL()})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	_, err = pestoClient.Ping(ctx)
+	tokenStatus, ok := registration.StatusFromError(err)
+	if !ok || tokenStatus.Registered {
+		t.Errorf("expecting token to not be registered yet, got %+v from %v", tokenStatus, err)
+	}
+
+	err = client.Approve(ctx, registration.Approval{Token: "jane-token", Email: "jane@example.com", MonthlyLimit: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	_, err = pestoClient.Execute(ctx, pesto.CodeRequest{
+		Language: pesto.LanguagePython,
+		Version:  pesto.VersionLatest,
+		Code:     "print('Hello world!')",
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+	}
+
+	tokenStatus, ok = registration.StatusFromError(err)
+	if !ok || !tokenStatus.Active() {
+		t.Errorf("expecting token to be active, got %+v", tokenStatus)
+	}
+
+	err = client.Revoke(ctx, "jane-token")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	_, err = pestoClient.Ping(ctx)
+	if !errors.Is(err, pesto.ErrTokenRevoked) {
+		t.Errorf("expecting an error of ErrTokenRevoked, got %v", err)
+	}
+
+	tokenStatus, ok = registration.StatusFromError(err)
+	if !ok || !tokenStatus.Registered || !tokenStatus.Revoked || tokenStatus.Active() {
+		t.Errorf("expecting token to be revoked, got %+v", tokenStatus)
+	}
+}
+
+func TestClient_Trial(t *testing.T) {
+	server := pestotest.NewServer()
+	defer server.Close()
+
+	client := registration.NewClientWithConfig(registration.Config{BaseURL: server.BaseURL()})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	trialToken, err := client.Trial(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(trialToken) != 64 {
+		t.Errorf("expecting trial token to have 64 characters, got %q", trialToken)
+	}
+
+	state, ok := server.Token(trialToken)
+	if !ok {
+		t.Fatalf("expecting trial token to be registered on the server")
+	}
+
+	pestoClient, err := pesto.NewClientWithConfig(pesto.Config{Token: trialToken, BaseURL: server.BaseURL()})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	for i := int64(0); i <= state.MonthlyLimit; i++ {
+		_, err = pestoClient.Ping(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+
+	_, err = pestoClient.Ping(ctx)
+	tokenStatus, ok := registration.StatusFromError(err)
+	if !ok || !tokenStatus.MonthlyLimitExceeded {
+		t.Errorf("expecting monthly limit to be exceeded, got %+v from %v", tokenStatus, err)
+	}
+}
+
+func TestStatusFromError(t *testing.T) {
+	_, ok := registration.StatusFromError(context.DeadlineExceeded)
+	if ok {
+		t.Error("expecting an error unrelated to the token to tell nothing")
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	server := pestotest.NewServer(pestotest.WithToken("existing-token", 100))
+	defer server.Close()
+
+	client := registration.NewClientWithConfig(registration.Config{BaseURL: server.BaseURL()})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	t.Run("EmailNotFound", func(t *testing.T) {
+		err := client.Approve(ctx, registration.Approval{Token: "new-token", Email: "nobody@example.com", MonthlyLimit: 10})
+		if !errors.Is(err, registration.ErrEmailNotFound) {
+			t.Errorf("expecting an error of ErrEmailNotFound, got %v", err)
+		}
+	})
+
+	t.Run("TokenAlreadyExists", func(t *testing.T) {
+		err := client.Approve(ctx, registration.Approval{Token: "existing-token", Email: "nobody@example.com", MonthlyLimit: 10})
+		if !errors.Is(err, registration.ErrTokenAlreadyExists) {
+			t.Errorf("expecting an error of ErrTokenAlreadyExists, got %v", err)
+		}
+	})
+
+	t.Run("UserNotFound", func(t *testing.T) {
+		err := client.Revoke(ctx, "unknown-token")
+		if !errors.Is(err, registration.ErrUserNotFound) {
+			t.Errorf("expecting an error of ErrUserNotFound, got %v", err)
+		}
+	})
+	t.Run("AlreadyRevoked", func(t *testing.T) {
+		err := client.Revoke(ctx, "existing-token")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		err = client.Revoke(ctx, "existing-token")
+		if !errors.Is(err, registration.ErrAlreadyRevoked) {
+			t.Errorf("expecting an error of ErrAlreadyRevoked, got %v", err)
+		}
+	})
+}
@@ -1,29 +1,296 @@
 package pesto
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 )
 
+const (
+	// defaultMaxResponseBytes caps the size of a response body that will be read into memory.
+	defaultMaxResponseBytes = 32 << 20
+	// maxDrainBytes is the most that will be read from an unread response body
+	// to let the connection be reused. Bigger bodies are closed instead.
+	maxDrainBytes = 64 << 10
+)
+
+// result is the decoded response of a successful call.
+type result[T any] struct {
+	body       T
+	header     http.Header
+	quota      Quota
+	statusCode int
+}
+
+// do is the request pipeline that every endpoint goes through. It encodes the
+// request body with the client's codec, sends the request, observes the quota
+// headers, maps the error responses, and decodes the response body into T.
+// The start and the end of every call is logged to the client's logger.
+//
+// The default timeout of the client covers the whole call, including the
+// retries on the other instances and reading the response body.
+//
+// The response body is always drained and closed before do returns, so the
+// connection can be reused.
+func do[T any](ctx context.Context, c *Client, method string, path string, requestBody any) (result[T], error) {
+	ctx, release, err := c.lifecycle.acquire(ctx)
+	if err != nil {
+		return result[T]{}, err
+	}
+	defer release()
+
+	if timeout := time.Duration(c.defaultTimeout.Load()); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	args := []any{"method", method, "endpoint", path, "token", redactToken(c.tokenFor(ctx))}
+	if attributer, ok := requestBody.(logAttributer); ok {
+		args = append(args, attributer.logAttributes()...)
+	}
+
+	c.logger.Debug("pesto: request started", args...)
+	start := time.Now()
+
+	res, err := roundTrip[T](ctx, c, method, path, requestBody)
+
+	args = append(args, "status", res.statusCode, "latency", time.Since(start))
+	if err != nil {
+		args = append(args, "error_class", ErrorClass(err), "error", err.Error())
+		if isServerError(err) {
+			c.logger.Error("pesto: request failed", args...)
+		} else {
+			c.logger.Warn("pesto: request failed", args...)
+		}
+
+		return result[T]{quota: res.quota, statusCode: res.statusCode}, err
+	}
+
+	c.logger.Info("pesto: request finished", args...)
+	return res, nil
+}
+
+// roundTrip sends the request and decodes the response for do. The status code
+// and the quota are set on the result even if an error is returned.
+func roundTrip[T any](ctx context.Context, c *Client, method string, path string, requestBody any) (result[T], error) {
+	var body io.Reader
+	if requestBody != nil {
+		encoded, err := c.codec.Marshal(requestBody)
+		if err != nil {
+			return result[T]{}, fmt.Errorf("marshalling body: %w", err)
+		}
+
+		if c.logBodies {
+			c.logger.Debug("pesto: request body", "endpoint", path, "body", loggedBody(encoded))
+		}
+
+		body = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, path, body)
+	if err != nil {
+		return result[T]{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	response, err := c.sendRequest(ctx, request)
+	if err != nil {
+		return result[T]{}, fmt.Errorf("sending request: %w", err)
+	}
+	quota := c.observeQuota(ctx, response)
+	status := result[T]{quota: quota, statusCode: response.StatusCode}
+
+	rawBody, readErr := io.ReadAll(io.LimitReader(response.Body, c.maxResponseBytes+1))
+
+	err = closeBody(response.Body)
+	if err != nil {
+		return status, err
+	}
+
+	if c.logBodies {
+		c.logger.Debug("pesto: response body", "endpoint", path, "status", response.StatusCode, "body", loggedBody(rawBody))
+	}
+
+	if response.StatusCode != http.StatusOK {
+		var errResponse errorResponse
+		// HACK: the error is intentionally not handled, we wanted to leave the empty errorResponse struct
+		// if there is any undecodable response being sent from the server
+		_ = c.codecFor(response.Header).Unmarshal(rawBody, &errResponse)
+
+		return status, c.handleErrorCode(response.StatusCode, errResponse)
+	}
+
+	if readErr != nil {
+		return status, fmt.Errorf("reading response body: %w", readErr)
+	}
+
+	var decoded T
+	if int64(len(rawBody)) > c.maxResponseBytes {
+		// Salvage whatever was received, rather than failing the whole call.
+		s, ok := any(&decoded).(salvager)
+		if !ok || !s.salvage(c.codecFor(response.Header), rawBody[:c.maxResponseBytes]) {
+			return status, fmt.Errorf("%w: exceeds %d bytes", ErrResponseTooLarge, c.maxResponseBytes)
+		}
+
+		c.logger.Warn("pesto: response exceeds the size limit, returning the truncated response", "endpoint", path, "limit", c.maxResponseBytes)
+		return result[T]{body: decoded, header: response.Header, quota: quota, statusCode: response.StatusCode}, nil
+	}
+
+	err = c.codecFor(response.Header).Unmarshal(rawBody, &decoded)
+	if err != nil {
+		return status, fmt.Errorf("decoding response body: %w", err)
+	}
+
+	return result[T]{body: decoded, header: response.Header, quota: quota, statusCode: response.StatusCode}, nil
+}
+
+// closeBody drains what is left of the response body, so the connection can be
+// reused, and closes it.
+func closeBody(body io.ReadCloser) error {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, maxDrainBytes))
+
+	err := body.Close()
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrClosedPipe) && !errors.Is(err, http.ErrBodyReadAfterClose) {
+		return fmt.Errorf("closing response body: %w", err)
+	}
+
+	return nil
+}
+
 // sendRequest will modify the http request from the given parameter
-// and adds some headers including the token and content types.
+// and adds some headers including the token and content types of the client's codec.
+//
+// The request URL should only contain the API path, the base URL is picked
+// by the balancer. If the picked instance is unreachable or responds with
+// an internal server error, the instance is ejected and the request is sent
+// to the next instance, until every instance has been tried.
+//
+// If the circuit breaker is enabled and open, the request is not sent at all,
+// and ErrCircuitOpen is returned.
 func (c *Client) sendRequest(ctx context.Context, request *http.Request) (*http.Response, error) {
-	request.Header.Set("X-Pesto-Token", c.token)
-	request.Header.Set("Content-Type", "application/json")
-	request.Header.Set("Accept", "application/json")
+	if c.circuitBreaker == nil || ctx.Value(circuitProbeKey{}) != nil {
+		return c.sendBalancedRequest(ctx, request)
+	}
+
+	err := c.circuitBreaker.allow(ctx, func(ctx context.Context) bool {
+		res, err := do[PingResponse](ctx, c, http.MethodGet, "/api/ping", nil)
+		var urlErr *url.Error
+		return errors.As(err, &urlErr) || res.statusCode >= http.StatusInternalServerError
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.sendBalancedRequest(ctx, request)
+	switch {
+	case err != nil:
+		// The caller gave up, it says nothing about the server's health.
+		if ctx.Err() == nil {
+			c.circuitBreaker.record(true)
+		}
+	default:
+		c.circuitBreaker.record(response.StatusCode >= http.StatusInternalServerError)
+	}
+
+	return response, err
+}
+
+// tokenFor returns the token that is sent with the request, which is
+// the token that was set through WithToken, or the client's token.
+func (c *Client) tokenFor(ctx context.Context) string {
+	if override, ok := ctx.Value(tokenKey{}).(string); ok {
+		return override
+	}
+
+	return *c.token.Load()
+}
 
-	return c.httpClient.Do(request)
+// sendBalancedRequest sends the request to one of the instances picked by the balancer.
+func (c *Client) sendBalancedRequest(ctx context.Context, request *http.Request) (*http.Response, error) {
+	request.Header.Set("X-Pesto-Token", c.tokenFor(ctx))
+	request.Header.Set("Content-Type", c.codec.ContentType())
+	request.Header.Set("Accept", c.codec.ContentType())
+
+	tried := make(map[*node]bool)
+	var lastResponse *http.Response
+	var lastErr error
+	for {
+		n := c.balancer.pick(tried)
+		if n == nil {
+			break
+		}
+		tried[n] = true
+
+		attempt := request.Clone(ctx)
+		attempt.URL = n.resolve(request.URL)
+		attempt.Host = ""
+		if request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("rewinding request body: %w", err)
+			}
+			attempt.Body = body
+		}
+
+		n.outstanding.Add(1)
+		response, err := c.httpClient.Do(attempt)
+		if err != nil {
+			n.outstanding.Add(-1)
+
+			// The caller gave up, it's not the instance's fault.
+			if ctx.Err() != nil {
+				return nil, err
+			}
+
+			c.balancer.eject(n)
+			c.logger.Warn("pesto: instance unreachable, ejecting it", "instance", n.baseURL.String(), "error", err.Error(), "retrying", c.balancer.hasUntried(tried))
+			lastErr = err
+			continue
+		}
+		response.Body = &outstandingBody{ReadCloser: response.Body, node: n}
+
+		if lastResponse != nil {
+			_ = closeBody(lastResponse.Body)
+		}
+		lastResponse = response
+
+		if response.StatusCode == http.StatusInternalServerError {
+			c.balancer.eject(n)
+			c.logger.Warn("pesto: instance responded with an internal server error, ejecting it", "instance", n.baseURL.String(), "retrying", c.balancer.hasUntried(tried))
+			if c.balancer.hasUntried(tried) {
+				continue
+			}
+		}
+
+		return response, nil
+	}
+
+	if lastResponse != nil {
+		return lastResponse, nil
+	}
+
+	return nil, lastErr
 }
 
 type errorResponse struct {
 	Message string `json:"message"`
+	Msg     string `json:"msg"`
 }
 
 // handleErrorCode will maps HTTP status code from the HTTP response
 // into the errors that are defined on this package
 func (c *Client) handleErrorCode(code int, response errorResponse) error {
+	// HACK: the invalid body response uses "msg" instead of "message".
+	if response.Message == "" {
+		response.Message = response.Msg
+	}
+
 	switch code {
 	case http.StatusNotFound:
 		return fmt.Errorf("api path not found")
@@ -48,10 +315,16 @@ func (c *Client) handleErrorCode(code int, response errorResponse) error {
 			return ErrRuntimeNotFound
 		}
 
-		if strings.HasPrefix(response.Message, "Missing parameters") {
+		if strings.HasPrefix(response.Message, "Missing parameters") ||
+			response.Message == "Both code and files must not be empty" ||
+			response.Message == "File name cannot be empty" {
 			return fmt.Errorf("%w: %s", ErrMissingParameters, response.Message)
 		}
 
+		if strings.HasPrefix(response.Message, "Maximum allowed entrypoint exceeded") {
+			return &ValidationError{Problems: []string{response.Message}}
+		}
+
 		return fmt.Errorf("%s (this is probably a problem with the SDK, please submit an issue on our Github repository)", response.Message)
 	}
 
@@ -0,0 +1,94 @@
+package pesto_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+func TestClient_ConnectionReuse(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		expected   error
+	}{
+		{name: "Success", statusCode: http.StatusOK, body: `{"message":"OK","padding":"` + strings.Repeat("a", 4096) + `"}`},
+		{name: "ErrorResponse", statusCode: http.StatusBadRequest, body: `{"message":"Runtime not found"}`, expected: pesto.ErrRuntimeNotFound},
+		{name: "NonJSONErrorResponse", statusCode: http.StatusTooManyRequests, body: strings.Repeat("Too many request ", 1024), expected: pesto.ErrServerRateLimited},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if test.statusCode == http.StatusOK {
+					w.Header().Set("Content-Type", "application/json")
+				}
+				w.WriteHeader(test.statusCode)
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			client, err := pesto.NewClientWithConfig(pesto.Config{
+				Token:   token,
+				BaseURL: mustParseURL(t, server.URL),
+			})
+			if err != nil {
+				t.Fatalf("creating client: %s", err.Error())
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			var reused atomic.Bool
+			ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+				GotConn: func(info httptrace.GotConnInfo) {
+					reused.Store(info.Reused)
+				},
+			})
+
+			for i := 0; i < 2; i++ {
+				_, err := client.Ping(ctx)
+				if !errors.Is(err, test.expected) {
+					t.Errorf("expecting an error of %v, instead got %v", test.expected, err)
+				}
+			}
+
+			if !reused.Load() {
+				t.Errorf("expecting the second request to reuse the connection")
+			}
+		})
+	}
+}
+
+func TestClient_InvalidResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"runtime":`))
+	}))
+	defer server.Close()
+
+	client, err := pesto.NewClientWithConfig(pesto.Config{
+		Token:   token,
+		BaseURL: mustParseURL(t, server.URL),
+	})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err = client.ListRuntimes(ctx)
+	if err == nil || !strings.HasPrefix(err.Error(), "decoding response body") {
+		t.Errorf("expecting a decoding error, instead got %v", err)
+	}
+}
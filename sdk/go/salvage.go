@@ -0,0 +1,212 @@
+package pesto
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// salvager is implemented by response bodies that can be partially
+// decoded from a body that was cut off at Config.MaxResponseBytes.
+type salvager interface {
+	// salvage decodes the truncated data, and reports whether anything
+	// useful could be decoded from it.
+	salvage(codec Codec, data []byte) bool
+}
+
+func (r *codeResponseBody) salvage(codec Codec, data []byte) bool {
+	// A form body can't tell a complete value apart from a truncated one.
+	if _, ok := codec.(JSONCodec); !ok {
+		return false
+	}
+
+	repaired, completed, ok := salvageJSON(data)
+	if !ok {
+		return false
+	}
+
+	err := json.Unmarshal(repaired, r)
+	if err != nil {
+		return false
+	}
+
+	// Nothing of the outputs was received, there's nothing worth returning.
+	if !completed["compile"] && r.Compile == (Output{}) && r.Runtime == (Output{}) {
+		return false
+	}
+
+	r.Compile.Truncated = !completed["compile"]
+	r.Runtime.Truncated = !completed["runtime"]
+	return true
+}
+
+// salvageJSON repairs a JSON document that was cut off at an arbitrary byte,
+// by closing the open string and containers. A string value that was cut off
+// is kept up to the last complete character, while any other value that was
+// cut off is dropped along with its key.
+//
+// completed contains the keys of the top-level object whose value was received
+// in full. ok is false if nothing could be salvaged.
+func salvageJSON(data []byte) (repaired []byte, completed map[string]bool, ok bool) {
+	completed = make(map[string]bool)
+
+	var (
+		stack       []byte
+		inString    bool
+		escaped     bool
+		stringIsKey bool
+		stringStart int
+		inScalar    bool
+		expectKey   bool
+		topKey      string
+		cut         int
+		cutStack    []byte
+	)
+
+	markSafe := func(end int) {
+		cut = end
+		cutStack = append(cutStack[:0], stack...)
+	}
+
+	valueDone := func(end int) {
+		if len(stack) == 1 && topKey != "" {
+			completed[topKey] = true
+		}
+		markSafe(end)
+	}
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+				if !stringIsKey {
+					valueDone(i + 1)
+				} else if len(stack) == 1 {
+					_ = json.Unmarshal(data[stringStart:i+1], &topKey)
+				}
+			}
+			continue
+		}
+
+		if inScalar {
+			switch c {
+			case ',', '}', ']', ' ', '\t', '\n', '\r':
+				inScalar = false
+				valueDone(i)
+			default:
+				continue
+			}
+		}
+
+		switch c {
+		case ' ', '\t', '\n', '\r':
+		case ':':
+			expectKey = false
+		case ',':
+			expectKey = len(stack) > 0 && stack[len(stack)-1] == '{'
+		case '{', '[':
+			stack = append(stack, c)
+			expectKey = c == '{'
+			markSafe(i + 1)
+		case '}', ']':
+			if len(stack) == 0 {
+				return nil, nil, false
+			}
+			stack = stack[:len(stack)-1]
+			valueDone(i + 1)
+		case '"':
+			inString = true
+			stringStart = i
+			stringIsKey = expectKey
+		default:
+			inScalar = true
+		}
+	}
+
+	if cut == 0 {
+		return nil, nil, false
+	}
+
+	if inString && !stringIsKey {
+		end := len(data)
+		if escaped {
+			end--
+		}
+		end = trimPartialEscape(data[stringStart+1:end]) + stringStart + 1
+		for i := 0; i < utf8.UTFMax-1 && end > stringStart+1; i++ {
+			r, size := utf8.DecodeLastRune(data[stringStart+1 : end])
+			if r != utf8.RuneError || size != 1 {
+				break
+			}
+			end--
+		}
+
+		repaired = append(repaired, data[:end]...)
+		repaired = append(repaired, '"')
+		cutStack = stack
+	} else {
+		repaired = append(repaired, data[:cut]...)
+	}
+
+	for i := len(cutStack) - 1; i >= 0; i-- {
+		if cutStack[i] == '{' {
+			repaired = append(repaired, '}')
+		} else {
+			repaired = append(repaired, ']')
+		}
+	}
+
+	return repaired, completed, true
+}
+
+// trimPartialEscape returns the length of the string content without
+// a trailing \u escape sequence that is missing some of its hex digits.
+func trimPartialEscape(content []byte) int {
+	for i := len(content) - 1; i >= 0 && i >= len(content)-5; i-- {
+		if content[i] != '\\' {
+			continue
+		}
+
+		// Count the backslashes, an escaped backslash is not the start of an escape sequence.
+		backslashes := 0
+		for j := i; j >= 0 && content[j] == '\\'; j-- {
+			backslashes++
+		}
+
+		if backslashes%2 == 1 && i+1 < len(content) && content[i+1] == 'u' {
+			return i
+		}
+
+		return len(content)
+	}
+
+	return len(content)
+}
+
+// truncate cuts each field of the output to at most n bytes, on a UTF-8 boundary.
+func (o *Output) truncate(n int) {
+	var truncated bool
+	for _, field := range []*string{&o.Stdout, &o.Stderr, &o.Output} {
+		if len(*field) <= n {
+			continue
+		}
+
+		end := n
+		for end > 0 && !utf8.RuneStart((*field)[end]) {
+			end--
+		}
+
+		*field = (*field)[:end]
+		truncated = true
+	}
+
+	if truncated {
+		o.Truncated = true
+	}
+}
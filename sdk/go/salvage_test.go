@@ -0,0 +1,205 @@
+package pesto_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+func TestClient_MaxResponseBytes(t *testing.T) {
+	compile := `{"stdout":"","stderr":"","output":"","exitCode":0}`
+	body := `{"language":"Python","version":"3.10.2","compile":` + compile + `,"runtime":{"stdout":"` +
+		strings.Repeat("y\\n", 100) + `","stderr":"","output":"","exitCode":0}}`
+
+	tests := []struct {
+		name     string
+		body     string
+		limit    int64
+		expected error
+		check    func(t *testing.T, response pesto.CodeResponse)
+	}{
+		{
+			name:  "WithinLimit",
+			body:  body,
+			limit: int64(len(body)),
+			check: func(t *testing.T, response pesto.CodeResponse) {
+				if response.Runtime.Truncated || response.Compile.Truncated {
+					t.Errorf("expecting outputs to not be truncated")
+				}
+
+				if response.Runtime.Stdout != strings.Repeat("y\n", 100) {
+					t.Errorf("unexpected stdout: %q", response.Runtime.Stdout)
+				}
+			},
+		},
+		{
+			name:  "CutInRuntimeStdout",
+			body:  body,
+			limit: int64(strings.Index(body, `"runtime"`) + len(`"runtime":{"stdout":"`) + 30),
+			check: func(t *testing.T, response pesto.CodeResponse) {
+				if response.Language != "Python" || response.Version != "3.10.2" {
+					t.Errorf("expecting language and version to be salvaged, got %s %s", response.Language, response.Version)
+				}
+
+				if response.Compile.Truncated {
+					t.Errorf("expecting compile output to not be truncated")
+				}
+
+				if !response.Runtime.Truncated {
+					t.Errorf("expecting runtime output to be truncated")
+				}
+
+				if response.Runtime.Stdout != strings.Repeat("y\n", 10) {
+					t.Errorf("expecting stdout to be cut after 10 lines, got %q", response.Runtime.Stdout)
+				}
+			},
+		},
+		{
+			name:  "CutInEscapeSequence",
+			body:  `{"language":"Python","version":"3.10.2","compile":` + compile + `,"runtime":{"stdout":"ab\u00e9cd"}}`,
+			limit: int64(len(`{"language":"Python","version":"3.10.2","compile":` + compile + `,"runtime":{"stdout":"ab\u00e`)),
+			check: func(t *testing.T, response pesto.CodeResponse) {
+				if !response.Runtime.Truncated || response.Runtime.Stdout != "ab" {
+					t.Errorf("expecting truncated stdout to be 'ab', got %+v", response.Runtime)
+				}
+			},
+		},
+		{
+			name:  "CutInMultiByteCharacter",
+			body:  `{"language":"Python","version":"3.10.2","compile":` + compile + `,"runtime":{"stdout":"ab😀cd"}}`,
+			limit: int64(len(`{"language":"Python","version":"3.10.2","compile":`+compile+`,"runtime":{"stdout":"ab`) + 2),
+			check: func(t *testing.T, response pesto.CodeResponse) {
+				if !response.Runtime.Truncated || response.Runtime.Stdout != "ab" {
+					t.Errorf("expecting truncated stdout to be 'ab', got %+v", response.Runtime)
+				}
+			},
+		},
+		{
+			name:  "CutInExitCode",
+			body:  `{"language":"Python","version":"3.10.2","compile":{"stdout":"","stderr":"boom","output":"boom","exitCode":127}}`,
+			limit: int64(len(`{"language":"Python","version":"3.10.2","compile":{"stdout":"","stderr":"boom","output":"boom","exitCode":12`)),
+			check: func(t *testing.T, response pesto.CodeResponse) {
+				if !response.Compile.Truncated || response.Compile.Output != "boom" || response.Compile.ExitCode != 0 {
+					t.Errorf("expecting compile output without exit code, got %+v", response.Compile)
+				}
+
+				if !response.Runtime.Truncated {
+					t.Errorf("expecting the missing runtime output to be truncated")
+				}
+			},
+		},
+		{
+			name:     "NothingToSalvage",
+			body:     body,
+			limit:    int64(len(`{"language":"Pyth`)),
+			expected: pesto.ErrResponseTooLarge,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			client, err := pesto.NewClientWithConfig(pesto.Config{
+				Token:            token,
+				BaseURL:          mustParseURL(t, server.URL),
+				MaxResponseBytes: test.limit,
+			})
+			if err != nil {
+				t.Fatalf("creating client: %s", err.Error())
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			response, err := client.Execute(ctx, pesto.CodeRequest{
+				Language: pesto.LanguagePython,
+				Version:  pesto.VersionPython,
+				Code:     "while True: print('y')",
+			})
+			if !errors.Is(err, test.expected) {
+				t.Fatalf("expecting an error of %v, instead got %v", test.expected, err)
+			}
+
+			if test.check != nil {
+				test.check(t, response)
+			}
+		})
+	}
+
+	t.Run("Ping", func(t *testing.T) {
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:            token,
+			BaseURL:          happyMockServerURL,
+			MaxResponseBytes: 8,
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err = client.Ping(ctx)
+		if !errors.Is(err, pesto.ErrResponseTooLarge) {
+			t.Errorf("expecting an error of ErrResponseTooLarge, instead got %v", err)
+		}
+	})
+}
+
+func TestCodeRequest_MaxOutputBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"language": "Python",
+			"version": "3.10.2",
+			"compile": {"stdout": "", "stderr": "", "output": "", "exitCode": 0},
+			"runtime": {"stdout": "héllo wörld", "stderr": "", "output": "héllo wörld", "exitCode": 0}
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := pesto.NewClientWithConfig(pesto.Config{
+		Token:   token,
+		BaseURL: mustParseURL(t, server.URL),
+	})
+	if err != nil {
+		t.Fatalf("creating client: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	response, err := client.Execute(ctx, pesto.CodeRequest{
+		Language:       pesto.LanguagePython,
+		Version:        pesto.VersionPython,
+		Code:           "print('héllo wörld')",
+		MaxOutputBytes: 2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	// "é" takes 2 bytes, so only "h" fits within 2 bytes.
+	if response.Runtime.Stdout != "h" || response.Runtime.Output != "h" {
+		t.Errorf("expecting runtime output to be cut to 'h', got %+v", response.Runtime)
+	}
+
+	if !response.Runtime.Truncated {
+		t.Errorf("expecting runtime output to be truncated")
+	}
+
+	if response.Compile.Truncated {
+		t.Errorf("expecting compile output to not be truncated")
+	}
+}
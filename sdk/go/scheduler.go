@@ -0,0 +1,292 @@
+package pesto
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SchedulerConfig provides configuration for the optional scheduler, which queues
+// the executions once MaxConcurrency of them are in flight.
+//
+// The queued executions are ordered by CodeRequest.Priority first, the higher the sooner.
+// Executions of the same priority are spread fairly across CodeRequest.Key according
+// to Weights, so a key that queues a thousand executions doesn't starve a key that
+// queues one. Executions of the same key and priority run in the order they were queued.
+//
+// Only Execute is scheduled, Ping and ListRuntimes are sent right away.
+type SchedulerConfig struct {
+	// MaxConcurrency caps the amount of executions that are in flight at once.
+	// Defaults to 8
+	MaxConcurrency int
+	// MaxQueueTime fails an execution with ErrQueueTimeout if it waited longer
+	// than the given duration in the queue.
+	// Defaults to 0 (no limit other than the context's deadline)
+	MaxQueueTime time.Duration
+	// Weights states the share of each key when the executions are queued.
+	// A key with a weight of 2 runs twice as many executions as a key with a weight of 1.
+	// Missing or non-positive weights default to 1.
+	Weights map[string]int
+	// OnQueueDepth is called with the key and the amount of its queued executions,
+	// every time the amount changes.
+	// It is called synchronously, so keep it short.
+	OnQueueDepth func(key string, depth int)
+	// OnDequeue is called with the key and the time an execution spent in the queue,
+	// every time an execution leaves the queue, including the ones that timed out
+	// or were canceled.
+	// It is called synchronously, so keep it short.
+	OnDequeue func(key string, wait time.Duration)
+}
+
+// SchedulerStats is a snapshot of the scheduler.
+type SchedulerStats struct {
+	// Running is the amount of executions in flight.
+	Running int
+	// Queued is the amount of executions waiting in the queue.
+	Queued int
+	// QueuedByKey is the amount of queued executions of every key that has any.
+	QueuedByKey map[string]int
+}
+
+// scheduler implements start-time fair queueing across the keys, with strict priorities.
+type scheduler struct {
+	config SchedulerConfig
+
+	mu          sync.Mutex
+	running     int
+	queued      int
+	virtualTime float64
+	seq         uint64
+	keys        map[string]*schedulerKey
+}
+
+type schedulerKey struct {
+	name   string
+	weight float64
+	// start is the virtual time at which the next execution of the key starts.
+	start   float64
+	waiters []*schedulerWaiter
+}
+
+type schedulerWaiter struct {
+	priority   int
+	seq        uint64
+	enqueuedAt time.Time
+	ready      chan struct{}
+}
+
+// schedulerEvent is a hook call that is deferred until the lock is released.
+type schedulerEvent func()
+
+func newScheduler(config SchedulerConfig) *scheduler {
+	if config.MaxConcurrency <= 0 {
+		config.MaxConcurrency = 8
+	}
+
+	return &scheduler{
+		config: config,
+		keys:   make(map[string]*schedulerKey),
+	}
+}
+
+// acquire waits for a free slot, and returns the function that frees it along
+// with the time spent in the queue. If the wait exceeds MaxQueueTime, it will
+// return ErrQueueTimeout error. If ctx is done first, it will return ctx.Err().
+func (s *scheduler) acquire(ctx context.Context, key string, priority int) (func(), time.Duration, error) {
+	s.mu.Lock()
+	k, ok := s.keys[key]
+	if !ok {
+		k = &schedulerKey{name: key, weight: s.weight(key)}
+		s.keys[key] = k
+	}
+
+	if len(k.waiters) == 0 && k.start < s.virtualTime {
+		k.start = s.virtualTime
+	}
+
+	w := &schedulerWaiter{
+		priority:   priority,
+		seq:        s.seq,
+		enqueuedAt: time.Now(),
+		ready:      make(chan struct{}),
+	}
+	s.seq++
+
+	// Keep the waiters sorted by priority, and by arrival within the same priority.
+	i := len(k.waiters)
+	for i > 0 && k.waiters[i-1].priority < priority {
+		i--
+	}
+	k.waiters = append(k.waiters, nil)
+	copy(k.waiters[i+1:], k.waiters[i:])
+	k.waiters[i] = w
+	s.queued++
+
+	events := []schedulerEvent{s.depthEvent(k)}
+	events = append(events, s.dispatch()...)
+	s.mu.Unlock()
+	s.emit(events)
+
+	var timeout <-chan time.Time
+	if s.config.MaxQueueTime > 0 {
+		timer := time.NewTimer(s.config.MaxQueueTime)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+		return s.release, time.Since(w.enqueuedAt), nil
+	case <-timeout:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.mu.Lock()
+	select {
+	case <-w.ready:
+		// The slot was handed over while giving up, pass it on to the next waiter.
+		s.mu.Unlock()
+		s.release()
+		return nil, time.Since(w.enqueuedAt), err
+	default:
+	}
+
+	for i, waiter := range k.waiters {
+		if waiter == w {
+			k.waiters = append(k.waiters[:i], k.waiters[i+1:]...)
+			break
+		}
+	}
+	s.queued--
+
+	wait := time.Since(w.enqueuedAt)
+	events = []schedulerEvent{s.depthEvent(k), s.dequeueEvent(k.name, wait)}
+	s.mu.Unlock()
+	s.emit(events)
+
+	return nil, wait, err
+}
+
+// release frees a slot, and hands it to the next waiter.
+func (s *scheduler) release() {
+	s.mu.Lock()
+	s.running--
+	events := s.dispatch()
+	s.mu.Unlock()
+	s.emit(events)
+}
+
+// dispatch hands the free slots to the waiters. It must be called with s.mu held.
+func (s *scheduler) dispatch() []schedulerEvent {
+	var events []schedulerEvent
+
+	for s.running < s.config.MaxConcurrency && s.queued > 0 {
+		var next *schedulerKey
+		for _, k := range s.keys {
+			if len(k.waiters) == 0 {
+				// Forget the idle keys that have caught up with the virtual time,
+				// they would start at the virtual time anyway.
+				if k.start <= s.virtualTime {
+					delete(s.keys, k.name)
+				}
+				continue
+			}
+
+			if next == nil || s.before(k, next) {
+				next = k
+			}
+		}
+
+		w := next.waiters[0]
+		next.waiters = next.waiters[1:]
+		s.queued--
+		s.running++
+
+		s.virtualTime = next.start
+		next.start += 1 / next.weight
+
+		close(w.ready)
+		events = append(events, s.depthEvent(next), s.dequeueEvent(next.name, time.Since(w.enqueuedAt)))
+	}
+
+	return events
+}
+
+// before reports whether the next waiter of a should run before the next waiter of b.
+func (s *scheduler) before(a, b *schedulerKey) bool {
+	wa, wb := a.waiters[0], b.waiters[0]
+	if wa.priority != wb.priority {
+		return wa.priority > wb.priority
+	}
+
+	if a.start != b.start {
+		return a.start < b.start
+	}
+
+	return wa.seq < wb.seq
+}
+
+func (s *scheduler) weight(key string) float64 {
+	if weight := s.config.Weights[key]; weight > 0 {
+		return float64(weight)
+	}
+
+	return 1
+}
+
+func (s *scheduler) depthEvent(k *schedulerKey) schedulerEvent {
+	if s.config.OnQueueDepth == nil {
+		return nil
+	}
+
+	key, depth := k.name, len(k.waiters)
+	return func() { s.config.OnQueueDepth(key, depth) }
+}
+
+func (s *scheduler) dequeueEvent(key string, wait time.Duration) schedulerEvent {
+	if s.config.OnDequeue == nil {
+		return nil
+	}
+
+	return func() { s.config.OnDequeue(key, wait) }
+}
+
+func (s *scheduler) emit(events []schedulerEvent) {
+	for _, event := range events {
+		if event != nil {
+			event()
+		}
+	}
+}
+
+func (s *scheduler) stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := SchedulerStats{
+		Running:     s.running,
+		Queued:      s.queued,
+		QueuedByKey: make(map[string]int),
+	}
+
+	for _, k := range s.keys {
+		if len(k.waiters) > 0 {
+			stats.QueuedByKey[k.name] = len(k.waiters)
+		}
+	}
+
+	return stats
+}
+
+// SchedulerStats returns a snapshot of the scheduler. If the scheduler is not enabled,
+// it will always return an empty SchedulerStats.
+func (c *Client) SchedulerStats() SchedulerStats {
+	if c.scheduler == nil {
+		return SchedulerStats{QueuedByKey: map[string]int{}}
+	}
+
+	return c.scheduler.stats()
+}
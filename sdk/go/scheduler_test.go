@@ -0,0 +1,249 @@
+package pesto_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+// OrderMockServer records the code of every execution in the order they arrive.
+// The execution of "blocker" waits until release is closed.
+func OrderMockServer(release <-chan struct{}) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var order []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Code string `json:"code"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		order = append(order, body.Code)
+		mu.Unlock()
+
+		if body.Code == "blocker" {
+			<-release
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"language":"Python","version":"3.10.2","compile":{"stdout":"","stderr":"","output":"","exitCode":0},"runtime":{"stdout":"","stderr":"","output":"","exitCode":0}}`))
+	}))
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), order...)
+	}
+}
+
+func TestClient_Scheduler(t *testing.T) {
+	type queued struct {
+		code     string
+		key      string
+		priority int
+	}
+
+	tests := []struct {
+		name     string
+		weights  map[string]int
+		queued   []queued
+		expected []string
+	}{
+		{
+			name: "PriorityAndFairness",
+			queued: []queued{
+				{code: "bulk-1", key: "bulk"},
+				{code: "bulk-2", key: "bulk"},
+				{code: "bulk-3", key: "bulk"},
+				{code: "interactive-1", key: "interactive"},
+				{code: "urgent", key: "bulk", priority: 10},
+			},
+			expected: []string{"blocker", "urgent", "interactive-1", "bulk-1", "bulk-2", "bulk-3"},
+		},
+		{
+			name:    "Weights",
+			weights: map[string]int{"a": 2},
+			queued: []queued{
+				{code: "a-1", key: "a"},
+				{code: "a-2", key: "a"},
+				{code: "a-3", key: "a"},
+				{code: "b-1", key: "b"},
+				{code: "b-2", key: "b"},
+				{code: "b-3", key: "b"},
+			},
+			expected: []string{"blocker", "a-1", "b-1", "a-2", "a-3", "b-2", "b-3"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			release := make(chan struct{})
+			server, order := OrderMockServer(release)
+			defer server.Close()
+
+			client, err := pesto.NewClientWithConfig(pesto.Config{
+				Token:     token,
+				BaseURL:   mustParseURL(t, server.URL),
+				Scheduler: &pesto.SchedulerConfig{MaxConcurrency: 1, Weights: test.weights},
+			})
+			if err != nil {
+				t.Fatalf("creating client: %s", err.Error())
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			var wg sync.WaitGroup
+			execute := func(q queued) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := client.Execute(ctx, pesto.CodeRequest{
+						Language: pesto.LanguagePython,
+						Version:  pesto.VersionPython,
+						Code:     q.code,
+						Key:      q.key,
+						Priority: q.priority,
+					})
+					if err != nil {
+						t.Errorf("unexpected error: %s", err.Error())
+					}
+				}()
+			}
+
+			execute(queued{code: "blocker"})
+			for client.SchedulerStats().Running != 1 {
+				time.Sleep(time.Millisecond)
+			}
+
+			// Queue the executions one at a time, so they arrive in order.
+			for i, q := range test.queued {
+				execute(q)
+				for client.SchedulerStats().Queued != i+1 {
+					time.Sleep(time.Millisecond)
+				}
+			}
+
+			close(release)
+			wg.Wait()
+
+			if !reflect.DeepEqual(order(), test.expected) {
+				t.Errorf("expecting executions in the order of %v, got %v", test.expected, order())
+			}
+		})
+	}
+
+	t.Run("QueueTimeout", func(t *testing.T) {
+		release := make(chan struct{})
+		server, _ := OrderMockServer(release)
+		defer server.Close()
+
+		var waits []time.Duration
+		var mu sync.Mutex
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:   token,
+			BaseURL: mustParseURL(t, server.URL),
+			Scheduler: &pesto.SchedulerConfig{
+				MaxConcurrency: 1,
+				MaxQueueTime:   20 * time.Millisecond,
+				OnDequeue: func(key string, wait time.Duration) {
+					mu.Lock()
+					defer mu.Unlock()
+					waits = append(waits, wait)
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		blocked := make(chan error)
+		go func() {
+			_, err := client.Execute(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionPython, Code: "blocker"})
+			blocked <- err
+		}()
+
+		for client.SchedulerStats().Running != 1 {
+			time.Sleep(time.Millisecond)
+		}
+
+		_, err = client.Execute(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionPython, Code: "print(1)"})
+		if !errors.Is(err, pesto.ErrQueueTimeout) {
+			t.Errorf("expecting an error of ErrQueueTimeout, instead got %v", err)
+		}
+
+		if stats := client.SchedulerStats(); stats.Queued != 0 || len(stats.QueuedByKey) != 0 {
+			t.Errorf("expecting an empty queue, got %+v", stats)
+		}
+
+		close(release)
+		if err := <-blocked; err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(waits) != 2 || waits[1] < 20*time.Millisecond {
+			t.Errorf("expecting the timed out wait to be observed, got %v", waits)
+		}
+	})
+
+	t.Run("QueueWaitMetadata", func(t *testing.T) {
+		release := make(chan struct{})
+		server, _ := OrderMockServer(release)
+		defer server.Close()
+
+		client, err := pesto.NewClientWithConfig(pesto.Config{
+			Token:     token,
+			BaseURL:   mustParseURL(t, server.URL),
+			Scheduler: &pesto.SchedulerConfig{MaxConcurrency: 1},
+		})
+		if err != nil {
+			t.Fatalf("creating client: %s", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		go func() {
+			_, _ = client.Execute(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionPython, Code: "blocker"})
+		}()
+
+		for client.SchedulerStats().Running != 1 {
+			time.Sleep(time.Millisecond)
+		}
+
+		job := client.Submit(ctx, pesto.CodeRequest{Language: pesto.LanguagePython, Version: pesto.VersionPython, Code: "print(1)", Key: "bot"})
+		for client.SchedulerStats().QueuedByKey["bot"] != 1 {
+			time.Sleep(time.Millisecond)
+		}
+
+		if job.State() != pesto.JobQueued {
+			t.Errorf("expecting the job to be queued, got %s", job.State())
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+
+		response, err := job.Wait()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if response.Metadata.QueueWait < 10*time.Millisecond {
+			t.Errorf("expecting the queue wait to be at least 10ms, got %s", response.Metadata.QueueWait)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package pesto
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// maxTimeout is the longest compile and run timeout that Pesto's API accepts.
+	maxTimeout = 30 * time.Second
+	// maxMemoryLimit is the highest memory limit in bytes that Pesto's API accepts.
+	maxMemoryLimit = 1024 * 1024 * 1024
+)
+
+// ValidationError lists every problem of a CodeRequest found by CodeRequest.Validate,
+// or the one reported by the server.
+// It matches ErrInvalidRequest with errors.Is.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return ErrInvalidRequest.Error() + ": " + strings.Join(e.Problems, ", ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidRequest
+}
+
+// Validate checks the request against the runtime it will run on, such as a
+// runtime returned by ListRuntimes, before it is sent to the server. It returns
+// a *ValidationError listing every problem at once, or nil if there is none.
+//
+// The checks that depend on the extended fields of the runtime, such as
+// AllowedEntrypoints and ShouldLimitMemory, are skipped if the server does not
+// provide them.
+func (r CodeRequest) Validate(runtime Runtime) error {
+	var problems []string
+	if r.Language == "" {
+		problems = append(problems, "language is empty")
+	}
+
+	if r.Code == "" && len(r.Files) == 0 {
+		problems = append(problems, "both code and files are empty")
+	}
+
+	if r.Code != "" && len(r.Files) > 0 {
+		problems = append(problems, "code is ignored when files are provided")
+	}
+
+	names := make(map[string]bool, len(r.Files))
+	entrypoints := 0
+	for i, file := range r.Files {
+		if file.Name == "" {
+			problems = append(problems, fmt.Sprintf("file %d has no name", i))
+		} else if names[file.Name] {
+			problems = append(problems, fmt.Sprintf("file name %q is duplicated", file.Name))
+		}
+		names[file.Name] = true
+
+		if file.Code == "" {
+			problems = append(problems, fmt.Sprintf("file %d has no code", i))
+		}
+
+		if file.Entrypoint {
+			entrypoints++
+		}
+	}
+
+	if runtime.AllowedEntrypoints > 0 && len(r.Files) > 1 && int64(entrypoints) > runtime.AllowedEntrypoints {
+		problems = append(problems, fmt.Sprintf("%d entrypoints exceed the %d allowed by %s %s", entrypoints, runtime.AllowedEntrypoints, runtime.Language, runtime.Version))
+	}
+
+	if r.CompileTimeout > maxTimeout {
+		problems = append(problems, fmt.Sprintf("compile timeout of %s exceeds %s", r.CompileTimeout, maxTimeout))
+	}
+
+	if r.RunTimeout > maxTimeout {
+		problems = append(problems, fmt.Sprintf("run timeout of %s exceeds %s", r.RunTimeout, maxTimeout))
+	}
+
+	if r.MemoryLimit > maxMemoryLimit {
+		problems = append(problems, fmt.Sprintf("memory limit of %d bytes exceeds %d bytes", r.MemoryLimit, maxMemoryLimit))
+	}
+
+	if r.MemoryLimit > 0 && runtime.ShouldLimitMemory != nil && !*runtime.ShouldLimitMemory {
+		if runtime.Compiled {
+			problems = append(problems, fmt.Sprintf("memory limit only applies to the compilation, %s %s does not limit the memory of the code", runtime.Language, runtime.Version))
+		} else {
+			problems = append(problems, fmt.Sprintf("memory limit is ignored, %s %s does not limit the memory of the code", runtime.Language, runtime.Version))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package pesto_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	pesto "github.com/teknologi-umum/pesto/sdk/go"
+)
+
+func TestCodeRequest_Validate(t *testing.T) {
+	limited, unlimited := true, false
+	python := pesto.Runtime{
+		Language:           "Python",
+		Version:            "3.10.2",
+		Extension:          "py",
+		ShouldLimitMemory:  &unlimited,
+		AllowedEntrypoints: 1,
+	}
+	cpp := pesto.Runtime{
+		Language:           "C++",
+		Version:            "10.2.1",
+		Compiled:           true,
+		ShouldLimitMemory:  &unlimited,
+		AllowedEntrypoints: -1,
+	}
+	basic := pesto.Runtime{Language: "Python", Version: "3.10.2"}
+
+	tests := []struct {
+		name     string
+		runtime  pesto.Runtime
+		request  pesto.CodeRequest
+		expected []string
+	}{
+		{
+			name:    "Valid",
+			runtime: python,
+			request: pesto.CodeRequest{Language: "Python", Version: "3.10.2", Code: "print(1)", RunTimeout: 30 * time.Second},
+		},
+		{
+			name:     "Empty",
+			runtime:  python,
+			request:  pesto.CodeRequest{},
+			expected: []string{"language is empty", "both code and files are empty"},
+		},
+		{
+			name:    "CodeAndFiles",
+			runtime: python,
+			request: pesto.CodeRequest{
+				Language: "Python",
+				Code:     "print(1)",
+				Files:    []pesto.File{{Name: "main.py", Code: "print(1)", Entrypoint: true}},
+			},
+			expected: []string{"code is ignored when files are provided"},
+		},
+		{
+			name:    "Files",
+			runtime: python,
+			request: pesto.CodeRequest{
+				Language: "Python",
+				Files: []pesto.File{
+					{Name: "main.py", Code: "import lib", Entrypoint: true},
+					{Name: "", Code: "print(1)"},
+					{Name: "main.py", Code: ""},
+				},
+			},
+			expected: []string{`file 1 has no name`, `file name "main.py" is duplicated`, `file 2 has no code`},
+		},
+		{
+			name:    "Entrypoints",
+			runtime: python,
+			request: pesto.CodeRequest{
+				Language: "Python",
+				Files: []pesto.File{
+					{Name: "a.py", Code: "print(1)", Entrypoint: true},
+					{Name: "b.py", Code: "print(2)", Entrypoint: true},
+				},
+			},
+			expected: []string{"2 entrypoints exceed the 1 allowed by Python 3.10.2"},
+		},
+		{
+			name:    "UnlimitedEntrypoints",
+			runtime: cpp,
+			request: pesto.CodeRequest{
+				Language: "C++",
+				Files: []pesto.File{
+					{Name: "a.cpp", Code: "int a;", Entrypoint: true},
+					{Name: "b.cpp", Code: "int main() {}", Entrypoint: true},
+				},
+			},
+		},
+		{
+			name:     "Ceilings",
+			runtime:  basic,
+			request:  pesto.CodeRequest{Language: "Python", Code: "print(1)", CompileTimeout: time.Minute, RunTimeout: 31 * time.Second, MemoryLimit: 2*1024*1024*1024 - 1},
+			expected: []string{"compile timeout of 1m0s exceeds 30s", "run timeout of 31s exceeds 30s", "memory limit of 2147483647 bytes exceeds 1073741824 bytes"},
+		},
+		{
+			name:     "MemoryLimitIgnored",
+			runtime:  python,
+			request:  pesto.CodeRequest{Language: "Python", Code: "print(1)", MemoryLimit: 1024},
+			expected: []string{"memory limit is ignored, Python 3.10.2 does not limit the memory of the code"},
+		},
+		{
+			name:     "MemoryLimitCompileOnly",
+			runtime:  cpp,
+			request:  pesto.CodeRequest{Language: "C++", Code: "int main() {}", MemoryLimit: 1024},
+			expected: []string{"memory limit only applies to the compilation, C++ 10.2.1 does not limit the memory of the code"},
+		},
+		{
+			name:    "MemoryLimitApplied",
+			runtime: pesto.Runtime{Language: "Python", Version: "3.10.2", ShouldLimitMemory: &limited},
+			request: pesto.CodeRequest{Language: "Python", Code: "print(1)", MemoryLimit: 1024},
+		},
+		{
+			name:    "UnknownRuntimeFields",
+			runtime: basic,
+			request: pesto.CodeRequest{
+				Language:    "Python",
+				MemoryLimit: 1024,
+				Files: []pesto.File{
+					{Name: "a.py", Code: "print(1)", Entrypoint: true},
+					{Name: "b.py", Code: "print(2)", Entrypoint: true},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.request.Validate(test.runtime)
+			if test.expected == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err.Error())
+				}
+				return
+			}
+
+			if !errors.Is(err, pesto.ErrInvalidRequest) {
+				t.Fatalf("expecting an error of ErrInvalidRequest, instead got %v", err)
+			}
+
+			var validationError *pesto.ValidationError
+			if !errors.As(err, &validationError) {
+				t.Fatalf("expecting a *ValidationError, instead got %T", err)
+			}
+
+			if !reflect.DeepEqual(validationError.Problems, test.expected) {
+				t.Errorf("expecting problems %q, got %q", test.expected, validationError.Problems)
+			}
+		})
+	}
+}